@@ -0,0 +1,148 @@
+package grpc
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/grpc/walletpb"
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+)
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func tokenString(token common.Address) string {
+	if token == (common.Address{}) {
+		return ""
+	}
+	return token.Hex()
+}
+
+func toAddress(address *database.Address) *walletpb.Address {
+	return &walletpb.Address{
+		Address:   address.Address,
+		Family:    address.Family,
+		Kind:      address.Kind,
+		UserId:    address.UserId,
+		CreatedAt: timestamp(address.CreatedAt),
+	}
+}
+
+func toDeposit(deposit *database.Deposit) *walletpb.Deposit {
+	return &walletpb.Deposit{
+		ChainId:     deposit.ChainId,
+		TxHash:      deposit.TxHash,
+		LogIndex:    deposit.LogIndex,
+		BlockNumber: deposit.BlockNumber,
+		BlockHash:   deposit.BlockHash,
+		Token:       deposit.Token,
+		From:        deposit.From,
+		To:          deposit.To,
+		Amount:      deposit.Amount,
+		Status:      deposit.Status,
+		CreatedAt:   timestamp(deposit.CreatedAt),
+	}
+}
+
+func toWithdrawal(withdrawal *database.Withdrawal) *walletpb.Withdrawal {
+	return &walletpb.Withdrawal{
+		Id:        withdrawal.Id,
+		ChainId:   withdrawal.ChainId,
+		UserId:    withdrawal.UserId,
+		Token:     withdrawal.Token,
+		To:        withdrawal.To,
+		Amount:    withdrawal.Amount,
+		Status:    withdrawal.Status,
+		TxHash:    withdrawal.TxHash,
+		CreatedAt: timestamp(withdrawal.CreatedAt),
+		UpdatedAt: timestamp(withdrawal.UpdatedAt),
+	}
+}
+
+func toTransaction(tx *service.TransactionStatus) *walletpb.Transaction {
+	summary := tx.Summary
+	resp := &walletpb.Transaction{
+		Hash:        summary.Hash,
+		Type:        uint32(summary.Type),
+		ChainId:     summary.ChainId,
+		Nonce:       summary.Nonce,
+		From:        summary.From,
+		To:          summary.To,
+		Value:       summary.Value,
+		Gas:         summary.Gas,
+		GasPrice:    summary.GasPrice,
+		GasTipCap:   summary.GasTipCap,
+		GasFeeCap:   summary.GasFeeCap,
+		Data:        summary.Data,
+		Status:      tx.Status,
+		BlockNumber: tx.BlockNumber,
+		GasUsed:     tx.GasUsed,
+	}
+	if call := summary.Call; call != nil {
+		resp.Call = &walletpb.ContractCall{
+			Standard: call.Standard,
+			Method:   call.Method,
+			Token:    call.Token,
+			From:     call.From,
+			To:       call.To,
+			Amount:   call.Amount,
+			TokenId:  call.TokenId,
+		}
+	}
+	return resp
+}
+
+func toDepositEvent(deposit *events.Deposit) *walletpb.DepositEvent {
+	return &walletpb.DepositEvent{
+		UserId:        deposit.UserId,
+		TxHash:        deposit.TxHash,
+		LogIndex:      deposit.LogIndex,
+		BlockNumber:   deposit.BlockNumber,
+		BlockHash:     deposit.BlockHash,
+		Token:         deposit.Token,
+		From:          deposit.From,
+		To:            deposit.To,
+		Amount:        deposit.Amount,
+		Confirmations: deposit.Confirmations,
+	}
+}
+
+func toWalletEvent(event events.Event) *walletpb.WalletEvent {
+	resp := &walletpb.WalletEvent{
+		Id:        event.Id,
+		Sequence:  event.Sequence,
+		ChainId:   event.ChainId,
+		CreatedAt: timestamp(event.CreatedAt),
+	}
+	switch {
+	case event.Type == events.DepositDetected && event.Deposit != nil:
+		resp.Payload = &walletpb.WalletEvent_DepositDetected{DepositDetected: toDepositEvent(event.Deposit)}
+	case event.Type == events.DepositConfirmed && event.Deposit != nil:
+		resp.Payload = &walletpb.WalletEvent_DepositConfirmed{DepositConfirmed: toDepositEvent(event.Deposit)}
+	case event.Type == events.WithdrawalStatus && event.Withdrawal != nil:
+		w := event.Withdrawal
+		resp.Payload = &walletpb.WalletEvent_WithdrawalStatus{WithdrawalStatus: &walletpb.WithdrawalEvent{
+			Id:     w.Id,
+			UserId: w.UserId,
+			Token:  w.Token,
+			To:     w.To,
+			Amount: w.Amount,
+			Status: w.Status,
+			TxHash: w.TxHash,
+		}}
+	case event.Type == events.ReorgRollback && event.Reorg != nil:
+		reorg := &walletpb.ReorgEvent{ForkBlock: event.Reorg.ForkBlock, RemovedBlocks: event.Reorg.RemovedBlocks}
+		for _, ref := range event.Reorg.Deposits {
+			reorg.Deposits = append(reorg.Deposits, &walletpb.DepositRef{UserId: ref.UserId, TxHash: ref.TxHash, LogIndex: ref.LogIndex})
+		}
+		resp.Payload = &walletpb.WalletEvent_ReorgRollback{ReorgRollback: reorg}
+	}
+	return resp
+}
@@ -0,0 +1,262 @@
+// Package grpc 钱包 gRPC 服务，接口定义见 api/proto/wallet.proto
+package grpc
+
+import (
+	"context"
+	"errors"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"net"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/grpc/walletpb"
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+)
+
+// codeByServiceCode 业务错误码对应的 gRPC 状态码
+var codeByServiceCode = map[service.Code]codes.Code{
+	service.CodeInvalidArgument:     codes.InvalidArgument,
+	service.CodeNotFound:            codes.NotFound,
+	service.CodeAlreadyExists:       codes.AlreadyExists,
+	service.CodeInsufficientBalance: codes.FailedPrecondition,
+	service.CodeDestinationBlocked:  codes.FailedPrecondition,
+	service.CodeUnavailable:         codes.Unavailable,
+	service.CodeInternal:            codes.Internal,
+}
+
+// Server 钱包 gRPC 服务
+type Server struct {
+	walletpb.UnimplementedWalletServiceServer
+
+	service *service.Service
+	server  *gogrpc.Server
+}
+
+func NewServer(svc *service.Service, opts ...gogrpc.ServerOption) *Server {
+	s := &Server{service: svc, server: gogrpc.NewServer(opts...)}
+	walletpb.RegisterWalletServiceServer(s.server, s)
+	return s
+}
+
+// Serve 在 lis 上提供服务，直到 GracefulStop 被调用
+func (s *Server) Serve(lis net.Listener) error {
+	log.Printf("grpc: listening on %s", lis.Addr())
+	return s.server.Serve(lis)
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// GracefulStop 停止接收新请求并等待进行中的调用结束，事件流会随事件总线关闭而结束
+func (s *Server) GracefulStop() {
+	s.server.GracefulStop()
+}
+
+func (s *Server) AllocateAddress(ctx context.Context, req *walletpb.AllocateAddressRequest) (*walletpb.Address, error) {
+	address, err := s.service.AllocateAddress(ctx, req.GetUserId(), req.GetFamily())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toAddress(address), nil
+}
+
+func (s *Server) ListAddresses(ctx context.Context, req *walletpb.ListAddressesRequest) (*walletpb.ListAddressesResponse, error) {
+	addresses, err := s.service.ListAddresses(ctx, req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &walletpb.ListAddressesResponse{Items: make([]*walletpb.Address, len(addresses))}
+	for i := range addresses {
+		resp.Items[i] = toAddress(&addresses[i])
+	}
+	return resp, nil
+}
+
+func (s *Server) ListDeposits(ctx context.Context, req *walletpb.ListDepositsRequest) (*walletpb.ListDepositsResponse, error) {
+	deposits, err := s.service.ListDeposits(ctx, req.GetUserId(), int(req.GetLimit()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &walletpb.ListDepositsResponse{Items: make([]*walletpb.Deposit, len(deposits))}
+	for i := range deposits {
+		resp.Items[i] = toDeposit(&deposits[i])
+	}
+	return resp, nil
+}
+
+func (s *Server) GetUserBalances(ctx context.Context, req *walletpb.GetUserBalancesRequest) (*walletpb.GetUserBalancesResponse, error) {
+	balances, err := s.service.UserBalances(req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &walletpb.GetUserBalancesResponse{Items: make([]*walletpb.Balance, len(balances))}
+	for i, balance := range balances {
+		resp.Items[i] = &walletpb.Balance{
+			ChainId: balance.Account.Asset.ChainId,
+			Token:   tokenString(balance.Account.Asset.Token),
+			Amount:  balance.Amount.String(),
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) SubmitWithdrawal(ctx context.Context, req *walletpb.SubmitWithdrawalRequest) (*walletpb.Withdrawal, error) {
+	result, err := s.service.SubmitWithdrawal(ctx, service.WithdrawalRequest{
+		Id:     req.GetId(),
+		UserId: req.GetUserId(),
+		Token:  req.GetToken(),
+		To:     req.GetTo(),
+		Amount: req.GetAmount(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	withdrawal := toWithdrawal(result.Withdrawal)
+	withdrawal.Fee = result.Fee.String()
+	withdrawal.Warning = result.Warning
+	return withdrawal, nil
+}
+
+func (s *Server) GetWithdrawal(ctx context.Context, req *walletpb.GetWithdrawalRequest) (*walletpb.Withdrawal, error) {
+	withdrawal, err := s.service.GetWithdrawal(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toWithdrawal(withdrawal), nil
+}
+
+func (s *Server) GetChainBalance(ctx context.Context, req *walletpb.GetChainBalanceRequest) (*walletpb.ChainBalance, error) {
+	address, token, balance, err := s.service.ChainBalance(ctx, req.GetAddress(), req.GetToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &walletpb.ChainBalance{Address: address.Hex(), Token: tokenString(token), Balance: balance.String()}, nil
+}
+
+func (s *Server) GetTransaction(ctx context.Context, req *walletpb.GetTransactionRequest) (*walletpb.Transaction, error) {
+	tx, err := s.service.Transaction(ctx, req.GetHash())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTransaction(tx), nil
+}
+
+func (s *Server) SubscribeEvents(req *walletpb.SubscribeEventsRequest, stream gogrpc.ServerStreamingServer[walletpb.WalletEvent]) error {
+	return s.subscribe(req, stream)
+}
+
+func (s *Server) SubscribeDepositEvents(req *walletpb.SubscribeEventsRequest, stream gogrpc.ServerStreamingServer[walletpb.WalletEvent]) error {
+	return s.subscribe(req, stream, events.DepositDetected, events.DepositConfirmed, events.ReorgRollback)
+}
+
+func (s *Server) SubscribeWithdrawalEvents(req *walletpb.SubscribeEventsRequest, stream gogrpc.ServerStreamingServer[walletpb.WalletEvent]) error {
+	return s.subscribe(req, stream, events.WithdrawalStatus)
+}
+
+func (s *Server) ListEvents(ctx context.Context, req *walletpb.ListEventsRequest) (*walletpb.ListEventsResponse, error) {
+	if req.GetFrom() == nil {
+		return nil, status.Error(codes.InvalidArgument, "from is required")
+	}
+	var to time.Time
+	if req.GetTo() != nil {
+		to = req.GetTo().AsTime()
+	}
+	page, err := s.service.ListEvents(ctx, req.GetFrom().AsTime(), to, req.GetPageToken(), int(req.GetLimit()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	types := make(map[string]bool, len(req.GetTypes()))
+	for _, eventType := range req.GetTypes() {
+		types[eventType] = true
+	}
+	resp := &walletpb.ListEventsResponse{NextPageToken: page.NextPageToken}
+	for _, event := range page.Events {
+		if len(types) > 0 && !types[string(event.Type)] {
+			continue
+		}
+		if req.GetUserId() != "" && !eventForUser(event, req.GetUserId()) {
+			continue
+		}
+		if item := toWalletEvent(event); item.Payload != nil {
+			resp.Items = append(resp.Items, item)
+		}
+	}
+	return resp, nil
+}
+
+// subscribe 将事件总线上的事件推送给客户端，直到客户端断开或订阅被总线关闭
+func (s *Server) subscribe(req *walletpb.SubscribeEventsRequest, stream gogrpc.ServerStreamingServer[walletpb.WalletEvent], types ...events.Type) error {
+	bus := s.service.Events()
+	if bus == nil {
+		return status.Error(codes.Unavailable, "event bus is not enabled")
+	}
+	sub, err := bus.Subscribe(req.GetAfterSequence(), 0, types...)
+	switch {
+	case errors.Is(err, events.ErrSequenceExpired):
+		return status.Errorf(codes.OutOfRange, "sequence %d is not retained by this process, resync with ListEvents", req.GetAfterSequence())
+	case err != nil:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				switch err := sub.Err(); {
+				case errors.Is(err, events.ErrSlowSubscriber):
+					return status.Error(codes.ResourceExhausted, err.Error())
+				case err != nil:
+					return status.Error(codes.Unavailable, err.Error())
+				}
+				return nil
+			}
+			if req.GetUserId() != "" && !eventForUser(event, req.GetUserId()) {
+				continue
+			}
+			resp := toWalletEvent(event)
+			if resp.Payload == nil {
+				// 协议中没有对应消息的事件（如交易池中的未确认充值）不推送
+				continue
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// eventForUser 回滚事件可能涉及任意用户，总是推送
+func eventForUser(event events.Event, userId string) bool {
+	switch {
+	case event.Deposit != nil:
+		return event.Deposit.UserId == userId
+	case event.Withdrawal != nil:
+		return event.Withdrawal.UserId == userId
+	}
+	return true
+}
+
+// toStatus 将业务错误转换为 gRPC 状态，内部错误只记录日志
+func toStatus(err error) error {
+	var serviceErr *service.Error
+	if !errors.As(err, &serviceErr) {
+		log.Printf("grpc: internal error: %v", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+	code, ok := codeByServiceCode[serviceErr.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, serviceErr.Message)
+}
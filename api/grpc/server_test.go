@@ -0,0 +1,226 @@
+package grpc
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/grpc/walletpb"
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+	"github.com/0xweb-3/EthCEXWallet/api/webhook"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	"github.com/0xweb-3/EthCEXWallet/wallet/pool"
+)
+
+type nopClient struct {
+	node.EthClient
+}
+
+func newTestClient(t *testing.T) (walletpb.WalletServiceClient, *events.Bus) {
+	t.Helper()
+	repo, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addressPool := pool.NewPool(pool.Config{Family: "evm", Target: 1}, pool.SourceFunc(WalletEthereum.CreateAddress))
+	if err := addressPool.Refill(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// u1 充值 1000 wei，用于提现测试
+	l := ledger.NewLedger()
+	if _, err := l.Post(ledger.DepositEntry("u1", common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227"), ledger.Asset{ChainId: 11155111}, big.NewInt(1000), common.HexToHash("0x01"), 0)); err != nil {
+		t.Fatal(err)
+	}
+	bus := events.NewBus(100)
+	svc, err := service.New(service.Options{
+		ChainId:    big.NewInt(11155111),
+		Client:     nopClient{},
+		Repository: repo,
+		Addresses:  pool.NewService(addressPool),
+		Ledger:     l,
+		Events:     bus,
+		Webhooks:   webhook.NewNotifier(repo, webhook.Config{}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(svc)
+	go server.Serve(lis)
+	conn, err := gogrpc.NewClient("passthrough:///bufnet",
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		bus.Close()
+		server.GracefulStop()
+		repo.Close()
+	})
+	return walletpb.NewWalletServiceClient(conn), bus
+}
+
+func TestWalletService(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	address, err := client.AllocateAddress(ctx, &walletpb.AllocateAddressRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.IsHexAddress(address.Address) || address.Kind != "deposit" || address.CreatedAt == nil {
+		t.Errorf("AllocateAddress() = %+v", address)
+	}
+
+	_, err = client.SubmitWithdrawal(ctx, &walletpb.SubmitWithdrawalRequest{Id: "w1", UserId: "u1", To: "0x1234", Amount: "1"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SubmitWithdrawal() invalid address err = %v", err)
+	}
+	withdrawal, err := client.SubmitWithdrawal(ctx, &walletpb.SubmitWithdrawalRequest{Id: "w1", UserId: "u1", To: "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74", Amount: "100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withdrawal.Status != "created" || withdrawal.Amount != "100" || withdrawal.Fee != "0" {
+		t.Errorf("SubmitWithdrawal() = %+v", withdrawal)
+	}
+	if _, err := client.SubmitWithdrawal(ctx, &walletpb.SubmitWithdrawalRequest{Id: "w1", UserId: "u1", To: withdrawal.To, Amount: "100"}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("SubmitWithdrawal() duplicate err = %v", err)
+	}
+	if _, err := client.GetWithdrawal(ctx, &walletpb.GetWithdrawalRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetWithdrawal() missing err = %v", err)
+	}
+	balances, err := client.GetUserBalances(ctx, &walletpb.GetUserBalancesRequest{UserId: "u1"})
+	if err != nil || len(balances.Items) != 1 || balances.Items[0].Amount != "900" {
+		t.Errorf("GetUserBalances() = %v %v", balances, err)
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	client, bus := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 1: 提现事件；2、3：充值事件；4：回滚
+	if _, err := client.SubmitWithdrawal(ctx, &walletpb.SubmitWithdrawalRequest{Id: "w1", UserId: "u1", To: "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74", Amount: "100"}); err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(events.Event{Type: events.DepositDetected, Deposit: &events.Deposit{UserId: "u1", TxHash: "0x01"}})
+	bus.Publish(events.Event{Type: events.DepositConfirmed, Deposit: &events.Deposit{UserId: "u2", TxHash: "0x02"}})
+	bus.Publish(events.Event{Type: events.ReorgRollback, Reorg: &events.Reorg{ForkBlock: 9, Deposits: []events.DepositRef{{UserId: "u1", TxHash: "0x01"}}}})
+
+	deposits, err := client.SubscribeDepositEvents(ctx, &walletpb.SubscribeEventsRequest{AfterSequence: 1, UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := deposits.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Sequence != 2 || event.GetDepositDetected().GetTxHash() != "0x01" {
+		t.Errorf("first deposit event = %v", event)
+	}
+	// u2 的充值被过滤，回滚事件总是推送
+	if event, err = deposits.Recv(); err != nil || event.GetReorgRollback().GetForkBlock() != 9 {
+		t.Errorf("reorg event = %v, err %v", event, err)
+	}
+
+	// 订阅已建立后的新事件实时推送
+	bus.Publish(events.Event{Type: events.DepositConfirmed, Deposit: &events.Deposit{UserId: "u1", TxHash: "0x01", Confirmations: 12}})
+	if event, err = deposits.Recv(); err != nil || event.GetDepositConfirmed().GetConfirmations() != 12 {
+		t.Errorf("live event = %v, err %v", event, err)
+	}
+
+	resumed, err := client.SubscribeEvents(ctx, &walletpb.SubscribeEventsRequest{AfterSequence: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event, err = resumed.Recv(); err != nil || event.Sequence != 5 {
+		t.Errorf("resumed event = %v, err %v", event, err)
+	}
+	withdrawals, err := client.SubscribeWithdrawalEvents(ctx, &walletpb.SubscribeEventsRequest{AfterSequence: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SubmitWithdrawal(ctx, &walletpb.SubmitWithdrawalRequest{Id: "w2", UserId: "u1", To: "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74", Amount: "1"})
+	if event, err = withdrawals.Recv(); err != nil || event.GetWithdrawalStatus().GetId() != "w2" {
+		t.Errorf("withdrawal event = %v, err %v", event, err)
+	}
+
+	bus.Close()
+	if _, err := deposits.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Recv() after bus close err = %v", err)
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	// 提现事件与业务数据一同写入存储
+	for _, id := range []string{"w1", "w2", "w3"} {
+		if _, err := client.SubmitWithdrawal(ctx, &walletpb.SubmitWithdrawalRequest{Id: id, UserId: "u1", To: "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74", Amount: "100"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	from := timestamppb.New(time.Now().Add(-time.Minute))
+	to := timestamppb.New(time.Now().Add(time.Minute))
+
+	if _, err := client.ListEvents(ctx, &walletpb.ListEventsRequest{To: to}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListEvents() without from err = %v", err)
+	}
+
+	seen := make(map[string]bool)
+	req := &walletpb.ListEventsRequest{From: from, To: to, Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 2 {
+			t.Fatal("ListEvents() does not stop paging")
+		}
+		resp, err := client.ListEvents(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range resp.Items {
+			id := item.GetWithdrawalStatus().GetId()
+			if seen[id] {
+				t.Errorf("ListEvents() returned %s twice", id)
+			}
+			seen[id] = true
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	if len(seen) != 3 || !seen["w1"] || !seen["w2"] || !seen["w3"] {
+		t.Errorf("ListEvents() withdrawals = %v", seen)
+	}
+
+	other, err := client.ListEvents(ctx, &walletpb.ListEventsRequest{From: from, To: to, UserId: "u2"})
+	if err != nil || len(other.Items) != 0 {
+		t.Errorf("ListEvents() for u2 = %v, err %v", other, err)
+	}
+	deposits, err := client.ListEvents(ctx, &walletpb.ListEventsRequest{From: from, To: to, Types: []string{string(events.DepositConfirmed)}})
+	if err != nil || len(deposits.Items) != 0 {
+		t.Errorf("ListEvents() deposit.confirmed = %v, err %v", deposits, err)
+	}
+	if _, err := client.ListEvents(ctx, &walletpb.ListEventsRequest{From: from, PageToken: "bad"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListEvents() bad page token err = %v", err)
+	}
+}
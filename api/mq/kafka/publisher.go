@@ -0,0 +1,63 @@
+// Package kafka 基于 sarama 的 Kafka 发布适配器
+package kafka
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+)
+
+const (
+	headerEventId   = "event-id"
+	headerEventType = "event-type"
+)
+
+// Publisher 同步发布，SendMessage 返回时消息已被所有同步副本确认
+type Publisher struct {
+	producer sarama.SyncProducer
+}
+
+var _ mq.Publisher = (*Publisher)(nil)
+
+// NewPublisher config 为空时使用 acks=all 的幂等生产者，避免 broker 重试产生重复或乱序
+func NewPublisher(brokers []string, config *sarama.Config) (*Publisher, error) {
+	if config == nil {
+		config = sarama.NewConfig()
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+	}
+	// SyncProducer 要求开启
+	config.Producer.Return.Successes = true
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewPublisherWithProducer(producer), nil
+}
+
+func NewPublisherWithProducer(producer sarama.SyncProducer) *Publisher {
+	return &Publisher{producer: producer}
+}
+
+// Publish 以分区键作为消息 key，同一用户的事件进入同一分区；sarama 的同步发送不支持 ctx 取消
+func (p *Publisher) Publish(ctx context.Context, msg *mq.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, _, err := p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: msg.Topic,
+		Key:   sarama.StringEncoder(msg.Key),
+		Value: sarama.ByteEncoder(msg.Payload),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(headerEventId), Value: []byte(msg.Id)},
+			{Key: []byte(headerEventType), Value: []byte(msg.Type)},
+		},
+	})
+	return err
+}
+
+func (p *Publisher) Close() error {
+	return p.producer.Close()
+}
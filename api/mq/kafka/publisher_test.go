@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+)
+
+func TestPublish(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	publisher := NewPublisherWithProducer(producer)
+	defer publisher.Close()
+
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		key, _ := msg.Key.Encode()
+		value, _ := msg.Value.Encode()
+		if msg.Topic != "wallet.deposit.confirmed" || string(key) != "u1" || string(value) != `{"id":"e1"}` {
+			return fmt.Errorf("unexpected message %s %s %s", msg.Topic, key, value)
+		}
+		if len(msg.Headers) != 2 || string(msg.Headers[0].Value) != "e1" || string(msg.Headers[1].Value) != "deposit.confirmed" {
+			return fmt.Errorf("unexpected headers %+v", msg.Headers)
+		}
+		return nil
+	})
+	err := publisher.Publish(context.Background(), &mq.Message{
+		Topic:   "wallet.deposit.confirmed",
+		Key:     "u1",
+		Id:      "e1",
+		Type:    "deposit.confirmed",
+		Payload: []byte(`{"id":"e1"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	brokerErr := errors.New("not enough in-sync replicas")
+	producer.ExpectSendMessageAndFail(brokerErr)
+	if err := publisher.Publish(context.Background(), &mq.Message{Topic: "wallet.deposit.confirmed", Id: "e2"}); !errors.Is(err, brokerErr) {
+		t.Errorf("Publish() err = %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Package nats 基于 NATS JetStream 的发布适配器
+package nats
+
+import (
+	"context"
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+)
+
+const headerEventType = "Wallet-Event-Type"
+
+// Publisher 通过 JetStream 发布，收到 stream 的确认才视为成功；核心 NATS 不持久化，不适合作为发件箱的目标
+// 主题需已被某个 stream 覆盖（如 wallet.>），否则发布返回 no responders
+type Publisher struct {
+	conn  *gonats.Conn
+	js    jetstream.JetStream
+	owned bool
+}
+
+var _ mq.Publisher = (*Publisher)(nil)
+
+func NewPublisher(url string, opts ...gonats.Option) (*Publisher, error) {
+	conn, err := gonats.Connect(url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewPublisherWithConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p.owned = true
+	return p, nil
+}
+
+// NewPublisherWithConn 复用已有连接，Close 不会关闭该连接
+func NewPublisherWithConn(conn *gonats.Conn) (*Publisher, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &Publisher{conn: conn, js: js}, nil
+}
+
+// Publish 事件 ID 作为 Nats-Msg-Id，stream 在去重窗口内丢弃重复发布的消息
+func (p *Publisher) Publish(ctx context.Context, msg *mq.Message) error {
+	m := gonats.NewMsg(msg.Topic)
+	m.Data = msg.Payload
+	m.Header.Set(headerEventType, msg.Type)
+	_, err := p.js.PublishMsg(ctx, m, jetstream.WithMsgID(msg.Id))
+	return err
+}
+
+func (p *Publisher) Close() error {
+	if p.owned {
+		return p.conn.Drain()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package nats
+
+import (
+	"context"
+	"github.com/nats-io/nats-server/v2/server"
+	gonats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"testing"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+)
+
+func runServer(t *testing.T) *server.Server {
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	s := runServer(t)
+
+	publisher, err := NewPublisher(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+
+	msg := &mq.Message{Topic: "wallet.deposit.confirmed", Key: "u1", Id: "e1", Type: "deposit.confirmed", Payload: []byte(`{"id":"e1"}`)}
+	// 没有 stream 覆盖的主题无法确认
+	if err := publisher.Publish(ctx, msg); err == nil {
+		t.Fatal("Publish() without stream should fail")
+	}
+
+	stream, err := publisher.js.CreateStream(ctx, jetstream.StreamConfig{Name: "WALLET", Subjects: []string{"wallet.>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 发件箱重复发布同一事件时由 stream 去重
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Fatalf("stream messages = %d, want 1", info.State.Msgs)
+	}
+
+	stored, err := stream.GetMsg(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stored.Data) != `{"id":"e1"}` || stored.Header.Get(gonats.MsgIdHdr) != "e1" || stored.Header.Get(headerEventType) != "deposit.confirmed" {
+		t.Errorf("stored message = %s %v", stored.Data, stored.Header)
+	}
+}
@@ -0,0 +1,179 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+)
+
+// maxLastError 与表结构中 last_error 的长度一致
+const maxLastError = 512
+
+// AddEvent 将事件写入发件箱，store 应为 RunInTx 传入的 repo，使事件与业务数据一同提交或回滚
+// 返回补全了 Id 与时间的事件，事务提交后可原样发布到进程内总线，下游据同一 Id 去重
+func AddEvent(ctx context.Context, store database.OutboxStore, event events.Event) (events.Event, error) {
+	if event.Id == "" {
+		event.Id = uuid.NewString()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	// Sequence 只在进程内总线中有意义
+	event.Sequence = 0
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return events.Event{}, err
+	}
+	err = store.AddOutboxMessage(ctx, &database.OutboxMessage{
+		EventId:      event.Id,
+		Type:         string(event.Type),
+		PartitionKey: partitionKey(&event),
+		Payload:      string(payload),
+		CreatedAt:    event.CreatedAt,
+	})
+	if err != nil {
+		return events.Event{}, err
+	}
+	return event, nil
+}
+
+// partitionKey 充值与提现按用户分区，回滚事件按链分区
+func partitionKey(event *events.Event) string {
+	switch {
+	case event.Deposit != nil:
+		return event.Deposit.UserId
+	case event.Withdrawal != nil:
+		return event.Withdrawal.UserId
+	default:
+		return strconv.FormatUint(event.ChainId, 10)
+	}
+}
+
+type RelayConfig struct {
+	TopicPrefix  string        // 主题为前缀加事件类型，如 wallet.deposit.confirmed，默认 "wallet."
+	BatchSize    int           // 每轮读取的消息数，默认 100
+	PollInterval time.Duration // 发件箱为空时的轮询间隔，默认 1s
+	BaseBackoff  time.Duration // 发布失败后的首次等待，连续失败时翻倍，默认 1s
+	MaxBackoff   time.Duration // 默认 1m
+}
+
+// Relay 按 Sequence 顺序把发件箱中的消息发布到消息队列，发布成功后删除
+// 同一发件箱只应运行一个 Relay，否则消息会被重复发布且失去顺序
+type Relay struct {
+	store     database.OutboxStore
+	publisher Publisher
+	config    RelayConfig
+	wake      chan struct{}
+}
+
+func NewRelay(store database.OutboxStore, publisher Publisher, config RelayConfig) *Relay {
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = "wallet."
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = time.Second
+	}
+	if config.MaxBackoff < config.BaseBackoff {
+		config.MaxBackoff = max(time.Minute, config.BaseBackoff)
+	}
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		config:    config,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Notify 事务提交后调用，让 Relay 立即发布而不必等待下一次轮询
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// PublishPending 按顺序发布一批消息，遇到失败即停止以保持顺序，返回成功发布的数量
+// 发布成功但删除失败时消息会在下一轮重复发布
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	messages, err := r.store.ListOutboxMessages(ctx, r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, record := range messages {
+		msg := &Message{
+			Topic:   r.config.TopicPrefix + record.Type,
+			Key:     record.PartitionKey,
+			Id:      record.EventId,
+			Type:    record.Type,
+			Payload: []byte(record.Payload),
+		}
+		if err := r.publisher.Publish(ctx, msg); err != nil {
+			if ctx.Err() == nil {
+				lastError := err.Error()
+				if len(lastError) > maxLastError {
+					lastError = lastError[:maxLastError]
+				}
+				if err := r.store.RecordOutboxFailure(ctx, record.Sequence, lastError); err != nil {
+					log.Printf("mq: record outbox failure %d: %v", record.Sequence, err)
+				}
+			}
+			return i, err
+		}
+		if err := r.store.DeleteOutboxMessage(ctx, record.Sequence); err != nil {
+			return i + 1, err
+		}
+	}
+	return len(messages), nil
+}
+
+// Run 持续发布发件箱中的消息直到 ctx 取消，连续失败时按指数退避等待
+func (r *Relay) Run(ctx context.Context) {
+	backoff := time.Duration(0)
+	for {
+		count, err := r.PublishPending(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := r.config.PollInterval
+		switch {
+		case err != nil:
+			backoff = min(max(backoff*2, r.config.BaseBackoff), r.config.MaxBackoff)
+			wait = backoff
+			log.Printf("mq: publish outbox failed, retry in %s: %v", wait, err)
+		case count == r.config.BatchSize:
+			// 整批发布成功说明还有积压，直接处理下一批
+			backoff = 0
+			continue
+		default:
+			backoff = 0
+		}
+
+		// 退避期间不因新消息提前重试
+		wake := r.wake
+		if err != nil {
+			wake = nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		case <-wake:
+			timer.Stop()
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+)
+
+// memoryPublisher 内存中的 broker，down 为 true 时拒绝发布
+type memoryPublisher struct {
+	mu       sync.Mutex
+	down     bool
+	messages []*Message
+}
+
+func (p *memoryPublisher) Publish(ctx context.Context, msg *Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down {
+		return errors.New("broker unavailable")
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+func (p *memoryPublisher) Close() error {
+	return nil
+}
+
+func (p *memoryPublisher) setDown(down bool) {
+	p.mu.Lock()
+	p.down = down
+	p.mu.Unlock()
+}
+
+func (p *memoryPublisher) published() []*Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Message(nil), p.messages...)
+}
+
+func openStore(t *testing.T) database.Repository {
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func depositEvent(userId string) events.Event {
+	return events.Event{
+		Type:    events.DepositConfirmed,
+		ChainId: 1,
+		Deposit: &events.Deposit{UserId: userId, TxHash: "0x01", Amount: "100"},
+	}
+}
+
+// saveDeposit 充值与事件在同一事务中写入，fail 为 true 时模拟事务失败
+func saveDeposit(t *testing.T, repo database.Repository, userId, txHash string, fail bool) (events.Event, error) {
+	var event events.Event
+	err := repo.RunInTx(context.Background(), func(ctx context.Context, tx database.Repository) error {
+		deposit := &database.Deposit{ChainId: 1, TxHash: txHash, UserId: userId, Amount: "100", Status: database.DepositStatusConfirmed}
+		if err := tx.SaveDeposit(ctx, deposit); err != nil {
+			return err
+		}
+		var err error
+		if event, err = AddEvent(ctx, tx, depositEvent(userId)); err != nil {
+			return err
+		}
+		if fail {
+			return errors.New("commit aborted")
+		}
+		return nil
+	})
+	return event, err
+}
+
+func TestRelayPublishesCommittedEventsOnly(t *testing.T) {
+	ctx := context.Background()
+	repo := openStore(t)
+	publisher := &memoryPublisher{}
+	relay := NewRelay(repo, publisher, RelayConfig{})
+
+	committed, err := saveDeposit(t, repo, "u1", "0x01", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := saveDeposit(t, repo, "u2", "0x02", true); err == nil {
+		t.Fatal("expected aborted transaction")
+	}
+
+	count, err := relay.PublishPending(ctx)
+	if err != nil || count != 1 {
+		t.Fatalf("PublishPending() = %d %v", count, err)
+	}
+	msgs := publisher.published()
+	if len(msgs) != 1 || msgs[0].Id != committed.Id || msgs[0].Topic != "wallet.deposit.confirmed" || msgs[0].Key != "u1" {
+		t.Fatalf("published = %+v", msgs)
+	}
+	var event events.Event
+	if err := json.Unmarshal(msgs[0].Payload, &event); err != nil || event.Deposit.TxHash != "0x01" {
+		t.Errorf("payload = %s %v", msgs[0].Payload, err)
+	}
+	if pending, _ := repo.ListOutboxMessages(ctx, 0); len(pending) != 0 {
+		t.Errorf("outbox not drained: %+v", pending)
+	}
+}
+
+func TestRelayKeepsEventsWhileBrokerDown(t *testing.T) {
+	ctx := context.Background()
+	repo := openStore(t)
+	publisher := &memoryPublisher{down: true}
+	relay := NewRelay(repo, publisher, RelayConfig{})
+
+	var ids []string
+	for _, user := range []string{"u1", "u2", "u3"} {
+		event, err := saveDeposit(t, repo, user, "0x"+user, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, event.Id)
+	}
+
+	if count, err := relay.PublishPending(ctx); err == nil || count != 0 {
+		t.Fatalf("PublishPending() with broker down = %d %v", count, err)
+	}
+	pending, err := repo.ListOutboxMessages(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 3 || pending[0].Attempts != 1 || pending[0].LastError != "broker unavailable" || pending[1].Attempts != 0 {
+		t.Fatalf("outbox after failure = %+v", pending)
+	}
+
+	// broker 恢复后按写入顺序发布
+	publisher.setDown(false)
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		relay.Run(runCtx)
+		close(done)
+	}()
+	relay.Notify()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publisher.published()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("relay did not publish after broker recovered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	for i, msg := range publisher.published() {
+		if msg.Id != ids[i] {
+			t.Errorf("message %d = %s, want %s", i, msg.Id, ids[i])
+		}
+	}
+}
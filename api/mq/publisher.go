@@ -0,0 +1,21 @@
+// Package mq 通过事务性发件箱将钱包事件发布到外部消息队列
+// 业务数据与待发布事件在同一数据库事务中写入发件箱，由 Relay 异步发布：未提交的数据不会产生事件，
+// 消息队列不可用时事件留在发件箱中等待重试。投递语义为至少一次，下游需按事件 ID 去重
+package mq
+
+import "context"
+
+// Message 发布到消息队列的一条消息
+type Message struct {
+	Topic   string // Kafka 的 topic、NATS 的 subject 或 Redis 的 stream key
+	Key     string // 分区键，同一用户的事件进入同一分区以保持顺序
+	Id      string // 事件 ID
+	Type    string
+	Payload []byte // events.Event 的 JSON 编码
+}
+
+// Publisher 消息队列适配器，Publish 返回 nil 表示消息已被 broker 确认，之后才会从发件箱删除
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+	Close() error
+}
@@ -0,0 +1,41 @@
+// Package redis 基于 Redis Streams 的发布适配器
+package redis
+
+import (
+	"context"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+)
+
+// Publisher 以 XADD 写入与主题同名的 stream，字段为 id、type、key、payload
+// Redis 不对消息去重，且只有开启 AOF 持久化时确认的消息才不会因重启丢失
+type Publisher struct {
+	client goredis.UniversalClient
+	maxLen int64
+}
+
+var _ mq.Publisher = (*Publisher)(nil)
+
+// NewPublisher maxLen 大于 0 时近似裁剪 stream 长度，防止无人消费时无限增长
+func NewPublisher(client goredis.UniversalClient, maxLen int64) *Publisher {
+	return &Publisher{client: client, maxLen: maxLen}
+}
+
+func (p *Publisher) Publish(ctx context.Context, msg *mq.Message) error {
+	return p.client.XAdd(ctx, &goredis.XAddArgs{
+		Stream: msg.Topic,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: map[string]any{
+			"id":      msg.Id,
+			"type":    msg.Type,
+			"key":     msg.Key,
+			"payload": msg.Payload,
+		},
+	}).Err()
+}
+
+func (p *Publisher) Close() error {
+	return p.client.Close()
+}
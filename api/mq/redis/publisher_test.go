@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+)
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	publisher := NewPublisher(client, 2)
+	defer publisher.Close()
+
+	for _, id := range []string{"e1", "e2", "e3"} {
+		msg := &mq.Message{Topic: "wallet.withdrawal.status", Key: "u1", Id: id, Type: "withdrawal.status", Payload: []byte(`{"id":"` + id + `"}`)}
+		if err := publisher.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := client.XRange(ctx, "wallet.withdrawal.status", "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// MaxLen 为 2，最早的消息被裁剪
+	if len(entries) != 2 {
+		t.Fatalf("stream length = %d, want 2", len(entries))
+	}
+	last := entries[1].Values
+	if last["id"] != "e3" || last["type"] != "withdrawal.status" || last["key"] != "u1" || last["payload"] != `{"id":"e3"}` {
+		t.Errorf("last entry = %+v", last)
+	}
+
+	server.Close()
+	if err := publisher.Publish(ctx, &mq.Message{Topic: "wallet.withdrawal.status", Id: "e4"}); err == nil {
+		t.Error("Publish() with redis down should fail")
+	}
+}
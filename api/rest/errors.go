@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+)
+
+// maxBodyBytes 请求体大小上限
+const maxBodyBytes = 1 << 20
+
+// statusByCode 业务错误码对应的 HTTP 状态码
+var statusByCode = map[service.Code]int{
+	service.CodeInvalidArgument:     http.StatusBadRequest,
+	service.CodeNotFound:            http.StatusNotFound,
+	service.CodeAlreadyExists:       http.StatusConflict,
+	service.CodeInsufficientBalance: http.StatusUnprocessableEntity,
+	service.CodeDestinationBlocked:  http.StatusUnprocessableEntity,
+	service.CodeUnavailable:         http.StatusServiceUnavailable,
+	service.CodeInternal:            http.StatusInternalServerError,
+}
+
+// errorResponse 所有错误响应的格式均为 {"error": {"code": ..., "message": ...}}
+type errorResponse struct {
+	Error *service.Error `json:"error"`
+}
+
+func invalidArgument(format string, args ...any) *service.Error {
+	return &service.Error{Code: service.CodeInvalidArgument, Message: fmt.Sprintf(format, args...)}
+}
+
+// writeError 输出统一格式的错误，非业务错误只记录日志，不把细节返回给调用方
+func writeError(w http.ResponseWriter, err error) {
+	var serviceErr *service.Error
+	if !errors.As(err, &serviceErr) {
+		log.Printf("rest: internal error: %v", err)
+		serviceErr = &service.Error{Code: service.CodeInternal, Message: "internal server error"}
+	}
+	status, ok := statusByCode[serviceErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, errorResponse{Error: serviceErr})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("rest: write response: %v", err)
+	}
+}
+
+// decodeJSON 解析请求体，拒绝未知字段与多余内容
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return invalidArgument("invalid request body: %v", err)
+	}
+	if decoder.More() {
+		return invalidArgument("invalid request body: unexpected data after JSON object")
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package rest
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+type allocateAddressRequest struct {
+	UserId string `json:"user_id"`
+	Family string `json:"family"`
+}
+
+type addressResponse struct {
+	Address   string    `json:"address"`
+	Family    string    `json:"family"`
+	Kind      string    `json:"kind"`
+	UserId    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type depositResponse struct {
+	ChainId     uint64    `json:"chain_id"`
+	TxHash      string    `json:"tx_hash"`
+	LogIndex    uint64    `json:"log_index"`
+	BlockNumber uint64    `json:"block_number"`
+	BlockHash   string    `json:"block_hash"`
+	Token       string    `json:"token,omitempty"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Amount      string    `json:"amount"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type balanceResponse struct {
+	ChainId uint64 `json:"chain_id"`
+	Token   string `json:"token,omitempty"`
+	Amount  string `json:"amount"`
+}
+
+type submitWithdrawalRequest struct {
+	Id     string `json:"id"`
+	UserId string `json:"user_id"`
+	Token  string `json:"token"`
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+type withdrawalResponse struct {
+	Id        string    `json:"id"`
+	ChainId   uint64    `json:"chain_id"`
+	UserId    string    `json:"user_id"`
+	Token     string    `json:"token,omitempty"`
+	To        string    `json:"to"`
+	Amount    string    `json:"amount"`
+	Fee       string    `json:"fee,omitempty"`
+	Status    string    `json:"status"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+	Warning   string    `json:"warning,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type chainBalanceResponse struct {
+	Address string `json:"address"`
+	Token   string `json:"token,omitempty"`
+	Balance string `json:"balance"`
+}
+
+type transactionResponse struct {
+	Transaction *WalletTypes.TransactionSummary `json:"transaction"`
+	Status      string                          `json:"status"` // pending / success / failed
+	BlockNumber uint64                          `json:"block_number,omitempty"`
+	GasUsed     uint64                          `json:"gas_used,omitempty"`
+}
+
+type listResponse[T any] struct {
+	Items []T `json:"items"`
+}
+
+func (s *Server) handleAllocateAddress(w http.ResponseWriter, r *http.Request) {
+	var req allocateAddressRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	address, err := s.service.AllocateAddress(r.Context(), req.UserId, req.Family)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toAddressResponse(address))
+}
+
+func (s *Server) handleListAddresses(w http.ResponseWriter, r *http.Request) {
+	addresses, err := s.service.ListAddresses(r.Context(), r.PathValue("user_id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items := make([]addressResponse, len(addresses))
+	for i := range addresses {
+		items[i] = toAddressResponse(&addresses[i])
+	}
+	writeJSON(w, http.StatusOK, listResponse[addressResponse]{Items: items})
+}
+
+func (s *Server) handleListDeposits(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+			writeError(w, invalidArgument("limit must be between 1 and %d", service.MaxListLimit))
+			return
+		}
+	}
+	deposits, err := s.service.ListDeposits(r.Context(), r.PathValue("user_id"), limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items := make([]depositResponse, len(deposits))
+	for i, d := range deposits {
+		items[i] = depositResponse{
+			ChainId:     d.ChainId,
+			TxHash:      d.TxHash,
+			LogIndex:    d.LogIndex,
+			BlockNumber: d.BlockNumber,
+			BlockHash:   d.BlockHash,
+			Token:       d.Token,
+			From:        d.From,
+			To:          d.To,
+			Amount:      d.Amount,
+			Status:      d.Status,
+			CreatedAt:   d.CreatedAt,
+		}
+	}
+	writeJSON(w, http.StatusOK, listResponse[depositResponse]{Items: items})
+}
+
+func (s *Server) handleUserBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := s.service.UserBalances(r.PathValue("user_id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items := make([]balanceResponse, len(balances))
+	for i, balance := range balances {
+		items[i] = toBalanceResponse(balance)
+	}
+	writeJSON(w, http.StatusOK, listResponse[balanceResponse]{Items: items})
+}
+
+func (s *Server) handleSubmitWithdrawal(w http.ResponseWriter, r *http.Request) {
+	var req submitWithdrawalRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := s.service.SubmitWithdrawal(r.Context(), service.WithdrawalRequest{
+		Id:     req.Id,
+		UserId: req.UserId,
+		Token:  req.Token,
+		To:     req.To,
+		Amount: req.Amount,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := toWithdrawalResponse(result.Withdrawal)
+	resp.Fee = result.Fee.String()
+	resp.Warning = result.Warning
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+func (s *Server) handleGetWithdrawal(w http.ResponseWriter, r *http.Request) {
+	withdrawal, err := s.service.GetWithdrawal(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toWithdrawalResponse(withdrawal))
+}
+
+func (s *Server) handleChainBalance(w http.ResponseWriter, r *http.Request) {
+	address, token, balance, err := s.service.ChainBalance(r.Context(), r.PathValue("address"), r.URL.Query().Get("token"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := chainBalanceResponse{Address: address.Hex(), Balance: balance.String()}
+	if token != (common.Address{}) {
+		resp.Token = token.Hex()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetTransaction(w http.ResponseWriter, r *http.Request) {
+	status, err := s.service.Transaction(r.Context(), r.PathValue("hash"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transactionResponse{
+		Transaction: status.Summary,
+		Status:      status.Status,
+		BlockNumber: status.BlockNumber,
+		GasUsed:     status.GasUsed,
+	})
+}
+
+func toAddressResponse(address *database.Address) addressResponse {
+	return addressResponse{
+		Address:   address.Address,
+		Family:    address.Family,
+		Kind:      address.Kind,
+		UserId:    address.UserId,
+		CreatedAt: address.CreatedAt,
+	}
+}
+
+func toBalanceResponse(balance ledger.Balance) balanceResponse {
+	resp := balanceResponse{ChainId: balance.Account.Asset.ChainId, Amount: balance.Amount.String()}
+	if balance.Account.Asset.Token != (common.Address{}) {
+		resp.Token = balance.Account.Asset.Token.Hex()
+	}
+	return resp
+}
+
+func toWithdrawalResponse(withdrawal *database.Withdrawal) withdrawalResponse {
+	return withdrawalResponse{
+		Id:        withdrawal.Id,
+		ChainId:   withdrawal.ChainId,
+		UserId:    withdrawal.UserId,
+		Token:     withdrawal.Token,
+		To:        withdrawal.To,
+		Amount:    withdrawal.Amount,
+		Status:    withdrawal.Status,
+		TxHash:    withdrawal.TxHash,
+		CreatedAt: withdrawal.CreatedAt,
+		UpdatedAt: withdrawal.UpdatedAt,
+	}
+}
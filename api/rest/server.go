@@ -0,0 +1,89 @@
+// Package rest 钱包 HTTP API，接口定义见 openapi.yaml
+package rest
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// Server 钱包 HTTP API 服务
+type Server struct {
+	service *service.Service
+	handler http.Handler
+	server  *http.Server
+}
+
+func NewServer(svc *service.Service) *Server {
+	s := &Server{service: svc}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.yaml", s.handleOpenAPI)
+	mux.HandleFunc("POST /v1/addresses", s.handleAllocateAddress)
+	mux.HandleFunc("GET /v1/users/{user_id}/addresses", s.handleListAddresses)
+	mux.HandleFunc("GET /v1/users/{user_id}/deposits", s.handleListDeposits)
+	mux.HandleFunc("GET /v1/users/{user_id}/balances", s.handleUserBalances)
+	mux.HandleFunc("POST /v1/withdrawals", s.handleSubmitWithdrawal)
+	mux.HandleFunc("GET /v1/withdrawals/{id}", s.handleGetWithdrawal)
+	mux.HandleFunc("GET /v1/chain/addresses/{address}/balance", s.handleChainBalance)
+	mux.HandleFunc("GET /v1/chain/transactions/{hash}", s.handleGetTransaction)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &service.Error{Code: service.CodeNotFound, Message: fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path)})
+	})
+	s.handler = recoverMiddleware(mux)
+	return s
+}
+
+// Handler 返回 API 的 http.Handler，便于挂载到已有的 HTTP 服务或测试
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// ListenAndServe 在 addr 上启动服务，直到 Shutdown 被调用
+func (s *Server) ListenAndServe(addr string) error {
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("rest: listening on %s", addr)
+	if err := s.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 停止接收新请求并等待进行中的请求完成
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+// recoverMiddleware 处理函数 panic 时返回统一的 500 错误而不是断开连接
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				log.Printf("rest: panic serving %s %s: %v", r.Method, r.URL.Path, v)
+				writeError(w, &service.Error{Code: service.CodeInternal, Message: "internal server error"})
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
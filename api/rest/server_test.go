@@ -0,0 +1,251 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	"github.com/0xweb-3/EthCEXWallet/wallet/pool"
+)
+
+var (
+	testChainId = big.NewInt(11155111)
+	testHot     = common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	testTo      = "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74"
+)
+
+type apiClient struct {
+	node.EthClient
+	tx *types.Transaction
+}
+
+func (c *apiClient) TxByHash(ctx context.Context, hash common.Hash) (*types.Transaction, error) {
+	if c.tx == nil || c.tx.Hash() != hash {
+		return nil, ethereum.NotFound
+	}
+	return c.tx, nil
+}
+
+func (c *apiClient) TxReceiptByHash(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	return &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(42), GasUsed: 21000}, nil
+}
+
+func (c *apiClient) BalancesAt(ctx context.Context, addresses []common.Address, blockNumber *big.Int) ([]*big.Int, error) {
+	return []*big.Int{big.NewInt(12345)}, nil
+}
+
+func newTestServer(t *testing.T, client node.EthClient) (*Server, *ledger.Ledger) {
+	t.Helper()
+	repo, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return newTestServerWithRepository(t, client, repo)
+}
+
+// newTestServerWithRepository 使用已有的存储创建服务，模拟进程重启
+func newTestServerWithRepository(t *testing.T, client node.EthClient, repo database.Repository) (*Server, *ledger.Ledger) {
+	t.Helper()
+	addressPool := pool.NewPool(pool.Config{Family: "evm", Target: 2}, pool.SourceFunc(WalletEthereum.CreateAddress))
+	if err := addressPool.Refill(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l := ledger.NewLedger()
+	svc, err := service.New(service.Options{
+		ChainId:       testChainId,
+		Client:        client,
+		Repository:    repo,
+		Addresses:     pool.NewService(addressPool),
+		Ledger:        l,
+		WithdrawalFee: map[common.Address]*big.Int{{}: big.NewInt(10)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(svc), l
+}
+
+func do(t *testing.T, server *Server, method, path string, body any, wantStatus int, out any) {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, &reader))
+	if rec.Code != wantStatus {
+		t.Fatalf("%s %s status = %d, want %d, body %s", method, path, rec.Code, wantStatus, rec.Body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAllocateAddress(t *testing.T) {
+	repo, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	server, _ := newTestServerWithRepository(t, &apiClient{}, repo)
+
+	var first, second addressResponse
+	do(t, server, http.MethodPost, "/v1/addresses", map[string]string{"user_id": "u1"}, http.StatusOK, &first)
+	do(t, server, http.MethodPost, "/v1/addresses", map[string]string{"user_id": "u1"}, http.StatusOK, &second)
+	if first.Address == "" || first.Address != second.Address || first.Kind != "deposit" {
+		t.Errorf("allocate = %+v, %+v", first, second)
+	}
+
+	var list listResponse[addressResponse]
+	do(t, server, http.MethodGet, "/v1/users/u1/addresses", nil, http.StatusOK, &list)
+	if len(list.Items) != 1 || list.Items[0].Address != first.Address {
+		t.Errorf("list = %+v", list)
+	}
+
+	var errResp errorResponse
+	do(t, server, http.MethodPost, "/v1/addresses", map[string]string{"user_id": "u1", "family": "tron"}, http.StatusBadRequest, &errResp)
+	if errResp.Error.Code != service.CodeInvalidArgument {
+		t.Errorf("error = %+v", errResp.Error)
+	}
+	do(t, server, http.MethodPost, "/v1/addresses", map[string]string{"user_id": "u1", "private_key": "x"}, http.StatusBadRequest, nil)
+
+	// 重启后地址池中没有分配关系，已分配的用户仍得到原地址
+	restarted, _ := newTestServerWithRepository(t, &apiClient{}, repo)
+	var again addressResponse
+	do(t, restarted, http.MethodPost, "/v1/addresses", map[string]string{"user_id": "u1"}, http.StatusOK, &again)
+	if again.Address != first.Address {
+		t.Errorf("allocate after restart = %s, want %s", again.Address, first.Address)
+	}
+}
+
+func TestSubmitWithdrawal(t *testing.T) {
+	repo, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	ctx := context.Background()
+	server, l := newTestServerWithRepository(t, &apiClient{}, repo)
+	deposit := common.HexToAddress("0x01C9E6bdb351AD536236b508092f49eDEe5be0e6")
+	asset := ledger.Asset{ChainId: testChainId.Uint64()}
+	_, err = l.PostWith(ledger.DepositEntry("u1", deposit, asset, big.NewInt(1000), common.HexToHash("0x01"), 0), func(posted *ledger.Entry) error {
+		return ledger.SaveEntry(ctx, repo, posted)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []map[string]string{
+		{"id": "w1", "user_id": "u1", "to": testTo, "amount": "-5"},
+		{"id": "w1", "user_id": "u1", "to": testTo, "amount": "1.5"},
+		{"id": "w1", "user_id": "u1", "to": "0x1234", "amount": "100"},
+		{"id": "w1", "user_id": "u1", "to": "0x0000000000000000000000000000000000000000", "amount": "100"},
+		{"id": "w 1", "user_id": "u1", "to": testTo, "amount": "100"},
+		{"id": "w1", "user_id": "u1", "to": testTo, "amount": "10"},
+	}
+	for _, req := range invalid {
+		var errResp errorResponse
+		do(t, server, http.MethodPost, "/v1/withdrawals", req, http.StatusBadRequest, &errResp)
+		if errResp.Error == nil || errResp.Error.Code != service.CodeInvalidArgument {
+			t.Errorf("request %v error = %+v", req, errResp.Error)
+		}
+	}
+
+	var errResp errorResponse
+	do(t, server, http.MethodPost, "/v1/withdrawals", map[string]string{"id": "w0", "user_id": "u1", "to": testTo, "amount": "5000"}, http.StatusUnprocessableEntity, &errResp)
+	if errResp.Error.Code != service.CodeInsufficientBalance {
+		t.Errorf("error = %+v", errResp.Error)
+	}
+
+	var withdrawal withdrawalResponse
+	do(t, server, http.MethodPost, "/v1/withdrawals", map[string]string{"id": "w1", "user_id": "u1", "to": testTo, "amount": "600"}, http.StatusAccepted, &withdrawal)
+	if withdrawal.Amount != "590" || withdrawal.Fee != "10" || withdrawal.Status != "created" {
+		t.Errorf("withdrawal = %+v", withdrawal)
+	}
+	// 重复提交不重复扣款
+	do(t, server, http.MethodPost, "/v1/withdrawals", map[string]string{"id": "w1", "user_id": "u1", "to": testTo, "amount": "600"}, http.StatusConflict, nil)
+	if got := l.Balance(ledger.UserAccount("u1", asset)); got.Int64() != 400 {
+		t.Errorf("user balance = %v, want 400", got)
+	}
+
+	// 提现记录写入失败时扣款分录随事务回滚
+	if err := repo.CreateWithdrawal(ctx, &database.Withdrawal{Id: "w2", UserId: "u1", Status: database.WithdrawalStatusCreated}); err != nil {
+		t.Fatal(err)
+	}
+	do(t, server, http.MethodPost, "/v1/withdrawals", map[string]string{"id": "w2", "user_id": "u1", "to": testTo, "amount": "100"}, http.StatusConflict, nil)
+	if got := l.Balance(ledger.UserAccount("u1", asset)); got.Int64() != 400 {
+		t.Errorf("user balance after failed write = %v, want 400", got)
+	}
+	reloaded, err := ledger.Load(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Balance(ledger.UserAccount("u1", asset)); got.Int64() != 400 {
+		t.Errorf("reloaded user balance = %v, want 400", got)
+	}
+
+	do(t, server, http.MethodGet, "/v1/withdrawals/w1", nil, http.StatusOK, &withdrawal)
+	if withdrawal.To != testTo {
+		t.Errorf("get withdrawal = %+v", withdrawal)
+	}
+	do(t, server, http.MethodGet, "/v1/withdrawals/missing", nil, http.StatusNotFound, &errResp)
+
+	var balances listResponse[balanceResponse]
+	do(t, server, http.MethodGet, "/v1/users/u1/balances", nil, http.StatusOK, &balances)
+	if len(balances.Items) != 1 || balances.Items[0].Amount != "400" {
+		t.Errorf("balances = %+v", balances)
+	}
+}
+
+func TestChainQueries(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA("17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4")
+	to := common.HexToAddress(testTo)
+	tx, err := types.SignNewTx(privateKey, types.NewLondonSigner(testChainId), &types.DynamicFeeTx{
+		ChainID: testChainId, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, To: &to, Value: big.NewInt(5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, _ := newTestServer(t, &apiClient{tx: tx})
+
+	var txResp transactionResponse
+	do(t, server, http.MethodGet, "/v1/chain/transactions/"+tx.Hash().Hex(), nil, http.StatusOK, &txResp)
+	if txResp.Status != "success" || txResp.BlockNumber != 42 || txResp.Transaction.From != testHot.Hex() {
+		t.Errorf("transaction = %+v", txResp)
+	}
+	do(t, server, http.MethodGet, "/v1/chain/transactions/"+common.Hash{}.Hex(), nil, http.StatusNotFound, nil)
+	do(t, server, http.MethodGet, "/v1/chain/transactions/0x12", nil, http.StatusBadRequest, nil)
+
+	var balance chainBalanceResponse
+	do(t, server, http.MethodGet, "/v1/chain/addresses/"+testTo+"/balance", nil, http.StatusOK, &balance)
+	if balance.Balance != "12345" {
+		t.Errorf("balance = %+v", balance)
+	}
+
+	var errResp errorResponse
+	do(t, server, http.MethodGet, "/v1/unknown", nil, http.StatusNotFound, &errResp)
+	if errResp.Error.Code != service.CodeNotFound {
+		t.Errorf("error = %+v", errResp.Error)
+	}
+	do(t, server, http.MethodGet, "/openapi.yaml", nil, http.StatusOK, nil)
+}
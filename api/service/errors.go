@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code 业务错误码，REST 与 gRPC 各自映射为 HTTP 状态码与 gRPC 状态码
+type Code string
+
+const (
+	CodeInvalidArgument     Code = "invalid_argument"
+	CodeNotFound            Code = "not_found"
+	CodeAlreadyExists       Code = "already_exists"
+	CodeInsufficientBalance Code = "insufficient_balance"
+	CodeDestinationBlocked  Code = "destination_blocked"
+	CodeUnavailable         Code = "unavailable"
+	CodeInternal            Code = "internal"
+)
+
+// Error 可以直接返回给调用方的业务错误，其他错误一律视为内部错误
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func newError(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorCode 返回错误对应的错误码，非 *Error 返回 CodeInternal
+func ErrorCode(err error) Code {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+	return CodeInternal
+}
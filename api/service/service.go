@@ -0,0 +1,512 @@
+// Package service 钱包对外业务逻辑，REST 与 gRPC 共用，负责参数校验与错误归类
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/destination"
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	"github.com/0xweb-3/EthCEXWallet/wallet/pool"
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+const (
+	DefaultFamily    = "evm"
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+var (
+	idPattern     = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+	amountPattern = regexp.MustCompile(`^[0-9]{1,78}$`)
+	hashPattern   = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+)
+
+// Options 服务依赖，Destination、Events、Outbox 与 Webhooks 可为空，为空时跳过提现目标检查与对应的事件发布
+type Options struct {
+	ChainId     *big.Int
+	Client      node.EthClient
+	Repository  database.Repository
+	Addresses   *pool.Service
+	Ledger      *ledger.Ledger
+	Destination *destination.Classifier
+	Events      *events.Bus
+	// Outbox 不为空时提现状态事件与提现记录在同一事务中写入发件箱，由 Relay 发布到消息队列
+	Outbox *mq.Relay
+	// Webhooks 不为空时 webhook 投递与提现记录在同一事务中写入
+	Webhooks EventRecorder
+	// WithdrawalFee 按代币收取的提现手续费，零地址为原生币，未配置的代币不收费
+	WithdrawalFee map[common.Address]*big.Int
+}
+
+// EventRecorder 在业务事务中持久化事件的下游，由 webhook.Notifier 实现；webhook 包引用了本包的错误类型，这里只依赖接口
+type EventRecorder interface {
+	AddEvent(ctx context.Context, repo database.Repository, event events.Event) (events.Event, error)
+	Notify()
+}
+
+// Service 钱包业务服务
+type Service struct {
+	opts Options
+}
+
+func New(opts Options) (*Service, error) {
+	if opts.ChainId == nil || opts.Client == nil || opts.Repository == nil || opts.Addresses == nil || opts.Ledger == nil {
+		return nil, errors.New("service: chain id, client, repository, address pool and ledger are required")
+	}
+	return &Service{opts: opts}, nil
+}
+
+func (s *Service) ChainId() uint64 {
+	return s.opts.ChainId.Uint64()
+}
+
+// Events 返回事件总线，未配置时为 nil
+func (s *Service) Events() *events.Bus {
+	return s.opts.Events
+}
+
+// WithdrawalRequest 提现请求，金额为代币最小单位的十进制字符串，Token 为空表示原生币
+type WithdrawalRequest struct {
+	Id     string
+	UserId string
+	Token  string
+	To     string
+	Amount string
+}
+
+// WithdrawalResult 受理的提现，Withdrawal.Amount 为扣除手续费后的实际出款金额
+type WithdrawalResult struct {
+	Withdrawal *database.Withdrawal
+	Fee        *big.Int
+	Warning    string // 目标地址检查的提示，如目标为合约
+}
+
+// TransactionStatus 链上交易及执行状态
+type TransactionStatus struct {
+	Summary     *WalletTypes.TransactionSummary
+	Status      string // pending / success / failed
+	BlockNumber uint64
+	GasUsed     uint64
+}
+
+// AllocateAddress 为用户分配充值地址，已分配过的用户返回原地址
+func (s *Service) AllocateAddress(ctx context.Context, userId, family string) (*database.Address, error) {
+	if err := validateId("user_id", userId); err != nil {
+		return nil, err
+	}
+	if family == "" {
+		family = DefaultFamily
+	}
+	if _, err := s.opts.Addresses.Pool(family); err != nil {
+		return nil, newError(CodeInvalidArgument, "unknown address family %q", family)
+	}
+
+	// 以数据库中的分配为准，地址池的内存状态在重启后可能不完整
+	existing, err := s.opts.Repository.ListAddressesByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range existing {
+		if existing[i].Family == family && existing[i].Kind == database.AddressKindDeposit {
+			return &existing[i], nil
+		}
+	}
+
+	assigned, err := s.opts.Addresses.Assign(family, userId)
+	switch {
+	case errors.Is(err, pool.ErrPoolEmpty):
+		return nil, newError(CodeUnavailable, "address pool is refilling, retry later")
+	case err != nil:
+		return nil, err
+	}
+
+	address := &database.Address{
+		Address: common.HexToAddress(assigned.Address).Hex(),
+		Family:  family,
+		Kind:    database.AddressKindDeposit,
+		UserId:  userId,
+	}
+	// 同一用户并发申请时地址池返回同一地址，记录已存在
+	if err := s.opts.Repository.CreateAddress(ctx, address); err != nil && !errors.Is(err, database.ErrAlreadyExists) {
+		s.opts.Addresses.Release(family, userId)
+		return nil, err
+	}
+	record, err := s.opts.Repository.GetAddress(ctx, address.Address)
+	if err != nil {
+		return nil, err
+	}
+	if record.UserId != userId {
+		return nil, fmt.Errorf("address %s assigned to user %s is already recorded for another user", address.Address, userId)
+	}
+	return record, nil
+}
+
+func (s *Service) ListAddresses(ctx context.Context, userId string) ([]database.Address, error) {
+	if err := validateId("user_id", userId); err != nil {
+		return nil, err
+	}
+	return s.opts.Repository.ListAddressesByUser(ctx, userId)
+}
+
+// ListDeposits 按区块高度倒序返回用户充值，limit 为 0 时使用默认值
+func (s *Service) ListDeposits(ctx context.Context, userId string, limit int) ([]database.Deposit, error) {
+	if err := validateId("user_id", userId); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit {
+		return nil, newError(CodeInvalidArgument, "limit must be between 1 and %d", MaxListLimit)
+	}
+	return s.opts.Repository.ListDepositsByUser(ctx, userId, limit)
+}
+
+// EventPage 持久化事件的一页，NextPageToken 为空表示没有更多事件
+type EventPage struct {
+	Events        []events.Event
+	NextPageToken string
+}
+
+// ListEvents 按 (时间, Id) 正序返回 [from, to) 内持久化的事件，时间按秒比较，包括其他进程写入的事件
+// pageToken 为上一页的 NextPageToken；事件订阅的序号失效后用于从存储补齐，事件在配置了 Webhooks 时与业务数据一同写入
+func (s *Service) ListEvents(ctx context.Context, from, to time.Time, pageToken string, limit int) (*EventPage, error) {
+	if s.opts.Webhooks == nil {
+		return nil, newError(CodeUnavailable, "events are not persisted")
+	}
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit {
+		return nil, newError(CodeInvalidArgument, "limit must be between 1 and %d", MaxListLimit)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	var records []database.Event
+	if pageToken != "" {
+		second, afterId, ok := parseEventPageToken(pageToken)
+		if !ok {
+			return nil, newError(CodeInvalidArgument, "invalid page_token")
+		}
+		// 同一秒内的事件按 Id 排序，先取上一页最后一个事件之后的部分
+		if second < to.Unix() {
+			same, err := s.opts.Repository.ListEvents(ctx, time.Unix(second, 0), time.Unix(second+1, 0), 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, record := range same {
+				if record.Id > afterId {
+					records = append(records, record)
+				}
+			}
+		}
+		from = time.Unix(second+1, 0)
+	}
+	if len(records) <= limit && from.Unix() < to.Unix() {
+		more, err := s.opts.Repository.ListEvents(ctx, from, to, limit+1-len(records))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, more...)
+	}
+
+	page := &EventPage{}
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[limit-1]
+		page.NextPageToken = fmt.Sprintf("%d:%s", last.CreatedAt.Unix(), last.Id)
+	}
+	for _, record := range records {
+		var event events.Event
+		if err := json.Unmarshal([]byte(record.Payload), &event); err != nil {
+			return nil, fmt.Errorf("decode event %s: %w", record.Id, err)
+		}
+		page.Events = append(page.Events, event)
+	}
+	return page, nil
+}
+
+func parseEventPageToken(token string) (int64, string, bool) {
+	secondStr, id, ok := strings.Cut(token, ":")
+	if !ok || id == "" {
+		return 0, "", false
+	}
+	second, err := strconv.ParseInt(secondStr, 10, 64)
+	return second, id, err == nil
+}
+
+// UserBalances 返回用户在内部账本中的余额
+func (s *Service) UserBalances(userId string) ([]ledger.Balance, error) {
+	if err := validateId("user_id", userId); err != nil {
+		return nil, err
+	}
+	return s.opts.Ledger.UserBalances(userId), nil
+}
+
+// SubmitWithdrawal 受理提现：校验参数与目标地址，在同一事务中扣减用户余额并落库，签名与广播由出款任务完成
+func (s *Service) SubmitWithdrawal(ctx context.Context, req WithdrawalRequest) (*WithdrawalResult, error) {
+	if err := validateId("id", req.Id); err != nil {
+		return nil, err
+	}
+	if err := validateId("user_id", req.UserId); err != nil {
+		return nil, err
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !amountPattern.MatchString(req.Amount) || !ok || amount.Sign() <= 0 {
+		return nil, newError(CodeInvalidArgument, "amount must be a positive integer in the token's smallest unit")
+	}
+	token, err := parseToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	validation, err := WalletEthereum.ValidateAddress(req.To, s.opts.ChainId)
+	if err != nil {
+		return nil, newError(CodeInvalidArgument, "to: %v", err)
+	}
+	if validation.Kind != "" {
+		return nil, newError(CodeInvalidArgument, "to is a %s address", validation.Kind)
+	}
+	to := common.HexToAddress(validation.Address)
+
+	var warning string
+	if s.opts.Destination != nil {
+		decision, err := s.opts.Destination.CheckWithdrawal(ctx, to, token)
+		if err != nil {
+			return nil, upstreamError(err)
+		}
+		switch decision.Action {
+		case destination.ActionBlock:
+			return nil, newError(CodeDestinationBlocked, "%s", decision.Reason)
+		case destination.ActionWarn:
+			warning = decision.Reason
+		}
+	}
+
+	fee := new(big.Int)
+	if configured, ok := s.opts.WithdrawalFee[token]; ok {
+		fee.Set(configured)
+	}
+	if fee.Cmp(amount) >= 0 {
+		return nil, newError(CodeInvalidArgument, "amount must exceed withdrawal fee %v", fee)
+	}
+
+	asset := ledger.Asset{ChainId: s.ChainId(), Token: token}
+	entry, err := ledger.WithdrawalEntry(req.Id, req.UserId, asset, amount, fee)
+	if err != nil {
+		return nil, newError(CodeInvalidArgument, "%v", err)
+	}
+	// 分录与提现记录同时提交，分录已存在即提现已受理
+	if _, ok := s.opts.Ledger.Entry(entry.Id); ok {
+		return nil, newError(CodeAlreadyExists, "withdrawal %s already exists", req.Id)
+	}
+
+	withdrawal := &database.Withdrawal{
+		Id:      req.Id,
+		ChainId: s.ChainId(),
+		UserId:  req.UserId,
+		Token:   tokenString(token),
+		To:      to.Hex(),
+		Amount:  new(big.Int).Sub(amount, fee).String(),
+		Status:  database.WithdrawalStatusCreated,
+	}
+	// 扣款分录在写入提现记录的事务中持久化，事务失败时账本不变
+	_, err = s.opts.Ledger.PostWith(entry, func(posted *ledger.Entry) error {
+		return s.saveWithdrawal(ctx, withdrawal, func(ctx context.Context, repo database.Repository) error {
+			if err := repo.CreateWithdrawal(ctx, withdrawal); err != nil {
+				return err
+			}
+			return ledger.SaveEntry(ctx, repo, posted)
+		})
+	})
+	switch {
+	case errors.Is(err, ledger.ErrInsufficientBalance):
+		return nil, newError(CodeInsufficientBalance, "insufficient balance")
+	case errors.Is(err, ledger.ErrEntryConflict), errors.Is(err, database.ErrAlreadyExists):
+		return nil, newError(CodeAlreadyExists, "withdrawal %s already exists", req.Id)
+	case err != nil:
+		return nil, err
+	}
+	return &WithdrawalResult{Withdrawal: withdrawal, Fee: fee, Warning: warning}, nil
+}
+
+// UpdateWithdrawal 出款任务签名、广播或确认后更新提现记录，并发布状态事件
+func (s *Service) UpdateWithdrawal(ctx context.Context, withdrawal *database.Withdrawal) error {
+	return s.saveWithdrawal(ctx, withdrawal, func(ctx context.Context, repo database.Repository) error {
+		return repo.UpdateWithdrawal(ctx, withdrawal)
+	})
+}
+
+// saveWithdrawal 在事务中执行 write 写入提现记录；配置了发件箱或 webhook 时状态事件在同一事务中写入，
+// 保证未提交的记录不会产生事件，提交后再发布到进程内总线
+func (s *Service) saveWithdrawal(ctx context.Context, withdrawal *database.Withdrawal, write func(ctx context.Context, repo database.Repository) error) error {
+	event := events.Event{
+		Type:    events.WithdrawalStatus,
+		ChainId: withdrawal.ChainId,
+		Withdrawal: &events.Withdrawal{
+			Id:     withdrawal.Id,
+			UserId: withdrawal.UserId,
+			Token:  withdrawal.Token,
+			To:     withdrawal.To,
+			Amount: withdrawal.Amount,
+			Status: withdrawal.Status,
+			TxHash: withdrawal.TxHash,
+		},
+	}
+	err := s.opts.Repository.RunInTx(ctx, func(ctx context.Context, tx database.Repository) error {
+		if err := write(ctx, tx); err != nil {
+			return err
+		}
+		var err error
+		if s.opts.Outbox != nil {
+			if event, err = mq.AddEvent(ctx, tx, event); err != nil {
+				return err
+			}
+		}
+		if s.opts.Webhooks != nil {
+			event, err = s.opts.Webhooks.AddEvent(ctx, tx, event)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if s.opts.Outbox != nil {
+		s.opts.Outbox.Notify()
+	}
+	if s.opts.Webhooks != nil {
+		s.opts.Webhooks.Notify()
+	}
+
+	if s.opts.Events != nil {
+		if _, err := s.opts.Events.Publish(event); err != nil {
+			log.Printf("service: publish withdrawal %s status: %v", withdrawal.Id, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetWithdrawal(ctx context.Context, id string) (*database.Withdrawal, error) {
+	if err := validateId("id", id); err != nil {
+		return nil, err
+	}
+	withdrawal, err := s.opts.Repository.GetWithdrawal(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, newError(CodeNotFound, "withdrawal %s not found", id)
+	}
+	return withdrawal, err
+}
+
+// ChainBalance 查询地址在最新区块的余额，token 为空查询原生币
+func (s *Service) ChainBalance(ctx context.Context, addressStr, tokenStr string) (common.Address, common.Address, *big.Int, error) {
+	if !common.IsHexAddress(addressStr) {
+		return common.Address{}, common.Address{}, nil, newError(CodeInvalidArgument, "address is not a valid address")
+	}
+	address := common.HexToAddress(addressStr)
+	token, err := parseToken(tokenStr)
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, err
+	}
+
+	if token == (common.Address{}) {
+		balances, err := s.opts.Client.BalancesAt(ctx, []common.Address{address}, nil)
+		if err != nil {
+			return common.Address{}, common.Address{}, nil, upstreamError(err)
+		}
+		return address, token, balances[0], nil
+	}
+
+	result, err := s.opts.Client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: WalletEthereum.BuildErc20BalanceOfData(address)}, nil)
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, upstreamError(err)
+	}
+	balance, err := WalletEthereum.DecodeErc20BalanceOfResult(result)
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, newError(CodeInvalidArgument, "token %s does not implement balanceOf", token.Hex())
+	}
+	return address, token, balance, nil
+}
+
+// Transaction 查询链上交易，未打包的交易状态为 pending
+func (s *Service) Transaction(ctx context.Context, hashStr string) (*TransactionStatus, error) {
+	if !hashPattern.MatchString(hashStr) {
+		return nil, newError(CodeInvalidArgument, "hash must be 0x followed by 64 hex characters")
+	}
+	hash := common.HexToHash(hashStr)
+
+	tx, err := s.opts.Client.TxByHash(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, newError(CodeNotFound, "transaction %s not found", hash.Hex())
+	} else if err != nil {
+		return nil, upstreamError(err)
+	}
+	summary, err := WalletEthereum.SummarizeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TransactionStatus{Summary: summary, Status: "pending"}
+	receipt, err := s.opts.Client.TxReceiptByHash(ctx, hash)
+	switch {
+	case errors.Is(err, ethereum.NotFound):
+	case err != nil:
+		return nil, upstreamError(err)
+	default:
+		status.Status = "failed"
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			status.Status = "success"
+		}
+		status.BlockNumber = receipt.BlockNumber.Uint64()
+		status.GasUsed = receipt.GasUsed
+	}
+	return status, nil
+}
+
+func validateId(field, id string) error {
+	if !idPattern.MatchString(id) {
+		return newError(CodeInvalidArgument, "%s must match %s", field, idPattern)
+	}
+	return nil
+}
+
+func parseToken(token string) (common.Address, error) {
+	if token == "" {
+		return common.Address{}, nil
+	}
+	if !common.IsHexAddress(token) {
+		return common.Address{}, newError(CodeInvalidArgument, "token is not a valid address")
+	}
+	return common.HexToAddress(token), nil
+}
+
+// upstreamError 节点调用失败，记录原始错误，对外只返回 unavailable
+func upstreamError(err error) error {
+	log.Printf("service: node request failed: %v", err)
+	return newError(CodeUnavailable, "blockchain node unavailable")
+}
+
+func tokenString(token common.Address) string {
+	if token == (common.Address{}) {
+		return ""
+	}
+	return token.Hex()
+}
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+)
+
+// maxBodyBytes 请求体大小上限
+const maxBodyBytes = 1 << 20
+
+type registerRequest struct {
+	URL        string        `json:"url"`
+	EventTypes []events.Type `json:"event_types"`
+}
+
+type endpointResponse struct {
+	Id         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type replayRequest struct {
+	EventId    string    `json:"event_id"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	EndpointId string    `json:"endpoint_id"`
+}
+
+type deliveryResponse struct {
+	EndpointId    string    `json:"endpoint_id"`
+	EventId       string    `json:"event_id"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Handler webhook 管理接口，包含密钥与重放能力，只应挂载在内部管理端口上
+//
+//	POST   /v1/webhooks                   注册回调地址，响应中返回签名密钥
+//	GET    /v1/webhooks                   列出回调地址，不含密钥
+//	DELETE /v1/webhooks/{id}              停用回调地址
+//	POST   /v1/webhooks/replay            按 event_id 或 from/to 重放
+//	GET    /v1/events/{id}/deliveries     查询事件的投递状态
+func (n *Notifier) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/webhooks", n.handleRegister)
+	mux.HandleFunc("GET /v1/webhooks", n.handleList)
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", n.handleDisable)
+	mux.HandleFunc("POST /v1/webhooks/replay", n.handleReplay)
+	mux.HandleFunc("GET /v1/events/{id}/deliveries", n.handleDeliveries)
+	return mux
+}
+
+func (n *Notifier) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	endpoint, err := n.Register(r.Context(), req.URL, req.EventTypes)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toEndpointResponse(endpoint, true))
+}
+
+func (n *Notifier) handleList(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := n.Endpoints(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items := make([]endpointResponse, 0, len(endpoints))
+	for i := range endpoints {
+		items = append(items, toEndpointResponse(&endpoints[i], false))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (n *Notifier) handleDisable(w http.ResponseWriter, r *http.Request) {
+	if err := n.Disable(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (n *Notifier) handleReplay(w http.ResponseWriter, r *http.Request) {
+	var req replayRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	queued, err := n.Replay(r.Context(), ReplayRequest{EventId: req.EventId, From: req.From, To: req.To, EndpointId: req.EndpointId})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"queued": queued})
+}
+
+func (n *Notifier) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := n.Deliveries(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items := make([]deliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		items = append(items, deliveryResponse{
+			EndpointId:    d.EndpointId,
+			EventId:       d.EventId,
+			Status:        d.Status,
+			Attempts:      d.Attempts,
+			NextAttemptAt: d.NextAttemptAt,
+			LastError:     d.LastError,
+			UpdatedAt:     d.UpdatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func toEndpointResponse(endpoint *database.WebhookEndpoint, withSecret bool) endpointResponse {
+	resp := endpointResponse{
+		Id:         endpoint.Id,
+		URL:        endpoint.URL,
+		EventTypes: endpoint.EventTypes,
+		Active:     endpoint.Active,
+		CreatedAt:  endpoint.CreatedAt,
+	}
+	if resp.EventTypes == nil {
+		resp.EventTypes = []string{}
+	}
+	if withSecret {
+		resp.Secret = endpoint.Secret
+	}
+	return resp
+}
+
+// writeError 错误格式与 REST API 一致：{"error": {"code": ..., "message": ...}}
+func writeError(w http.ResponseWriter, err error) {
+	serviceErr := &service.Error{Code: service.CodeInternal, Message: "internal server error"}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &serviceErr):
+		status = http.StatusBadRequest
+	case errors.Is(err, database.ErrNotFound):
+		serviceErr = &service.Error{Code: service.CodeNotFound, Message: err.Error()}
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrUnknownEventType), errors.Is(err, ErrEndpointInactive),
+		errors.Is(err, ErrInvalidReplay), errors.Is(err, ErrReplayTooLarge):
+		serviceErr = &service.Error{Code: service.CodeInvalidArgument, Message: err.Error()}
+		status = http.StatusBadRequest
+	default:
+		log.Printf("webhook: internal error: %v", err)
+	}
+	writeJSON(w, status, map[string]*service.Error{"error": serviceErr})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("webhook: write response: %v", err)
+	}
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return &service.Error{Code: service.CodeInvalidArgument, Message: fmt.Sprintf("invalid request body: %v", err)}
+	}
+	return nil
+}
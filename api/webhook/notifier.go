@@ -0,0 +1,454 @@
+// Package webhook 将钱包事件以签名的 HTTP 回调投递给下游
+// 事件先写入持久化发件箱再投递，失败按指数退避重试，投递语义为至少一次，接收方需按事件 ID 去重
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+)
+
+var (
+	ErrInvalidURL        = errors.New("webhook url must be an absolute http or https url")
+	ErrUnknownEventType  = errors.New("unknown event type")
+	ErrEndpointInactive  = errors.New("webhook endpoint is inactive")
+	ErrInvalidReplay     = errors.New("replay requires an event id or a time range with from before to")
+	ErrReplayTooLarge    = errors.New("too many events in replay range, narrow the time range")
+	errEndpointResponded = errors.New("endpoint responded with non-2xx status")
+)
+
+// maxLastError 与表结构中 last_error 的长度一致
+const maxLastError = 512
+
+var knownTypes = map[events.Type]bool{
+	events.DepositPending:        true,
+	events.DepositPendingDropped: true,
+	events.DepositDetected:       true,
+	events.DepositConfirmed:      true,
+	events.WithdrawalStatus:      true,
+	events.ReorgRollback:         true,
+}
+
+// Store 通知器依赖的持久化接口，database.Repository 均已实现
+type Store interface {
+	database.EventStore
+	database.WebhookStore
+}
+
+type Config struct {
+	Workers      int           // 并发投递数，默认 4
+	BatchSize    int           // 每轮取出的到期投递数，默认 100
+	PollInterval time.Duration // 没有到期投递时的轮询间隔，默认 1s
+	Timeout      time.Duration // 单次请求超时，默认 10s
+	MaxAttempts  int           // 达到后标记为 failed，只能人工重放，默认 12
+	BaseBackoff  time.Duration // 首次重试间隔，之后每次翻倍，默认 10s
+	MaxBackoff   time.Duration // 重试间隔上限，默认 1h
+	MaxReplay    int           // 按时间范围重放时的事件数上限，默认 10000
+}
+
+// Notifier webhook 通知器，多个实例共用同一存储时同一投递可能被重复发送
+type Notifier struct {
+	store  Store
+	config Config
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewNotifier(store Store, config Config) *Notifier {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 12
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 10 * time.Second
+	}
+	if config.MaxBackoff < config.BaseBackoff {
+		config.MaxBackoff = max(time.Hour, config.BaseBackoff)
+	}
+	if config.MaxReplay <= 0 {
+		config.MaxReplay = 10000
+	}
+	return &Notifier{
+		store:  store,
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			// 不跟随重定向，避免签名请求被转发到注册地址以外的主机
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Register 注册回调地址并生成签名密钥，密钥只在返回值中出现一次，由调用方转交给接收方
+func (n *Notifier) Register(ctx context.Context, rawURL string, eventTypes []events.Type) (*database.WebhookEndpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	types := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !knownTypes[eventType] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+		}
+		types = append(types, string(eventType))
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	endpoint := &database.WebhookEndpoint{
+		Id:         uuid.NewString(),
+		URL:        u.String(),
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: types,
+		Active:     true,
+	}
+	if len(endpoint.EventTypes) == 0 {
+		endpoint.EventTypes = nil
+	}
+	if err := n.store.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// Disable 停用回调地址，未完成的投递在下次尝试时标记为 failed
+func (n *Notifier) Disable(ctx context.Context, endpointId string) error {
+	return n.store.SetWebhookEndpointActive(ctx, endpointId, false)
+}
+
+func (n *Notifier) Endpoints(ctx context.Context) ([]database.WebhookEndpoint, error) {
+	return n.store.ListWebhookEndpoints(ctx)
+}
+
+func (n *Notifier) Deliveries(ctx context.Context, eventId string) ([]database.WebhookDelivery, error) {
+	if _, err := n.store.GetEvent(ctx, eventId); err != nil {
+		return nil, err
+	}
+	return n.store.ListDeliveriesByEvent(ctx, eventId)
+}
+
+// Enqueue 将事件写入发件箱并为订阅了该类型的有效地址创建投递，重复调用是幂等的
+func (n *Notifier) Enqueue(ctx context.Context, event events.Event) error {
+	if _, err := n.addEvent(ctx, n.store, event); err != nil {
+		return err
+	}
+	n.Notify()
+	return nil
+}
+
+// AddEvent 与 Enqueue 相同但写入 repo，repo 应为 RunInTx 传入的 repo，使投递与业务数据一同提交，
+// 进程重启也不会丢失；事务提交后调用 Notify。返回补全了 Id 与时间的事件，可原样发布到进程内总线
+func (n *Notifier) AddEvent(ctx context.Context, repo database.Repository, event events.Event) (events.Event, error) {
+	return n.addEvent(ctx, repo, event)
+}
+
+func (n *Notifier) addEvent(ctx context.Context, store Store, event events.Event) (events.Event, error) {
+	if event.Id == "" {
+		event.Id = uuid.NewString()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	// Sequence 只在进程内总线中有意义
+	event.Sequence = 0
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return events.Event{}, err
+	}
+	record := &database.Event{
+		Id:        event.Id,
+		Type:      string(event.Type),
+		ChainId:   event.ChainId,
+		Payload:   string(payload),
+		CreatedAt: event.CreatedAt,
+	}
+	if err := store.SaveEvent(ctx, record); err != nil && !errors.Is(err, database.ErrAlreadyExists) {
+		return events.Event{}, err
+	}
+
+	endpoints, err := store.ListWebhookEndpoints(ctx)
+	if err != nil {
+		return events.Event{}, err
+	}
+	now := time.Now()
+	for _, endpoint := range endpoints {
+		if !endpoint.Active || !subscribes(&endpoint, record.Type) {
+			continue
+		}
+		delivery := &database.WebhookDelivery{
+			EndpointId:    endpoint.Id,
+			EventId:       record.Id,
+			Status:        database.DeliveryStatusPending,
+			NextAttemptAt: now,
+		}
+		if err := store.CreateDelivery(ctx, delivery); err != nil && !errors.Is(err, database.ErrAlreadyExists) {
+			return events.Event{}, err
+		}
+	}
+	return event, nil
+}
+
+// ReplayRequest 按事件 ID 或时间范围 [From, To) 重放，EndpointId 为空时重放到所有订阅该类型的有效地址
+type ReplayRequest struct {
+	EventId    string
+	From       time.Time
+	To         time.Time
+	EndpointId string
+}
+
+// Replay 将事件重新加入投递队列，已投递或已失败的记录会重置重试次数，返回加入队列的投递数
+func (n *Notifier) Replay(ctx context.Context, req ReplayRequest) (int, error) {
+	var records []database.Event
+	if req.EventId != "" {
+		event, err := n.store.GetEvent(ctx, req.EventId)
+		if err != nil {
+			return 0, err
+		}
+		records = append(records, *event)
+	} else {
+		if req.From.IsZero() || !req.From.Before(req.To) {
+			return 0, ErrInvalidReplay
+		}
+		var err error
+		records, err = n.store.ListEvents(ctx, req.From, req.To, n.config.MaxReplay+1)
+		if err != nil {
+			return 0, err
+		}
+		if len(records) > n.config.MaxReplay {
+			return 0, ErrReplayTooLarge
+		}
+	}
+
+	var endpoints []database.WebhookEndpoint
+	if req.EndpointId != "" {
+		endpoint, err := n.store.GetWebhookEndpoint(ctx, req.EndpointId)
+		if err != nil {
+			return 0, err
+		}
+		if !endpoint.Active {
+			return 0, ErrEndpointInactive
+		}
+		endpoints = append(endpoints, *endpoint)
+	} else {
+		all, err := n.store.ListWebhookEndpoints(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for _, endpoint := range all {
+			if endpoint.Active {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	queued := 0
+	now := time.Now()
+	for _, record := range records {
+		for _, endpoint := range endpoints {
+			if !subscribes(&endpoint, record.Type) {
+				continue
+			}
+			delivery := &database.WebhookDelivery{
+				EndpointId:    endpoint.Id,
+				EventId:       record.Id,
+				Status:        database.DeliveryStatusPending,
+				NextAttemptAt: now,
+			}
+			err := n.store.UpdateDelivery(ctx, delivery)
+			if errors.Is(err, database.ErrNotFound) {
+				err = n.store.CreateDelivery(ctx, delivery)
+			}
+			if err != nil {
+				return queued, err
+			}
+			queued++
+		}
+	}
+	n.Notify()
+	return queued, nil
+}
+
+// Run 持续投递到期的记录，直到 ctx 取消；事件由产生方通过 AddEvent 或 Enqueue 写入
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		count, err := n.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("webhook: deliver failed: %v", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// 整批取满说明还有积压，不等待直接处理下一批
+		if count == n.config.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-n.wake:
+		}
+	}
+}
+
+// DeliverDue 投递一批到期的记录并等待完成，返回本批记录数
+func (n *Notifier) DeliverDue(ctx context.Context) (int, error) {
+	due, err := n.store.ListDueDeliveries(ctx, time.Now(), n.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, n.config.Workers)
+	for _, delivery := range due {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery database.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := n.deliver(ctx, &delivery); err != nil && ctx.Err() == nil {
+				log.Printf("webhook: update delivery %s/%s failed: %v", delivery.EndpointId, delivery.EventId, err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+// deliver 发送一次并记录结果，返回的错误只表示状态写入失败
+func (n *Notifier) deliver(ctx context.Context, delivery *database.WebhookDelivery) error {
+	delivery.Attempts++
+	endpoint, event, err := n.load(ctx, delivery)
+	switch {
+	case errors.Is(err, database.ErrNotFound), errors.Is(err, ErrEndpointInactive):
+		delivery.Status = database.DeliveryStatusFailed
+		delivery.LastError = err.Error()
+		return n.store.UpdateDelivery(ctx, delivery)
+	case err != nil:
+		return err
+	}
+
+	err = n.post(ctx, endpoint, event, delivery.Attempts)
+	if ctx.Err() != nil {
+		// 停机中断的请求不计入重试次数
+		return nil
+	}
+	if err == nil {
+		delivery.Status = database.DeliveryStatusDelivered
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = truncate(err.Error(), maxLastError)
+		if delivery.Attempts >= n.config.MaxAttempts {
+			delivery.Status = database.DeliveryStatusFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(n.backoff(delivery.Attempts))
+		}
+	}
+	return n.store.UpdateDelivery(ctx, delivery)
+}
+
+func (n *Notifier) load(ctx context.Context, delivery *database.WebhookDelivery) (*database.WebhookEndpoint, *database.Event, error) {
+	endpoint, err := n.store.GetWebhookEndpoint(ctx, delivery.EndpointId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("endpoint %s: %w", delivery.EndpointId, err)
+	}
+	if !endpoint.Active {
+		return nil, nil, ErrEndpointInactive
+	}
+	event, err := n.store.GetEvent(ctx, delivery.EventId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("event %s: %w", delivery.EventId, err)
+	}
+	return endpoint, event, nil
+}
+
+func (n *Notifier) post(ctx context.Context, endpoint *database.WebhookEndpoint, event *database.Event, attempt int) error {
+	body := []byte(event.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventId, event.Id)
+	req.Header.Set(HeaderEventType, event.Type)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, time.Now().Unix(), body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %s", errEndpointResponded, resp.Status)
+	}
+	return nil
+}
+
+// backoff 第 attempts 次失败后的等待时间：BaseBackoff * 2^(attempts-1)，不超过 MaxBackoff
+func (n *Notifier) backoff(attempts int) time.Duration {
+	d := n.config.BaseBackoff
+	for i := 1; i < attempts && d < n.config.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, n.config.MaxBackoff)
+}
+
+// Notify 事务提交后调用，让投递循环立即处理而不必等待下一次轮询
+func (n *Notifier) Notify() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+func subscribes(endpoint *database.WebhookEndpoint, eventType string) bool {
+	if len(endpoint.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range endpoint.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
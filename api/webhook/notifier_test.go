@@ -0,0 +1,328 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+)
+
+// receiver 记录成功收到的回调，failures 为各事件前若干次返回 500 的次数
+type receiver struct {
+	t        *testing.T
+	secret   string
+	failures map[string]int
+
+	mu       sync.Mutex
+	received []events.Event
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.secret != "" {
+		if err := Verify(rc.secret, r.Header.Get(HeaderSignature), body, time.Minute); err != nil {
+			rc.t.Errorf("Verify() err = %v", err)
+		}
+	}
+	var event events.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		rc.t.Errorf("invalid payload: %v", err)
+	}
+	if r.Header.Get(HeaderEventId) != event.Id {
+		rc.t.Errorf("%s = %s, want %s", HeaderEventId, r.Header.Get(HeaderEventId), event.Id)
+	}
+	if rc.failures[event.Id] > 0 {
+		rc.failures[event.Id]--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	rc.received = append(rc.received, event)
+}
+
+func (rc *receiver) events() []events.Event {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]events.Event(nil), rc.received...)
+}
+
+func newTestNotifier(t *testing.T, config Config) (*Notifier, database.Repository) {
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return NewNotifier(store, config), store
+}
+
+func testEvent(id string, eventType events.Type, createdAt time.Time) events.Event {
+	return events.Event{
+		Id:        id,
+		Type:      eventType,
+		ChainId:   1,
+		CreatedAt: createdAt,
+		Deposit:   &events.Deposit{UserId: "u1", TxHash: "0x01", Amount: "100"},
+	}
+}
+
+// deliverUntil 反复投递直到 done 返回 true，存储中的时间精度为秒，重试可能需要等到下一秒
+func deliverUntil(t *testing.T, n *Notifier, done func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for delivery")
+		}
+		if _, err := n.DeliverDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"e1"}`)
+	now := time.Now().Unix()
+	header := Sign("secret", now, body)
+	if err := Verify("secret", header, body, time.Minute); err != nil {
+		t.Fatalf("Verify() err = %v", err)
+	}
+	if err := Verify("other", header, body, time.Minute); err != ErrInvalidSignature {
+		t.Errorf("Verify() wrong secret err = %v", err)
+	}
+	if err := Verify("secret", header, []byte(`{"id":"e2"}`), time.Minute); err != ErrInvalidSignature {
+		t.Errorf("Verify() tampered body err = %v", err)
+	}
+	old := Sign("secret", now-3600, body)
+	if err := Verify("secret", old, body, time.Minute); err != ErrSignatureExpired {
+		t.Errorf("Verify() old timestamp err = %v", err)
+	}
+	if err := Verify("secret", "garbage", body, 0); err != ErrInvalidSignature {
+		t.Errorf("Verify() malformed header err = %v", err)
+	}
+}
+
+func TestNotifierRetry(t *testing.T) {
+	ctx := context.Background()
+	n, store := newTestNotifier(t, Config{BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxAttempts: 5})
+
+	rc := &receiver{t: t, failures: map[string]int{"e1": 2}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	endpoint, err := n.Register(ctx, server.URL, []events.Type{events.DepositConfirmed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.secret = endpoint.Secret
+	if _, err := n.Register(ctx, "ftp://example.com", nil); err != ErrInvalidURL {
+		t.Errorf("Register() invalid url err = %v", err)
+	}
+
+	if err := n.Enqueue(ctx, testEvent("e1", events.DepositConfirmed, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	// 未订阅的类型不投递
+	if err := n.Enqueue(ctx, testEvent("e2", events.DepositDetected, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	// 重复入队不会重复投递
+	if err := n.Enqueue(ctx, testEvent("e1", events.DepositConfirmed, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+
+	deliverUntil(t, n, func() bool { return len(rc.events()) > 0 })
+	if got := rc.events(); len(got) != 1 || got[0].Id != "e1" || got[0].Deposit.Amount != "100" {
+		t.Fatalf("received = %+v", got)
+	}
+	deliveries, err := store.ListDeliveriesByEvent(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != database.DeliveryStatusDelivered || deliveries[0].Attempts != 3 {
+		t.Errorf("deliveries = %+v", deliveries)
+	}
+	if deliveries, _ := store.ListDeliveriesByEvent(ctx, "e2"); len(deliveries) != 0 {
+		t.Errorf("unsubscribed event deliveries = %+v", deliveries)
+	}
+}
+
+func TestNotifierGiveUpAndReplay(t *testing.T) {
+	ctx := context.Background()
+	n, store := newTestNotifier(t, Config{BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxAttempts: 2})
+
+	rc := &receiver{t: t, failures: map[string]int{"e1": 2}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	endpoint, err := n.Register(ctx, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.secret = endpoint.Secret
+
+	base := time.Unix(time.Now().Unix(), 0)
+	for i, id := range []string{"e1", "e2", "e3"} {
+		if err := n.Enqueue(ctx, testEvent(id, events.WithdrawalStatus, base.Add(time.Duration(i)*time.Hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// e1 连续失败达到最大次数后放弃，其余事件正常投递
+	failed := func() bool {
+		deliveries, _ := store.ListDeliveriesByEvent(ctx, "e1")
+		return len(deliveries) == 1 && deliveries[0].Status == database.DeliveryStatusFailed
+	}
+	deliverUntil(t, n, func() bool { return failed() && len(rc.events()) == 2 })
+	deliveries, err := store.ListDeliveriesByEvent(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deliveries[0].Attempts != 2 || !strings.Contains(deliveries[0].LastError, "500") {
+		t.Errorf("failed delivery = %+v", deliveries[0])
+	}
+
+	// 按事件 ID 重放
+	queued, err := n.Replay(ctx, ReplayRequest{EventId: "e1"})
+	if err != nil || queued != 1 {
+		t.Fatalf("Replay(e1) = %d %v", queued, err)
+	}
+	deliverUntil(t, n, func() bool { return len(rc.events()) == 3 })
+
+	// 按时间范围重放 [base+1h, base+2h+1s)，只包含 e2、e3
+	queued, err = n.Replay(ctx, ReplayRequest{From: base.Add(time.Hour), To: base.Add(2*time.Hour + time.Second), EndpointId: endpoint.Id})
+	if err != nil || queued != 2 {
+		t.Fatalf("Replay(range) = %d %v", queued, err)
+	}
+	deliverUntil(t, n, func() bool { return len(rc.events()) == 5 })
+	// 并发投递不保证顺序
+	if got := rc.events(); got[3].Id+got[4].Id != "e2e3" && got[3].Id+got[4].Id != "e3e2" {
+		t.Errorf("replayed = %s %s", got[3].Id, got[4].Id)
+	}
+
+	if _, err := n.Replay(ctx, ReplayRequest{To: base}); err != ErrInvalidReplay {
+		t.Errorf("Replay() without range err = %v", err)
+	}
+	if err := n.Disable(ctx, endpoint.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Replay(ctx, ReplayRequest{EventId: "e1", EndpointId: endpoint.Id}); err != ErrEndpointInactive {
+		t.Errorf("Replay() to inactive endpoint err = %v", err)
+	}
+}
+
+func TestNotifierRun(t *testing.T) {
+	n, store := newTestNotifier(t, Config{PollInterval: 10 * time.Millisecond})
+	rc := &receiver{t: t}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	endpoint, err := n.Register(context.Background(), server.URL, []events.Type{events.DepositDetected})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.secret = endpoint.Secret
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx)
+		close(done)
+	}()
+
+	// 回滚的事务不产生投递，提交的事件在 Run 启动前后写入都会投递
+	errAbort := errors.New("abort")
+	err = store.RunInTx(ctx, func(ctx context.Context, tx database.Repository) error {
+		if _, err := n.AddEvent(ctx, tx, testEvent("rolled-back", events.DepositDetected, time.Now())); err != nil {
+			t.Fatal(err)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("RunInTx() err = %v", err)
+	}
+	var added events.Event
+	err = store.RunInTx(ctx, func(ctx context.Context, tx database.Repository) error {
+		var err error
+		added, err = n.AddEvent(ctx, tx, events.Event{Type: events.DepositDetected, ChainId: 1, Deposit: &events.Deposit{UserId: "u1"}})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rc.events()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("committed event was not delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if got := rc.events(); len(got) != 1 || added.Id == "" || got[0].Id != added.Id {
+		t.Errorf("received = %+v, want %s", got, added.Id)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	n, _ := newTestNotifier(t, Config{})
+	handler := n.Handler()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/v1/webhooks", `{"url":"https://example.com/hook","event_types":["deposit.confirmed"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register status = %d %s", rec.Code, rec.Body)
+	}
+	var created endpointResponse
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.Secret == "" || created.Id == "" {
+		t.Fatalf("register response = %+v", created)
+	}
+
+	rec = do(http.MethodGet, "/v1/webhooks", "")
+	if rec.Code != http.StatusOK || bytes.Contains(rec.Body.Bytes(), []byte(created.Secret)) {
+		t.Errorf("list = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/v1/webhooks", `{"url":"https://example.com","event_types":["nope"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("register unknown type status = %d", rec.Code)
+	}
+
+	if err := n.Enqueue(context.Background(), testEvent("e1", events.DepositConfirmed, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	rec = do(http.MethodGet, "/v1/events/e1/deliveries", "")
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte(`"status":"pending"`)) {
+		t.Errorf("deliveries = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/v1/events/missing/deliveries", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("deliveries of missing event status = %d", rec.Code)
+	}
+	rec = do(http.MethodPost, "/v1/webhooks/replay", `{"event_id":"e1"}`)
+	if rec.Code != http.StatusAccepted || !bytes.Contains(rec.Body.Bytes(), []byte(`"queued":1`)) {
+		t.Errorf("replay = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/v1/webhooks/"+created.Id, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("disable status = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/webhooks/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("disable missing status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 投递请求携带的头部，接收方用 HeaderEventId 去重
+const (
+	HeaderSignature = "X-Wallet-Signature"
+	HeaderEventId   = "X-Wallet-Event-Id"
+	HeaderEventType = "X-Wallet-Event-Type"
+	HeaderAttempt   = "X-Wallet-Delivery-Attempt"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Sign 生成签名头 t=<unix 秒>,v1=<hex>，v1 为 HMAC-SHA256(secret, "<t>.<body>")
+// 时间戳参与签名，接收方校验时间窗口即可拒绝重放的旧请求
+func Sign(secret string, timestamp int64, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac(secret, timestamp, body)))
+}
+
+// Verify 供接收方校验签名头，tolerance 为允许的时钟偏差，0 表示不校验时间
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidSignature
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			timestamp = t
+		case "v1":
+			// 更换密钥期间可能携带多个 v1
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if d := time.Since(time.Unix(timestamp, 0)); d > tolerance || d < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	expected := mac(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
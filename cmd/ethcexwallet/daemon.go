@@ -0,0 +1,483 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	goredis "github.com/redis/go-redis/v9"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/grpc"
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+	"github.com/0xweb-3/EthCEXWallet/api/mq/kafka"
+	"github.com/0xweb-3/EthCEXWallet/api/mq/nats"
+	"github.com/0xweb-3/EthCEXWallet/api/mq/redis"
+	"github.com/0xweb-3/EthCEXWallet/api/rest"
+	"github.com/0xweb-3/EthCEXWallet/api/service"
+	"github.com/0xweb-3/EthCEXWallet/api/webhook"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+	"github.com/0xweb-3/EthCEXWallet/database/sqlstore"
+	"github.com/0xweb-3/EthCEXWallet/global"
+	"github.com/0xweb-3/EthCEXWallet/wallet/custody"
+	"github.com/0xweb-3/EthCEXWallet/wallet/destination"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+	"github.com/0xweb-3/EthCEXWallet/wallet/keygen"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/mempool"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	"github.com/0xweb-3/EthCEXWallet/wallet/pool"
+	"github.com/0xweb-3/EthCEXWallet/wallet/scanner"
+	"github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+// dsnEnv SQL 数据库连接串从环境变量读取，避免密码出现在命令行中
+const dsnEnv = "ETHCEXWALLET_DB_DSN"
+
+// shutdownTimeout 退出时等待进行中请求的时间
+const shutdownTimeout = 10 * time.Second
+
+// ledgerSyncInterval serve 读取其他进程（如单独运行的 scan）写入的账本分录的间隔
+const ledgerSyncInterval = 5 * time.Second
+
+// daemonFlags scan 与 serve 共用的参数
+type daemonFlags struct {
+	chain         string
+	db            string
+	dbDialect     string
+	startBlock    uint64
+	confirmations uint64
+	tokens        string
+	nfts          string
+	mq            string
+	mqURL         string
+	mempool       bool
+}
+
+func (f *daemonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.chain, "chain", "", "chain id, or a chain name under chains in the config")
+	fs.StringVar(&f.db, "db", "wallet.db", "bolt database file, ignored when -db-dialect is set")
+	fs.StringVar(&f.dbDialect, "db-dialect", "", "postgres, mysql or sqlite, the DSN is read from "+dsnEnv)
+	fs.Uint64Var(&f.startBlock, "start-block", 0, "first block to scan when no cursor is saved")
+	fs.Uint64Var(&f.confirmations, "confirmations", 12, "confirmations before a deposit is credited")
+	fs.StringVar(&f.tokens, "tokens", "", "comma separated ERC-20 contracts to watch")
+	fs.StringVar(&f.nfts, "nfts", "", "comma separated ERC-721 contracts whose deposits are held in custody")
+	fs.StringVar(&f.mq, "mq", "", "publish events through the outbox to kafka, nats or redis")
+	fs.StringVar(&f.mqURL, "mq-url", "", "comma separated kafka brokers, nats url or redis address")
+	fs.BoolVar(&f.mempool, "mempool", false, "publish deposit.pending events for deposits seen in the mempool, requires a ws or ipc rpc_url")
+}
+
+// daemon 守护进程共用的依赖
+type daemon struct {
+	chainId    uint64
+	client     node.EthClient
+	repository database.Repository
+	ledger     *ledger.Ledger
+	nftLedger  *custody.NftLedger
+	bus        *events.Bus
+	relay      *mq.Relay
+	publisher  mq.Publisher
+	// webhooks 事件与 webhook 投递一同写入存储，由带 -admin 的 serve 进程投递
+	webhooks *webhook.Notifier
+	tokens   []common.Address
+	nfts     []common.Address
+}
+
+func (f *daemonFlags) open(ctx context.Context, e *env) (*daemon, error) {
+	if f.chain == "" {
+		return nil, errors.New("-chain is required")
+	}
+	chainId, err := e.chainId(f.chain)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := parseAddressList("tokens", f.tokens)
+	if err != nil {
+		return nil, err
+	}
+	nfts, err := parseAddressList("nfts", f.nfts)
+	if err != nil {
+		return nil, err
+	}
+	client, err := e.dial(ctx, f.chain)
+	if err != nil {
+		return nil, err
+	}
+	// 运行期间修改 max_request_time 等配置无需重启
+	if err := global.ServerConfig.Watch(ctx, e.configPath); err != nil {
+		return nil, err
+	}
+
+	d := &daemon{chainId: chainId, client: client, bus: events.NewBus(0), tokens: tokens, nfts: nfts}
+	if f.dbDialect != "" {
+		dsn := os.Getenv(dsnEnv)
+		if dsn == "" {
+			return nil, fmt.Errorf("%s is not set", dsnEnv)
+		}
+		if d.repository, err = sqlstore.Open(ctx, f.dbDialect, dsn); err != nil {
+			return nil, err
+		}
+	} else if d.repository, err = boltstore.Open(f.db); err != nil {
+		return nil, err
+	}
+	// 账本分录与业务数据一同持久化，启动时按顺序重放
+	if d.ledger, err = ledger.Load(ctx, d.repository); err != nil {
+		d.repository.Close()
+		return nil, fmt.Errorf("load ledger: %w", err)
+	}
+	if d.nftLedger, err = custody.LoadNftLedger(ctx, client, chainId, d.repository); err != nil {
+		d.repository.Close()
+		return nil, fmt.Errorf("load nft custody: %w", err)
+	}
+
+	d.webhooks = webhook.NewNotifier(d.repository, webhook.Config{})
+	if f.mq != "" {
+		if d.publisher, err = newPublisher(f.mq, f.mqURL); err != nil {
+			d.repository.Close()
+			return nil, err
+		}
+		d.relay = mq.NewRelay(d.repository, d.publisher, mq.RelayConfig{})
+	}
+	return d, nil
+}
+
+func newPublisher(kind, url string) (mq.Publisher, error) {
+	if url == "" {
+		return nil, errors.New("-mq-url is required with -mq")
+	}
+	switch kind {
+	case "kafka":
+		return kafka.NewPublisher(strings.Split(url, ","), nil)
+	case "nats":
+		return nats.NewPublisher(url)
+	case "redis":
+		return redis.NewPublisher(goredis.NewClient(&goredis.Options{Addr: url}), 0), nil
+	default:
+		return nil, fmt.Errorf("unsupported message queue %q", kind)
+	}
+}
+
+func (d *daemon) close() {
+	d.bus.Close()
+	if d.publisher != nil {
+		if err := d.publisher.Close(); err != nil {
+			log.Printf("close publisher: %v", err)
+		}
+	}
+	if err := d.repository.Close(); err != nil {
+		log.Printf("close database: %v", err)
+	}
+}
+
+func (d *daemon) scanner(f *daemonFlags) (*scanner.Scanner, error) {
+	return scanner.NewScanner(scanner.Options{
+		Client:     d.client,
+		Repository: d.repository,
+		Ledger:     d.ledger,
+		Events:     d.bus,
+		Outbox:     d.relay,
+		Nfts:       d.nftLedger,
+		Webhooks:   d.webhooks,
+	}, scanner.Config{
+		ChainId:       d.chainId,
+		StartBlock:    f.startBlock,
+		Confirmations: f.confirmations,
+		Tokens:        d.tokens,
+		Nfts:          d.nfts,
+	})
+}
+
+// runMempool 运行交易池监听，节点不支持订阅时只记录日志，不影响其他服务
+func (d *daemon) runMempool(ctx context.Context) {
+	w, err := mempool.NewWatcher(mempool.Options{
+		Client:     d.client,
+		Repository: d.repository,
+		Events:     d.bus,
+		Webhooks:   d.webhooks,
+	}, mempool.Config{
+		ChainId: d.chainId,
+		Tokens:  d.tokens,
+	})
+	if err == nil {
+		log.Printf("mempool: chain %d watching", d.chainId)
+		err = w.Run(ctx)
+	}
+	if err != nil {
+		log.Printf("mempool: chain %d watcher stopped: %v", d.chainId, err)
+	}
+}
+
+// runScan 只运行充值扫描与发件箱发布，不对外提供 API；入账分录写入存储，由 serve 进程同步
+func runScan(ctx context.Context, e *env, args []string) error {
+	var flags daemonFlags
+	fs := e.newFlagSet("scan", "-chain id|name [flags]")
+	flags.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	d, err := flags.open(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer d.close()
+	s, err := d.scanner(&flags)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	if d.relay != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.relay.Run(ctx)
+		}()
+	}
+	if flags.mempool {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.runMempool(ctx)
+		}()
+	}
+	log.Printf("scanner: chain %d started", d.chainId)
+	s.Run(ctx)
+	wg.Wait()
+	return nil
+}
+
+// runServe 在同一进程中运行 API、充值扫描、webhook 通知与发件箱发布，它们共用账本与事件总线
+// 启动时从存储恢复账本与充值地址的分配，运行中定期同步其他进程写入的账本分录
+func runServe(ctx context.Context, e *env, args []string) error {
+	var flags daemonFlags
+	fs := e.newFlagSet("serve", "-chain id|name -keystore dir [flags]")
+	flags.register(fs)
+	restAddr := fs.String("rest", "127.0.0.1:8080", "REST listen address, empty disables")
+	grpcAddr := fs.String("grpc", "127.0.0.1:9090", "gRPC listen address, empty disables")
+	adminAddr := fs.String("admin", "", "webhook admin listen address, enables webhook delivery when set")
+	allowRemote := fs.Bool("allow-remote", false, "allow listening on non-loopback addresses, the APIs have no authentication and must sit behind an authenticating proxy")
+	keystoreDir := fs.String("keystore", "", "keystore directory for deposit address keys, passphrase from "+passphraseEnv)
+	poolSize := fs.Int("pool-size", 100, "free deposit addresses kept in the pool")
+	scan := fs.Bool("scan", true, "run the deposit scanner")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "chain", "keystore"); err != nil {
+		return err
+	}
+	if !*allowRemote {
+		for name, addr := range map[string]string{"rest": *restAddr, "grpc": *grpcAddr, "admin": *adminAddr} {
+			if addr != "" && !isLoopback(addr) {
+				return fmt.Errorf("-%s %s is not a loopback address, the API has no authentication; use -allow-remote behind an authenticating proxy", name, addr)
+			}
+		}
+	}
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" {
+		return fmt.Errorf("%s is not set", passphraseEnv)
+	}
+	keys, err := keygen.NewKeyStore(*keystoreDir, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return err
+	}
+
+	d, err := flags.open(ctx, e)
+	if err != nil {
+		return err
+	}
+	defer d.close()
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return err
+	}
+	chain, err := cfg.Chain(flags.chain)
+	if err != nil {
+		return err
+	}
+	fees, err := chain.WithdrawalFee()
+	if err != nil {
+		return err
+	}
+
+	addresses := pool.NewService(pool.NewPool(pool.Config{Family: service.DefaultFamily, Target: *poolSize}, &keystoreSource{store: keys}))
+	assigned, err := d.repository.ListAddressesByKind(ctx, service.DefaultFamily, database.AddressKindDeposit)
+	if err != nil {
+		return err
+	}
+	assignments := make([]pool.Assignment, len(assigned))
+	for i, address := range assigned {
+		assignments[i] = pool.Assignment{UserId: address.UserId, Address: address.Address}
+	}
+	if restored, err := addresses.Restore(service.DefaultFamily, assignments); err != nil {
+		return err
+	} else if restored != len(assignments) {
+		log.Printf("pool: restored %d of %d deposit address assignments", restored, len(assignments))
+	}
+	svc, err := service.New(service.Options{
+		ChainId:       new(big.Int).SetUint64(d.chainId),
+		Client:        d.client,
+		Repository:    d.repository,
+		Addresses:     addresses,
+		Ledger:        d.ledger,
+		Destination:   destination.NewClassifier(d.client, d.chainId, d.tokens),
+		Events:        d.bus,
+		Outbox:        d.relay,
+		Webhooks:      d.webhooks,
+		WithdrawalFee: fees,
+	})
+	if err != nil {
+		return err
+	}
+
+	var s *scanner.Scanner
+	if *scan {
+		if s, err = d.scanner(&flags); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	goRun := func(run func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run()
+		}()
+	}
+
+	addresses.Start(ctx)
+	goRun(func() { d.syncLedger(ctx) })
+	if d.relay != nil {
+		goRun(func() { d.relay.Run(ctx) })
+	}
+	if s != nil {
+		goRun(func() { s.Run(ctx) })
+	}
+	if flags.mempool {
+		goRun(func() { d.runMempool(ctx) })
+	}
+
+	var servers []func(context.Context) error
+	if *restAddr != "" {
+		server := rest.NewServer(svc)
+		goRun(func() { errs <- server.ListenAndServe(*restAddr) })
+		servers = append(servers, server.Shutdown)
+	}
+	if *grpcAddr != "" {
+		server := grpc.NewServer(svc)
+		goRun(func() { errs <- server.ListenAndServe(*grpcAddr) })
+		servers = append(servers, func(context.Context) error {
+			server.GracefulStop()
+			return nil
+		})
+	}
+	if *adminAddr != "" {
+		goRun(func() { d.webhooks.Run(ctx) })
+		server := &http.Server{Addr: *adminAddr, Handler: d.webhooks.Handler(), ReadHeaderTimeout: 10 * time.Second}
+		goRun(func() {
+			log.Printf("webhook: admin listening on %s", *adminAddr)
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		})
+		servers = append(servers, server.Shutdown)
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errs:
+	}
+	cancel()
+	// 先关闭总线结束 gRPC 事件流，GracefulStop 才能返回
+	d.bus.Close()
+	shutdownCtx, stop := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer stop()
+	for _, shutdown := range servers {
+		if err := shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}
+	wg.Wait()
+	return serveErr
+}
+
+// isLoopback 监听地址的主机是否为回环地址，主机为空时监听所有网卡
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// syncLedger 定期读取其他进程写入的账本分录，直到 ctx 取消
+func (d *daemon) syncLedger(ctx context.Context) {
+	ticker := time.NewTicker(ledgerSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.ledger.Sync(ctx, d.repository); err != nil && ctx.Err() == nil {
+				log.Printf("ledger: sync failed: %v", err)
+			}
+		}
+	}
+}
+
+// keystoreSource 地址池的地址来源：生成随机私钥并加密写入密钥库，地址池中只保留地址
+type keystoreSource struct {
+	store *keygen.KeyStore
+	mu    sync.Mutex
+}
+
+func (s *keystoreSource) NewAddress(ctx context.Context) (*types.EthAddress, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := s.store.Encrypt(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoint, err := s.store.LoadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Write(checkpoint.NextIndex, address, keyJSON); err != nil {
+		return nil, err
+	}
+	checkpoint.Generated++
+	checkpoint.NextIndex++
+	if err := s.store.SaveCheckpoint(checkpoint); err != nil {
+		return nil, err
+	}
+	return &types.EthAddress{
+		PublicKey: fmt.Sprintf("%x", crypto.FromECDSAPub(&privateKey.PublicKey)[1:]),
+		Address:   address,
+	}, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"os"
+	"strings"
+
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/keygen"
+	"github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+// 口令、种子与助记词不接受命令行参数，只从环境变量或文件读取，避免出现在命令行历史与进程列表中
+const (
+	passphraseEnv      = "ETHCEXWALLET_KEYSTORE_PASSPHRASE"
+	seedEnv            = "ETHCEXWALLET_HD_SEED"
+	mnemonicEnv        = "ETHCEXWALLET_MNEMONIC"
+	bip39PassphraseEnv = "ETHCEXWALLET_BIP39_PASSPHRASE"
+)
+
+func runAddress(ctx context.Context, e *env, args []string) error {
+	return subcommand(ctx, e, "address", args, map[string]func(context.Context, *env, []string) error{
+		"new":         addressNew,
+		"generate":    addressGenerate,
+		"from-pubkey": addressFromPublicKey,
+	})
+}
+
+// addressNew 生成随机私钥地址并输出私钥，仅用于测试或离线冷钱包
+func addressNew(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("address new", "[-count n]")
+	count := fs.Int("count", 1, "number of addresses")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *count <= 0 {
+		return errors.New("count must be positive")
+	}
+
+	addresses := make([]*types.EthAddress, 0, *count)
+	for range *count {
+		address, err := WalletEthereum.CreateAddress(ctx)
+		if err != nil {
+			return err
+		}
+		addresses = append(addresses, address)
+	}
+	return e.printJSON(addresses)
+}
+
+// addressGenerate 批量生成地址，私钥加密写入密钥库目录，支持中断后续跑
+func addressGenerate(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("address generate", "-keystore dir -count n [-seed-file path] [-prefix hex] [-suffix hex]")
+	dir := fs.String("keystore", "", "keystore directory")
+	count := fs.Int("count", 0, "total number of addresses in the keystore")
+	workers := fs.Int("workers", 0, "parallel workers, defaults to the number of CPUs")
+	seedFile := fs.String("seed-file", "", "file holding the HD seed in hex, defaults to $"+seedEnv+"; random keys are used when neither is set")
+	prefix := fs.String("prefix", "", "vanity address prefix")
+	suffix := fs.String("suffix", "", "vanity address suffix")
+	light := fs.Bool("light-scrypt", false, "use light scrypt parameters, for testing only")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "keystore", "count"); err != nil {
+		return err
+	}
+
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" {
+		return fmt.Errorf("%s is not set", passphraseEnv)
+	}
+	scryptN, scryptP := keystore.StandardScryptN, keystore.StandardScryptP
+	if *light {
+		scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+	}
+	store, err := keygen.NewKeyStore(*dir, passphrase, scryptN, scryptP)
+	if err != nil {
+		return err
+	}
+	seedHex, err := readSecret(*seedFile, seedEnv)
+	if err != nil {
+		return err
+	}
+	var seed []byte
+	if seedHex != "" {
+		if seed, err = decodeHex(seedHex); err != nil {
+			return fmt.Errorf("seed: %w", err)
+		}
+	}
+	generator, err := keygen.NewGenerator(store, keygen.Options{
+		Count:   *count,
+		Workers: *workers,
+		Seed:    seed,
+		Prefix:  *prefix,
+		Suffix:  *suffix,
+		Progress: func(generated, total int) {
+			fmt.Fprintf(e.stderr, "generated %d/%d\n", generated, total)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	generated, err := generator.Run(ctx)
+	if err != nil {
+		return err
+	}
+	return e.printJSON(map[string]any{"keystore": *dir, "generated": generated})
+}
+
+func addressFromPublicKey(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("address from-pubkey", "-pubkey hex")
+	publicKey := fs.String("pubkey", "", "compressed or uncompressed public key in hex")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "pubkey"); err != nil {
+		return err
+	}
+
+	address, err := WalletEthereum.GetAddressByPublicKey(ctx, strings.TrimPrefix(*publicKey, "0x"))
+	if err != nil {
+		return err
+	}
+	return e.printJSON(map[string]string{"address": address})
+}
+
+func runHD(ctx context.Context, e *env, args []string) error {
+	return subcommand(ctx, e, "hd", args, map[string]func(context.Context, *env, []string) error{
+		"seed":   hdSeed,
+		"derive": hdDerive,
+	})
+}
+
+// hdSeed 生成随机种子，或按 BIP39 由助记词计算种子，BIP39 口令从 bip39PassphraseEnv 读取
+func hdSeed(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("hd seed", "[-mnemonic-file path]")
+	mnemonicFile := fs.String("mnemonic-file", "", "file holding the BIP39 mnemonic, defaults to $"+mnemonicEnv+"; a random seed is generated when neither is set")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	mnemonic, err := readSecret(*mnemonicFile, mnemonicEnv)
+	if err != nil {
+		return err
+	}
+
+	var seed []byte
+	if mnemonic != "" {
+		seed = WalletEthereum.SeedFromMnemonic(strings.Join(strings.Fields(mnemonic), " "), os.Getenv(bip39PassphraseEnv))
+	} else if seed, err = WalletEthereum.NewHDSeed(); err != nil {
+		return err
+	}
+	return e.printJSON(map[string]string{"seed": hex.EncodeToString(seed)})
+}
+
+// hdDerive 派生 m/44'/60'/0'/0/index 起的连续地址，默认只输出地址与公钥
+func hdDerive(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("hd derive", "[-seed-file path] [-index n] [-count n] [-show-private-keys]")
+	seedFile := fs.String("seed-file", "", "file holding the HD seed in hex, defaults to $"+seedEnv)
+	index := fs.Uint("index", 0, "first address index")
+	count := fs.Uint("count", 1, "number of addresses")
+	showPrivateKeys := fs.Bool("show-private-keys", false, "include the derived private keys in the output")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	seedHex, err := readSecret(*seedFile, seedEnv)
+	if err != nil {
+		return err
+	}
+	if seedHex == "" {
+		return fmt.Errorf("no HD seed, use -seed-file or %s", seedEnv)
+	}
+	seed, err := decodeHex(seedHex)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	if *count == 0 || uint64(*index)+uint64(*count) > 1<<31 {
+		return errors.New("index range must stay below 2^31")
+	}
+
+	type derived struct {
+		Index      uint32 `json:"index"`
+		Address    string `json:"address"`
+		PublicKey  string `json:"public_key"`
+		PrivateKey string `json:"private_key,omitempty"`
+	}
+	addresses := make([]derived, 0, *count)
+	for i := uint32(*index); i < uint32(*index+*count); i++ {
+		address, err := WalletEthereum.CreateAddressByHDSeed(ctx, seed, i)
+		if err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+		item := derived{Index: i, Address: address.Address, PublicKey: address.PublicKey}
+		if *showPrivateKeys {
+			item.PrivateKey = address.PrivateKey
+		}
+		addresses = append(addresses, item)
+	}
+	return e.printJSON(addresses)
+}
+
+// readSecret 读取 file 的内容，未指定文件时读取环境变量 name，都未设置时返回空串
+// file 可以是 /dev/stdin，从标准输入读取
+func readSecret(file, name string) (string, error) {
+	if file == "" {
+		return strings.TrimSpace(os.Getenv(name)), nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+}
@@ -0,0 +1,223 @@
+// ethcexwallet 钱包命令行工具：生成地址与 HD 种子、离线签名与解码交易、广播与链上查询，以及运行扫链与 API 服务
+//
+// 需要访问节点的命令从 -config 指定的 YAML 配置中读取链的 rpc_url 与 max_request_time，
+// 离线命令不读取配置（按名称指定链时除外），可在隔离环境中使用
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/config"
+	"github.com/0xweb-3/EthCEXWallet/global"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+// errUsage 参数错误，已输出用法说明
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+// env 命令运行环境，配置与节点连接在首次使用时加载
+type env struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	configPath string
+	config     *config.Config
+}
+
+var commands = []command{
+	{"address", "generate addresses or derive one from a public key", runAddress},
+	{"hd", "create HD seeds and derive addresses from them", runHD},
+	{"tx", "build, sign, decode and broadcast transactions", runTx},
+	{"balance", "query native or ERC-20 balances", runBalance},
+	{"nonce", "query the next nonce of an address", runNonce},
+	{"receipt", "query a transaction receipt", runReceipt},
+	{"scan", "run the deposit scanner", runScan},
+	{"serve", "run the REST/gRPC API together with the deposit scanner", runServe},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "ethcexwallet: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	e := &env{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("ethcexwallet", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&e.configPath, "config", "", "path of the YAML config file, defaults to $"+config.PathEnv+" or "+config.DefaultPath)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: ethcexwallet [-config path] <command> [arguments]")
+		fmt.Fprintln(stderr, "\ncommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintln(stderr, "\nrun 'ethcexwallet <command> -h' for command usage")
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(ctx, e, fs.Args()[1:])
+		}
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n", name)
+	fs.Usage()
+	return errUsage
+}
+
+// subcommand 分派二级命令，如 address new
+func subcommand(ctx context.Context, e *env, group string, args []string, subs map[string]func(ctx context.Context, e *env, args []string) error) error {
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(args) == 0 {
+		fmt.Fprintf(e.stderr, "usage: ethcexwallet %s <%s> [arguments]\n", group, strings.Join(names, "|"))
+		return errUsage
+	}
+	sub, ok := subs[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "unknown command %q, expected one of: %s\n", group+" "+args[0], strings.Join(names, ", "))
+		return errUsage
+	}
+	return sub(ctx, e, args[1:])
+}
+
+// newFlagSet 创建命令的参数集，解析错误与 -h 输出到 stderr
+func (e *env) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: ethcexwallet %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse 解析参数，不接受多余的位置参数
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// required 检查必填参数
+func required(fs *flag.FlagSet, names ...string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range names {
+		if !set[name] {
+			fmt.Fprintf(fs.Output(), "missing required flag -%s\n", name)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+// loadConfig 加载并校验配置，写入 global.ServerConfig，serve 与 scan 监听配置文件时在此基础上热更新
+func (e *env) loadConfig() (*config.Config, error) {
+	if e.config != nil {
+		return e.config, nil
+	}
+	cfg, err := config.Load(e.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := global.ServerConfig.Apply(cfg); err != nil {
+		return nil, err
+	}
+	e.config = cfg
+	return cfg, nil
+}
+
+// dial 连接指定链的节点，chain 为空时使用配置中的 default_chain
+func (e *env) dial(ctx context.Context, chain string) (node.EthClient, error) {
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	selected := cfg.Default()
+	if chain != "" {
+		if selected, err = cfg.Chain(chain); err != nil {
+			return nil, err
+		}
+	}
+	if selected.RpcUrl == "" {
+		return nil, fmt.Errorf("chain %s has no rpc_url", selected.Name)
+	}
+	// 请求超时每次从当前配置读取，max_request_time 热更新后对已建立的连接生效
+	client, err := node.DailEthClient(ctx, selected.Endpoint(),
+		node.WithUserAgent("ethcexwallet"),
+		node.WithTimeoutFunc(func(node.MethodClass) time.Duration {
+			return time.Duration(global.ServerConfig.Current().MaxRequestTime) * time.Second
+		}),
+		node.WithRetry(node.RetryPolicy{MaxAttempts: 3}),
+	)
+	if err != nil {
+		// 地址中可能带有密钥，只输出链名
+		return nil, fmt.Errorf("dial chain %s: %w", selected.Name, err)
+	}
+	return client, nil
+}
+
+// chainId 解析 -chain 参数：数字直接作为链 ID，否则按配置中 chains 下的名称查找
+func (e *env) chainId(chain string) (uint64, error) {
+	if id, err := strconv.ParseUint(chain, 10, 64); err == nil && id > 0 {
+		return id, nil
+	}
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return 0, err
+	}
+	selected, err := cfg.Chain(chain)
+	if err != nil {
+		return 0, err
+	}
+	return selected.ChainId, nil
+}
+
+// printJSON 以缩进 JSON 输出结果
+func (e *env) printJSON(v any) error {
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+const testPrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, &stdout, &stderr)
+	if err != nil {
+		t.Logf("stderr: %s", stderr.String())
+	}
+	return stdout.String(), err
+}
+
+func writeConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "name: test\nmax_request_time: 1\ndefault_chain: ethereum_sepolia\nchains:\n  ethereum_sepolia:\n    chain_id: 11155111\n    rpc_url: http://127.0.0.1:1\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSignAndDecode(t *testing.T) {
+	t.Setenv(privateKeyEnv, testPrivateKey)
+	config := writeConfig(t)
+
+	out, err := runCommand(t, "-config", config, "tx", "sign", "-chain", "ethereum_sepolia", "-nonce", "7",
+		"-to", "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74", "-value", "1000",
+		"-token", "0xdAC17F958D2ee523a2206206994597C13D831ec7", "-gas", "60000", "-tip", "1", "-fee-cap", "30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signed struct {
+		RawTx       string                         `json:"raw_tx"`
+		Transaction WalletTypes.TransactionSummary `json:"transaction"`
+	}
+	if err := json.Unmarshal([]byte(out), &signed); err != nil {
+		t.Fatal(err)
+	}
+	if signed.Transaction.ChainId != "11155111" || signed.Transaction.Nonce != 7 || signed.Transaction.Call == nil ||
+		signed.Transaction.Call.Amount != "1000" || signed.Transaction.From != "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23" {
+		t.Fatalf("signed transaction: %+v", signed.Transaction)
+	}
+
+	out, err = runCommand(t, "tx", "decode", "-raw", signed.RawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded WalletTypes.TransactionSummary
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash != signed.Transaction.Hash {
+		t.Fatalf("decoded hash %s, want %s", decoded.Hash, signed.Transaction.Hash)
+	}
+}
+
+func TestHDDerive(t *testing.T) {
+	t.Setenv(mnemonicEnv, "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about")
+	out, err := runCommand(t, "hd", "seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seed map[string]string
+	if err := json.Unmarshal([]byte(out), &seed); err != nil {
+		t.Fatal(err)
+	}
+
+	// 种子从文件读取，默认不输出私钥
+	seedFile := filepath.Join(t.TempDir(), "seed")
+	if err := os.WriteFile(seedFile, []byte(seed["seed"]+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCommand(t, "hd", "derive", "-seed-file", seedFile, "-count", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var derived []struct {
+		Index      uint32 `json:"index"`
+		Address    string `json:"address"`
+		PrivateKey string `json:"private_key"`
+	}
+	if err := json.Unmarshal([]byte(out), &derived); err != nil {
+		t.Fatal(err)
+	}
+	// BIP39 测试助记词在 m/44'/60'/0'/0/0 的公开地址
+	if len(derived) != 2 || derived[0].Address != "0x9858EfFD232B4033E47d90003D41EC34EcaEda94" || derived[1].Index != 1 || derived[0].PrivateKey != "" {
+		t.Fatalf("derived: %+v", derived)
+	}
+
+	t.Setenv(seedEnv, seed["seed"])
+	out, err = runCommand(t, "hd", "derive", "-show-private-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &derived); err != nil {
+		t.Fatal(err)
+	}
+	if len(derived) != 1 || derived[0].Address != "0x9858EfFD232B4033E47d90003D41EC34EcaEda94" || derived[0].PrivateKey == "" {
+		t.Fatalf("derived with private keys: %+v", derived)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"tx"},
+		{"tx", "sign", "-nonce", "1"},
+		{"address", "from-pubkey", "extra"},
+	} {
+		if _, err := runCommand(t, args...); !errors.Is(err, errUsage) {
+			t.Errorf("%s: got %v, want usage error", strings.Join(args, " "), err)
+		}
+	}
+
+	if _, err := runCommand(t, "-config", writeConfig(t), "tx", "sign", "-chain", "unknown_chain", "-nonce", "0",
+		"-to", "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74", "-gas", "21000", "-fee-cap", "1"); err == nil || !strings.Contains(err.Error(), "unknown_chain") {
+		t.Fatalf("unknown chain: %v", err)
+	}
+}
+
+func TestServeRejectsPublicListen(t *testing.T) {
+	for addr, want := range map[string]bool{"127.0.0.1:8080": true, "localhost:8080": true, "[::1]:9090": true, ":8080": false, "0.0.0.0:8080": false, "10.0.0.1:8080": false} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+	_, err := runCommand(t, "serve", "-chain", "1", "-keystore", t.TempDir(), "-rest", ":8080")
+	if err == nil || !strings.Contains(err.Error(), "-allow-remote") {
+		t.Fatalf("serve on all interfaces: %v", err)
+	}
+}
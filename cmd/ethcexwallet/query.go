@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+)
+
+type balanceResult struct {
+	Address string `json:"address"`
+	Token   string `json:"token,omitempty"`
+	Balance string `json:"balance"`
+}
+
+// runBalance 在同一区块上批量查询原生币或 ERC-20 余额
+func runBalance(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("balance", "-address a[,b...] [-token address] [-block n] [-chain name]")
+	chain := fs.String("chain", "", "chain name or id in the config, defaults to default_chain")
+	addressList := fs.String("address", "", "comma separated addresses")
+	token := fs.String("token", "", "ERC-20 contract, native balance when empty")
+	block := fs.Int64("block", -1, "block number, latest when negative")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "address"); err != nil {
+		return err
+	}
+
+	var addresses []common.Address
+	for _, s := range strings.Split(*addressList, ",") {
+		address, err := parseAddress("address", strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		addresses = append(addresses, address)
+	}
+	var blockNumber *big.Int
+	if *block >= 0 {
+		blockNumber = big.NewInt(*block)
+	}
+	client, err := e.dial(ctx, *chain)
+	if err != nil {
+		return err
+	}
+
+	results := make([]balanceResult, len(addresses))
+	if *token == "" {
+		balances, err := client.BalancesAt(ctx, addresses, blockNumber)
+		if err != nil {
+			return err
+		}
+		for i, address := range addresses {
+			results[i] = balanceResult{Address: address.Hex(), Balance: balances[i].String()}
+		}
+		return e.printJSON(results)
+	}
+
+	contract, err := parseAddress("token", *token)
+	if err != nil {
+		return err
+	}
+	msgs := make([]ethereum.CallMsg, len(addresses))
+	for i, address := range addresses {
+		msgs[i] = ethereum.CallMsg{To: &contract, Data: WalletEthereum.BuildErc20BalanceOfData(address)}
+	}
+	outputs, err := client.BatchCallContract(ctx, msgs, blockNumber)
+	if err != nil {
+		return err
+	}
+	for i, address := range addresses {
+		balance, err := WalletEthereum.DecodeErc20BalanceOfResult(outputs[i])
+		if err != nil {
+			return fmt.Errorf("balanceOf %s: %w", address.Hex(), err)
+		}
+		results[i] = balanceResult{Address: address.Hex(), Token: contract.Hex(), Balance: balance.String()}
+	}
+	return e.printJSON(results)
+}
+
+// runNonce 查询包含交易池中待打包交易在内的下一个 nonce
+func runNonce(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("nonce", "-address address [-chain name]")
+	chain := fs.String("chain", "", "chain name or id in the config, defaults to default_chain")
+	addressFlag := fs.String("address", "", "account address")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "address"); err != nil {
+		return err
+	}
+	address, err := parseAddress("address", *addressFlag)
+	if err != nil {
+		return err
+	}
+	client, err := e.dial(ctx, *chain)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := client.GetAddressNonce(ctx, address)
+	if err != nil {
+		return err
+	}
+	return e.printJSON(map[string]any{"address": address.Hex(), "nonce": uint64(nonce)})
+}
+
+func runReceipt(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("receipt", "-hash txhash [-chain name]")
+	chain := fs.String("chain", "", "chain name or id in the config, defaults to default_chain")
+	hash := fs.String("hash", "", "transaction hash")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "hash"); err != nil {
+		return err
+	}
+	hashBytes, err := decodeHex(*hash)
+	if err != nil || len(hashBytes) != common.HashLength {
+		return fmt.Errorf("hash: invalid transaction hash %q", *hash)
+	}
+	client, err := e.dial(ctx, *chain)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := client.TxReceiptByHash(ctx, common.BytesToHash(hashBytes))
+	if err != nil {
+		return err
+	}
+	return e.printJSON(receipt)
+}
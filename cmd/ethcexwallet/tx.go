@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"os"
+	"strings"
+
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/keygen"
+)
+
+// privateKeyEnv 未指定密钥库或密钥文件时从该环境变量读取十六进制私钥
+const privateKeyEnv = "ETHCEXWALLET_PRIVATE_KEY"
+
+func runTx(ctx context.Context, e *env, args []string) error {
+	return subcommand(ctx, e, "tx", args, map[string]func(context.Context, *env, []string) error{
+		"sign":   txSign,
+		"decode": txDecode,
+		"send":   txSend,
+	})
+}
+
+// txSign 离线构建并签名 EIP-1559 交易，不访问节点，nonce 与 gas 参数需由调用方提供
+func txSign(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("tx sign", "-chain id|name -nonce n -to address -gas n -fee-cap wei [-tip wei] [-value amount] [-token address] [key source]")
+	chain := fs.String("chain", "", "chain id, or a chain name under chains in the config")
+	nonce := fs.Uint64("nonce", 0, "sender nonce")
+	to := fs.String("to", "", "recipient address, the token receiver when -token is set")
+	value := fs.String("value", "0", "amount in the smallest unit, token amount when -token is set")
+	token := fs.String("token", "", "ERC-20 contract, sends a transfer(to, value) call when set")
+	data := fs.String("data", "", "call data in hex, cannot be combined with -token")
+	gas := fs.Uint64("gas", 0, "gas limit")
+	tip := fs.String("tip", "0", "max priority fee per gas in wei")
+	feeCap := fs.String("fee-cap", "", "max fee per gas in wei")
+	keystoreDir := fs.String("keystore", "", "keystore directory, used with -from and "+passphraseEnv)
+	from := fs.String("from", "", "sender address in the keystore")
+	keyFile := fs.String("key-file", "", "file holding the sender private key in hex")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "chain", "nonce", "to", "gas", "fee-cap"); err != nil {
+		return err
+	}
+
+	chainId, err := e.chainId(*chain)
+	if err != nil {
+		return err
+	}
+	recipient, err := parseAddress("to", *to)
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount("value", *value)
+	if err != nil {
+		return err
+	}
+	tipCap, err := parseAmount("tip", *tip)
+	if err != nil {
+		return err
+	}
+	maxFee, err := parseAmount("fee-cap", *feeCap)
+	if err != nil {
+		return err
+	}
+	if maxFee.Cmp(tipCap) < 0 {
+		return errors.New("fee-cap must not be lower than tip")
+	}
+
+	feeTx := &types.DynamicFeeTx{
+		ChainID:   new(big.Int).SetUint64(chainId),
+		Nonce:     *nonce,
+		GasTipCap: tipCap,
+		GasFeeCap: maxFee,
+		Gas:       *gas,
+		To:        &recipient,
+		Value:     amount,
+	}
+	switch {
+	case *token != "" && *data != "":
+		return errors.New("-token and -data cannot be combined")
+	case *token != "":
+		contract, err := parseAddress("token", *token)
+		if err != nil {
+			return err
+		}
+		if feeTx.Data, err = WalletEthereum.BuildErc20Data(recipient, amount); err != nil {
+			return err
+		}
+		feeTx.To = &contract
+		feeTx.Value = new(big.Int)
+	case *data != "":
+		if feeTx.Data, err = decodeHex(*data); err != nil {
+			return fmt.Errorf("data: %w", err)
+		}
+	}
+
+	privateKey, err := loadPrivateKey(*keystoreDir, *from, *keyFile)
+	if err != nil {
+		return err
+	}
+	rawTx, err := WalletEthereum.OfflineSignTx(feeTx, privateKey, feeTx.ChainID)
+	if err != nil {
+		return err
+	}
+	summary, err := WalletEthereum.DecodeRawTransaction(rawTx)
+	if err != nil {
+		return err
+	}
+	return e.printJSON(map[string]any{"raw_tx": "0x" + rawTx, "transaction": summary})
+}
+
+func txDecode(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("tx decode", "-raw hex")
+	raw := fs.String("raw", "", "signed raw transaction in hex")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "raw"); err != nil {
+		return err
+	}
+
+	summary, err := WalletEthereum.DecodeRawTransaction(*raw)
+	if err != nil {
+		return err
+	}
+	return e.printJSON(summary)
+}
+
+// txSend 广播前先解码交易，确保发送的是合法的已签名交易
+func txSend(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("tx send", "-raw hex [-chain name]")
+	chain := fs.String("chain", "", "chain name or id in the config, defaults to default_chain")
+	raw := fs.String("raw", "", "signed raw transaction in hex")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := required(fs, "raw"); err != nil {
+		return err
+	}
+
+	summary, err := WalletEthereum.DecodeRawTransaction(*raw)
+	if err != nil {
+		return err
+	}
+	client, err := e.dial(ctx, *chain)
+	if err != nil {
+		return err
+	}
+	rawTx := "0x" + strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(*raw), "0x"), "0X")
+	if err := client.SendRawTransaction(ctx, rawTx); err != nil {
+		return err
+	}
+	return e.printJSON(map[string]string{"hash": summary.Hash})
+}
+
+// loadPrivateKey 按密钥库、密钥文件、环境变量的顺序读取私钥，返回不带 0x 的十六进制字符串
+func loadPrivateKey(keystoreDir, from, keyFile string) (string, error) {
+	switch {
+	case keystoreDir != "":
+		if from == "" {
+			return "", errors.New("-from is required with -keystore")
+		}
+		passphrase := os.Getenv(passphraseEnv)
+		if passphrase == "" {
+			return "", fmt.Errorf("%s is not set", passphraseEnv)
+		}
+		store, err := keygen.NewKeyStore(keystoreDir, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+		if err != nil {
+			return "", err
+		}
+		privateKey, err := store.Load(from)
+		if err != nil {
+			return "", fmt.Errorf("load key %s: %w", from, err)
+		}
+		return fmt.Sprintf("%x", crypto.FromECDSA(privateKey)), nil
+	case keyFile != "":
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimPrefix(strings.TrimSpace(string(content)), "0x"), nil
+	case os.Getenv(privateKeyEnv) != "":
+		return strings.TrimPrefix(strings.TrimSpace(os.Getenv(privateKeyEnv)), "0x"), nil
+	default:
+		return "", fmt.Errorf("no signing key, use -keystore with -from, -key-file or %s", privateKeyEnv)
+	}
+}
+
+func parseAddress(name, s string) (common.Address, error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("%s: invalid address %q", name, s)
+	}
+	return common.HexToAddress(s), nil
+}
+
+// parseAddressList 解析逗号分隔的地址列表，空串返回 nil
+func parseAddressList(name, s string) ([]common.Address, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var addresses []common.Address
+	for _, item := range strings.Split(s, ",") {
+		address, err := parseAddress(name, strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// parseAmount 解析最小单位的十进制金额
+func parseAmount(name, s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("%s: invalid amount %q", name, s)
+	}
+	return amount, nil
+}
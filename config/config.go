@@ -1,30 +1,81 @@
 package config
 
-type ChainId struct {
-	Scroll          uint64 `mapstructure:"scroll"`
-	Polygon         uint64 `mapstructure:"polygon"`
-	PolygonSepolia  uint64 `mapstructure:"polygon_sepolia"`
-	Ethereum        uint64 `mapstructure:"ethereum"`
-	EthereumSepolia uint64 `mapstructure:"ethereum_sepolia"`
-	Base            uint64 `mapstructure:"base"`
-	BaseSepolia     uint64 `mapstructure:"base_sepolia"`
-	Manta           uint64 `mapstructure:"manta"`
-	MantaSepolia    uint64 `mapstructure:"manta_sepolia"`
-	MantleSepolia   uint64 `mapstructure:"mantle_sepolia"`
-	Mantle          uint64 `mapstructure:"mantle"`
-	ZkFairSepolia   uint64 `mapstructure:"zk_fair_sepolia"`
-	ZkFair          uint64 `mapstructure:"zk_fair"`
-	OkxSepolia      uint64 `mapstructure:"okx_sepolia"`
-	Okx             uint64 `mapstructure:"okx"`
-	Op              uint64 `mapstructure:"op"`
-	OpTest          uint64 `mapstructure:"op_test"`
-	Linea           uint64 `mapstructure:"linea"`
-	arb             uint64 `mapstructure:"arb"`
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ApiKeyPlaceholder rpc_url 中的该占位符在加载时替换为 api_key 解析出的值
+const ApiKeyPlaceholder = "{api_key}"
+
+// Chain 单条链的配置，以 chains 下的键名标识
+type Chain struct {
+	Name    string `mapstructure:"-"`
+	ChainId uint64 `mapstructure:"chain_id"`
+	// RpcUrl 节点地址，可包含 {api_key} 占位符，为空时该链只能用于离线操作
+	RpcUrl string `mapstructure:"rpc_url"`
+	// ApiKey 节点服务的密钥引用：env:NAME 读取环境变量，file:/path 读取文件内容，其他值按原文使用
+	ApiKey string `mapstructure:"api_key"`
+	// WithdrawalFees 按代币收取的提现手续费，键为代币合约地址或 native，值为最小单位的十进制整数，未配置的代币不收费
+	WithdrawalFees map[string]string `mapstructure:"withdrawal_fees"`
+}
+
+// NativeToken withdrawal_fees 中表示原生币的键
+const NativeToken = "native"
+
+// WithdrawalFee 解析 withdrawal_fees，原生币对应零地址，Load 已校验格式
+func (c *Chain) WithdrawalFee() (map[common.Address]*big.Int, error) {
+	fees := make(map[common.Address]*big.Int, len(c.WithdrawalFees))
+	for key, value := range c.WithdrawalFees {
+		var token common.Address
+		if !strings.EqualFold(key, NativeToken) {
+			if !common.IsHexAddress(key) {
+				return nil, fmt.Errorf("withdrawal_fees: %q is not a token address or %s", key, NativeToken)
+			}
+			token = common.HexToAddress(key)
+		}
+		fee, ok := new(big.Int).SetString(value, 10)
+		if !ok || fee.Sign() < 0 {
+			return nil, fmt.Errorf("withdrawal_fees.%s: %q is not a non-negative integer", key, value)
+		}
+		fees[token] = fee
+	}
+	return fees, nil
+}
+
+// Endpoint 返回替换了密钥占位符的节点地址，可能包含密钥，不应写入日志
+func (c *Chain) Endpoint() string {
+	return strings.ReplaceAll(c.RpcUrl, ApiKeyPlaceholder, c.ApiKey)
 }
 
 type Config struct {
-	Name           string  `mapstructure:"name"`
-	EthRpcUrl      string  `mapstructure:"eth_rpc_url"`
-	MaxRequestTime int     `mapstructure:"max_request_time"`
-	ChainId        ChainId `mapstructure:"chain_id"`
+	Name           string `mapstructure:"name"`
+	MaxRequestTime int    `mapstructure:"max_request_time"` // 单次 RPC 请求超时，单位秒
+	// DefaultChain 未指定链时使用的链，需要配置 rpc_url
+	DefaultChain string           `mapstructure:"default_chain"`
+	Chains       map[string]Chain `mapstructure:"chains"`
+}
+
+// Chain 按名称或十进制链 ID 查找链配置
+func (c *Config) Chain(nameOrId string) (*Chain, error) {
+	if chain, ok := c.Chains[strings.ToLower(nameOrId)]; ok {
+		return &chain, nil
+	}
+	if id, err := strconv.ParseUint(nameOrId, 10, 64); err == nil {
+		for _, chain := range c.Chains {
+			if chain.ChainId == id {
+				return &chain, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("chain %q not configured", nameOrId)
+}
+
+// Default 返回 default_chain 对应的链配置，Load 已保证其存在
+func (c *Config) Default() *Chain {
+	chain := c.Chains[c.DefaultChain]
+	return &chain
 }
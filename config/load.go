@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultPath 相对于仓库根目录的默认配置文件
+	DefaultPath = "config/conf/config.yaml"
+	// PathEnv 未显式指定路径时从该环境变量读取配置文件路径
+	PathEnv = "ETHCEXWALLET_CONFIG"
+	// EnvPrefix 覆盖配置项的环境变量前缀
+	EnvPrefix = "ETHCEXWALLET"
+)
+
+// Load 读取并校验配置文件，路径按 ResolvePath 确定
+// 环境变量可覆盖配置项：键名转为大写，层级分隔与名称中的 . 换成 _，并加上 ETHCEXWALLET_ 前缀，
+// 如 ETHCEXWALLET_MAX_REQUEST_TIME、ETHCEXWALLET_CHAINS_ETHEREUM_RPC_URL；链下的配置项只能覆盖文件中已有的键
+func Load(path string) (*Config, error) {
+	path = ResolvePath(path)
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	for _, key := range []string{"name", "max_request_time", "default_chain"} {
+		if err := v.BindEnv(key); err != nil {
+			return nil, err
+		}
+	}
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	var config Config
+	// 未知的键多半是拼写错误或旧版配置，直接报错而不是静默忽略
+	if err := v.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", path, err)
+	}
+	return &config, nil
+}
+
+// ResolvePath 返回实际使用的配置文件路径：path 为空时依次使用 ETHCEXWALLET_CONFIG 与 DefaultPath
+func ResolvePath(path string) string {
+	if path == "" {
+		path = os.Getenv(PathEnv)
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+	return path
+}
+
+// validate 解析密钥引用并检查所有配置项，一次返回全部问题
+func (c *Config) validate() error {
+	var errs []error
+	if c.MaxRequestTime <= 0 {
+		errs = append(errs, errors.New("max_request_time: must be positive"))
+	}
+	if len(c.Chains) == 0 {
+		errs = append(errs, errors.New("chains: at least one chain is required"))
+	}
+
+	names := make([]string, 0, len(c.Chains))
+	for name := range c.Chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ids := make(map[uint64]string, len(c.Chains))
+	for _, name := range names {
+		chain := c.Chains[name]
+		chain.Name = name
+		prefix := "chains." + name
+		if chain.ChainId == 0 {
+			errs = append(errs, fmt.Errorf("%s.chain_id: must be positive", prefix))
+		} else if other, ok := ids[chain.ChainId]; ok {
+			errs = append(errs, fmt.Errorf("%s.chain_id: %d is already used by %s", prefix, chain.ChainId, other))
+		} else {
+			ids[chain.ChainId] = name
+		}
+
+		placeholder := strings.Contains(chain.RpcUrl, ApiKeyPlaceholder)
+		switch {
+		case chain.ApiKey != "" && !placeholder:
+			errs = append(errs, fmt.Errorf("%s.api_key: rpc_url has no %s placeholder", prefix, ApiKeyPlaceholder))
+		case chain.ApiKey == "" && placeholder:
+			errs = append(errs, fmt.Errorf("%s.rpc_url: %s placeholder without api_key", prefix, ApiKeyPlaceholder))
+		case chain.ApiKey != "":
+			key, err := resolveSecret(chain.ApiKey)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s.api_key: %w", prefix, err))
+			}
+			chain.ApiKey = key
+		}
+		if chain.RpcUrl != "" {
+			if err := validateEndpoint(chain.RpcUrl); err != nil {
+				errs = append(errs, fmt.Errorf("%s.rpc_url: %w", prefix, err))
+			}
+		}
+		if _, err := chain.WithdrawalFee(); err != nil {
+			errs = append(errs, fmt.Errorf("%s.%w", prefix, err))
+		}
+		c.Chains[name] = chain
+	}
+
+	c.DefaultChain = strings.ToLower(c.DefaultChain)
+	if c.DefaultChain == "" {
+		errs = append(errs, errors.New("default_chain: required"))
+	} else if chain, ok := c.Chains[c.DefaultChain]; !ok {
+		errs = append(errs, fmt.Errorf("default_chain: %q is not configured under chains", c.DefaultChain))
+	} else if chain.RpcUrl == "" {
+		errs = append(errs, fmt.Errorf("default_chain: chain %q has no rpc_url", c.DefaultChain))
+	}
+	return errors.Join(errs...)
+}
+
+// validateEndpoint 接受 http(s)、ws(s) 地址与 IPC 文件路径，校验前去掉占位符，错误信息中不会出现密钥
+func validateEndpoint(rawURL string) error {
+	u, err := url.Parse(strings.ReplaceAll(rawURL, ApiKeyPlaceholder, "key"))
+	if err != nil {
+		return errors.New("invalid url")
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+		if u.Host == "" {
+			return errors.New("missing host")
+		}
+	case "":
+		if u.Path == "" {
+			return errors.New("empty ipc path")
+		}
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// resolveSecret 解析密钥引用：env:NAME 读取环境变量，file:/path 读取文件并去掉首尾空白，其他值按原文返回
+func resolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		value := os.Getenv(name)
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		name := strings.TrimPrefix(ref, "file:")
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return "", fmt.Errorf("secret file %s is empty", name)
+		}
+		return value, nil
+	default:
+		return ref, nil
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRepositoryConfig(t *testing.T) {
+	config, err := Load("conf/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Default().Name != "ethereum_sepolia" || config.Default().Endpoint() != "https://sepolia.drpc.org" {
+		t.Fatalf("default chain: %+v", config.Default())
+	}
+	// 原扁平结构中未导出的 arb 不会被加载
+	arb, err := config.Chain("arb")
+	if err != nil || arb.ChainId != 42161 {
+		t.Fatalf("arb: %+v, %v", arb, err)
+	}
+	if chain, err := config.Chain("10"); err != nil || chain.Name != "op" {
+		t.Fatalf("chain by id: %+v, %v", chain, err)
+	}
+}
+
+func TestLoadOverridesAndSecrets(t *testing.T) {
+	secretFile := writeFile(t, "sepolia_key", "file-key\n")
+	path := writeFile(t, "config.yaml", `
+name: wallet
+max_request_time: 5
+default_chain: ethereum
+chains:
+  ethereum:
+    chain_id: 1
+    rpc_url: https://eth-mainnet.example.com/v2/{api_key}
+    api_key: env:TEST_MAINNET_KEY
+    withdrawal_fees:
+      native: "1000"
+      "0xdAC17F958D2ee523a2206206994597C13D831ec7": "2000000"
+  ethereum_sepolia:
+    chain_id: 11155111
+    rpc_url: wss://sepolia.example.com/{api_key}
+    api_key: file:`+secretFile+`
+`)
+	t.Setenv("TEST_MAINNET_KEY", "env-key")
+	t.Setenv("ETHCEXWALLET_MAX_REQUEST_TIME", "9")
+	t.Setenv("ETHCEXWALLET_DEFAULT_CHAIN", "ethereum_sepolia")
+	t.Setenv("ETHCEXWALLET_CHAINS_ETHEREUM_RPC_URL", "https://override.example.com/{api_key}")
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxRequestTime != 9 || config.DefaultChain != "ethereum_sepolia" {
+		t.Fatalf("overrides not applied: %+v", config)
+	}
+	if got := config.Default().Endpoint(); got != "wss://sepolia.example.com/file-key" {
+		t.Fatalf("sepolia endpoint %s", got)
+	}
+	mainnet, _ := config.Chain("ethereum")
+	if got := mainnet.Endpoint(); got != "https://override.example.com/env-key" {
+		t.Fatalf("mainnet endpoint %s", got)
+	}
+	fees, err := mainnet.WithdrawalFee()
+	if err != nil || len(fees) != 2 || fees[common.Address{}].Int64() != 1000 ||
+		fees[common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")].Int64() != 2000000 {
+		t.Fatalf("withdrawal fees %v, %v", fees, err)
+	}
+
+	t.Setenv(PathEnv, path)
+	if _, err := Load(""); err != nil {
+		t.Fatalf("load from %s: %v", PathEnv, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+max_request_time: 0
+default_chain: missing
+chains:
+  a:
+    chain_id: 1
+    rpc_url: ftp://node.example.com
+  b:
+    chain_id: 1
+    rpc_url: https://node.example.com/{api_key}
+  c:
+    chain_id: 0
+    rpc_url: https://node.example.com/{api_key}
+    api_key: env:TEST_UNSET_KEY
+    withdrawal_fees:
+      native: "-1"
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		"max_request_time: must be positive",
+		`chains.a.rpc_url: unsupported scheme "ftp"`,
+		"chains.b.chain_id: 1 is already used by a",
+		"chains.b.rpc_url: {api_key} placeholder without api_key",
+		"chains.c.chain_id: must be positive",
+		"chains.c.api_key: environment variable TEST_UNSET_KEY is not set",
+		`default_chain: "missing" is not configured`,
+		`chains.c.withdrawal_fees.native: "-1" is not a non-negative integer`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	// 旧版的扁平配置包含未知的键
+	path = writeFile(t, "old.yaml", "name: wallet\neth_rpc_url: https://sepolia.drpc.org\nmax_request_time: 5\nchain_id:\n  arb: 42161\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "eth_rpc_url") {
+		t.Fatalf("old config: %v", err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
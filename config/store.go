@@ -0,0 +1,159 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reloadDelay 文件变更后等待的时间，编辑器保存一次通常产生多个事件，合并为一次重新加载
+const reloadDelay = 100 * time.Millisecond
+
+// Validator 在新配置生效前检查变更，返回错误时拒绝这次变更，如禁止运行中修改已有链的 chain_id
+type Validator func(old, new *Config) error
+
+// Subscriber 新配置生效后被调用，old 为上一份配置，首次设置时为 nil
+type Subscriber func(old, new *Config)
+
+// Store 当前生效的配置快照，读取无锁，更新时整体替换
+// 快照发布后不可修改，需要调整时复制一份再通过 Apply 替换
+type Store struct {
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex // 串行化 Apply，保证订阅方按顺序收到变更
+	validators  []Validator
+	subscribers map[int]Subscriber
+	nextId      int
+}
+
+func NewStore(initial *Config) *Store {
+	s := &Store{subscribers: make(map[int]Subscriber)}
+	s.current.Store(initial)
+	return s
+}
+
+// Current 返回当前配置快照，调用方不得修改返回值
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// AddValidator 添加变更校验，对之后的 Apply 生效
+func (s *Store) AddValidator(validator Validator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validators = append(s.validators, validator)
+}
+
+// Subscribe 订阅配置变更，返回的函数用于取消订阅；回调在 Apply 的调用方协程中同步执行，不应阻塞
+func (s *Store) Subscribe(subscriber Subscriber) (cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextId
+	s.nextId++
+	s.subscribers[id] = subscriber
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// Apply 依次执行校验，全部通过后原子替换配置并通知订阅方
+func (s *Store) Apply(config *Config) error {
+	if config == nil {
+		return errors.New("config is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.current.Load()
+	for _, validate := range s.validators {
+		if err := validate(old, config); err != nil {
+			return err
+		}
+	}
+	s.current.Store(config)
+	for id, subscriber := range s.subscribers {
+		s.notify(id, subscriber, old, config)
+	}
+	return nil
+}
+
+// notify 订阅方的 panic 不影响其他订阅方与已生效的配置
+func (s *Store) notify(id int, subscriber Subscriber, old, new *Config) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("config: subscriber %d panicked: %v", id, r)
+		}
+	}()
+	subscriber(old, new)
+}
+
+// Reload 重新加载配置文件并应用，加载或校验失败时保留当前配置
+func (s *Store) Reload(path string) error {
+	config, err := Load(path)
+	if err != nil {
+		return err
+	}
+	return s.Apply(config)
+}
+
+// Watch 监听配置文件变更并重新加载，直到 ctx 取消；被拒绝的变更只记录日志
+// 监听所在目录而不是文件本身，以覆盖编辑器改名保存与 Kubernetes ConfigMap 的符号链接切换
+func (s *Store) Watch(ctx context.Context, path string) error {
+	path = filepath.Clean(ResolvePath(path))
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watch config %s: %w", path, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		realPath, _ := filepath.EvalSymlinks(path)
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				current, _ := filepath.EvalSymlinks(path)
+				if filepath.Clean(event.Name) == path || current != realPath {
+					realPath = current
+					timer.Reset(reloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("config: watch %s: %v", path, err)
+			case <-timer.C:
+				if _, err := os.Stat(path); err != nil {
+					// 改名保存的中间状态，等待新文件出现
+					continue
+				}
+				if err := s.Reload(path); err != nil {
+					log.Printf("config: reload %s rejected, keeping current config: %v", path, err)
+					continue
+				}
+				log.Printf("config: reloaded %s", path)
+			}
+		}
+	}()
+	return nil
+}
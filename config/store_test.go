@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+const storeConfig = `
+max_request_time: %d
+default_chain: ethereum
+chains:
+  ethereum:
+    chain_id: %d
+    rpc_url: https://node.example.com
+`
+
+func writeConfig(t *testing.T, path string, maxRequestTime int, chainId uint64) {
+	t.Helper()
+	content := []byte(fmt.Sprintf(storeConfig, maxRequestTime, chainId))
+	// 先写临时文件再改名，模拟编辑器与配置下发的原子替换
+	if err := os.WriteFile(path+".tmp", content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreApply(t *testing.T) {
+	store := NewStore(&Config{MaxRequestTime: 1})
+	store.AddValidator(func(old, new *Config) error {
+		if new.MaxRequestTime > 60 {
+			return errors.New("max_request_time too large")
+		}
+		return nil
+	})
+
+	var changes [][2]int
+	cancel := store.Subscribe(func(old, new *Config) {
+		changes = append(changes, [2]int{old.MaxRequestTime, new.MaxRequestTime})
+	})
+	store.Subscribe(func(old, new *Config) { panic("broken subscriber") })
+
+	if err := store.Apply(&Config{MaxRequestTime: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Apply(&Config{MaxRequestTime: 120}); err == nil {
+		t.Fatal("expected validator to reject")
+	}
+	if store.Current().MaxRequestTime != 5 {
+		t.Fatalf("rejected config applied: %+v", store.Current())
+	}
+	cancel()
+	if err := store.Apply(&Config{MaxRequestTime: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0] != [2]int{1, 5} {
+		t.Fatalf("changes: %v", changes)
+	}
+}
+
+func TestStoreWatch(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	writeConfig(t, path, 5, 1)
+
+	store := NewStore(&Config{})
+	if err := store.Reload(path); err != nil {
+		t.Fatal(err)
+	}
+	store.AddValidator(func(old, new *Config) error {
+		if old.Default().ChainId != new.Default().ChainId {
+			return errors.New("chain id cannot change at runtime")
+		}
+		return nil
+	})
+	applied := make(chan *Config, 10)
+	store.Subscribe(func(old, new *Config) { applied <- new })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := store.Watch(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 并发读取快照，配合 -race 检查
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			_ = store.Current().MaxRequestTime
+		}
+	}()
+	defer wg.Wait()
+	defer cancel()
+
+	writeConfig(t, path, 9, 1)
+	select {
+	case config := <-applied:
+		if config.MaxRequestTime != 9 {
+			t.Fatalf("reloaded %+v", config)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded")
+	}
+
+	// 校验失败与违反变更规则的配置都被拒绝，当前配置保持不变
+	if err := os.WriteFile(path, []byte("max_request_time: [broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * reloadDelay)
+	writeConfig(t, path, 11, 2)
+	time.Sleep(5 * reloadDelay)
+	select {
+	case config := <-applied:
+		t.Fatalf("invalid config applied: %+v", config)
+	default:
+	}
+	if store.Current().MaxRequestTime != 9 {
+		t.Fatalf("current config changed: %+v", store.Current())
+	}
+
+	writeConfig(t, path, 12, 1)
+	select {
+	case config := <-applied:
+		if config.MaxRequestTime != 12 {
+			t.Fatalf("reloaded %+v", config)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded after fix")
+	}
+}
@@ -0,0 +1,632 @@
+package boltstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.etcd.io/bbolt"
+	"sort"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+)
+
+var (
+	cursorBucket     = []byte("block_cursors")
+	addressBucket    = []byte("addresses")
+	depositBucket    = []byte("deposits")
+	withdrawalBucket = []byte("withdrawals")
+	nonceBucket      = []byte("nonces")
+	eventBucket      = []byte("events")
+	endpointBucket   = []byte("webhook_endpoints")
+	deliveryBucket   = []byte("webhook_deliveries")
+	outboxBucket     = []byte("outbox")
+	outboxIdBucket   = []byte("outbox_event_ids") // event_id -> seq，保证 EventId 唯一
+	ledgerBucket     = []byte("ledger_entries")
+	ledgerIdBucket   = []byte("ledger_entry_ids") // id -> seq，保证分录 Id 唯一
+	nftBucket        = []byte("nft_holdings")
+)
+
+// Store 基于 bbolt 的嵌入式 Repository 实现，适用于单节点部署与测试
+// 记录以 JSON 保存，列表查询为全桶扫描，数据量大时应使用 sqlstore
+type Store struct {
+	db *bbolt.DB
+	tx *bbolt.Tx // RunInTx 内的写事务
+}
+
+var _ database.Repository = (*Store)(nil)
+
+func Open(path string) (*Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{cursorBucket, addressBucket, depositBucket, withdrawalBucket, nonceBucket, eventBucket, endpointBucket, deliveryBucket, outboxBucket, outboxIdBucket, ledgerBucket, ledgerIdBucket, nftBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭数据库，RunInTx 传入的 repo 上调用无效果
+func (s *Store) Close() error {
+	if s.tx != nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// RunInTx 在同一个 bbolt 写事务中执行 fn，fn 返回错误时回滚；嵌套调用复用外层事务
+func (s *Store) RunInTx(ctx context.Context, fn func(ctx context.Context, repo database.Repository) error) error {
+	if s.tx != nil {
+		return fn(ctx, s)
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return fn(ctx, &Store{db: s.db, tx: tx})
+	})
+}
+
+// view 与 update 在 RunInTx 内复用当前事务，bbolt 不允许在写事务中再开启事务
+func (s *Store) view(fn func(tx *bbolt.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.db.View(fn)
+}
+
+func (s *Store) update(fn func(tx *bbolt.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.db.Update(fn)
+}
+
+func chainKey(chainId uint64, parts ...string) []byte {
+	key := binary.BigEndian.AppendUint64(nil, chainId)
+	for _, part := range parts {
+		key = append(key, '/')
+		key = append(key, part...)
+	}
+	return key
+}
+
+func depositKey(chainId uint64, txHash string, logIndex uint64) []byte {
+	return chainKey(chainId, txHash, fmt.Sprintf("%020d", logIndex))
+}
+
+func deliveryKey(endpointId, eventId string) []byte {
+	return []byte(endpointId + "/" + eventId)
+}
+
+func get(tx *bbolt.Tx, bucket, key []byte, v any) error {
+	data := tx.Bucket(bucket).Get(key)
+	if data == nil {
+		return database.ErrNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+func put(tx *bbolt.Tx, bucket, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucket).Put(key, data)
+}
+
+// scan 遍历桶中所有记录，match 返回 true 的记录被收集
+func scan[T any](s *Store, bucket []byte, match func(*T) bool) ([]T, error) {
+	var result []T
+	err := s.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			var record T
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if match(&record) {
+				result = append(result, record)
+			}
+			return nil
+		})
+	})
+	return result, err
+}
+
+func limit[T any](records []T, n int) []T {
+	if n > 0 && len(records) > n {
+		return records[:n]
+	}
+	return records
+}
+
+func (s *Store) GetCursor(ctx context.Context, chainId uint64, name string) (*database.BlockCursor, error) {
+	var cursor database.BlockCursor
+	err := s.view(func(tx *bbolt.Tx) error {
+		return get(tx, cursorBucket, chainKey(chainId, name), &cursor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func (s *Store) SaveCursor(ctx context.Context, cursor *database.BlockCursor) error {
+	if cursor.UpdatedAt.IsZero() {
+		cursor.UpdatedAt = time.Now()
+	}
+	return s.update(func(tx *bbolt.Tx) error {
+		return put(tx, cursorBucket, chainKey(cursor.ChainId, cursor.Name), cursor)
+	})
+}
+
+func (s *Store) CreateAddress(ctx context.Context, address *database.Address) error {
+	if address.CreatedAt.IsZero() {
+		address.CreatedAt = time.Now()
+	}
+	return s.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(addressBucket).Get([]byte(address.Address)) != nil {
+			return database.ErrAlreadyExists
+		}
+		return put(tx, addressBucket, []byte(address.Address), address)
+	})
+}
+
+func (s *Store) GetAddress(ctx context.Context, address string) (*database.Address, error) {
+	var result database.Address
+	err := s.view(func(tx *bbolt.Tx) error {
+		return get(tx, addressBucket, []byte(address), &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *Store) ListAddressesByUser(ctx context.Context, userId string) ([]database.Address, error) {
+	result, err := scan(s, addressBucket, func(a *database.Address) bool { return a.UserId == userId })
+	sortAddresses(result)
+	return result, err
+}
+
+func (s *Store) ListAddressesByKind(ctx context.Context, family, kind string) ([]database.Address, error) {
+	result, err := scan(s, addressBucket, func(a *database.Address) bool { return a.Family == family && a.Kind == kind })
+	sortAddresses(result)
+	return result, err
+}
+
+func sortAddresses(addresses []database.Address) {
+	sort.Slice(addresses, func(i, j int) bool {
+		if !addresses[i].CreatedAt.Equal(addresses[j].CreatedAt) {
+			return addresses[i].CreatedAt.Before(addresses[j].CreatedAt)
+		}
+		return addresses[i].Address < addresses[j].Address
+	})
+}
+
+func (s *Store) SaveDeposit(ctx context.Context, deposit *database.Deposit) error {
+	now := time.Now()
+	deposit.UpdatedAt = now
+	return s.update(func(tx *bbolt.Tx) error {
+		key := depositKey(deposit.ChainId, deposit.TxHash, deposit.LogIndex)
+		var existing database.Deposit
+		switch err := get(tx, depositBucket, key, &existing); {
+		case err == nil:
+			deposit.CreatedAt = existing.CreatedAt
+		case errors.Is(err, database.ErrNotFound):
+			if deposit.CreatedAt.IsZero() {
+				deposit.CreatedAt = now
+			}
+		default:
+			return err
+		}
+		return put(tx, depositBucket, key, deposit)
+	})
+}
+
+func (s *Store) GetDeposit(ctx context.Context, chainId uint64, txHash string, logIndex uint64) (*database.Deposit, error) {
+	var deposit database.Deposit
+	err := s.view(func(tx *bbolt.Tx) error {
+		return get(tx, depositBucket, depositKey(chainId, txHash, logIndex), &deposit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+func (s *Store) UpdateDepositStatus(ctx context.Context, chainId uint64, txHash string, logIndex uint64, status string) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		key := depositKey(chainId, txHash, logIndex)
+		var deposit database.Deposit
+		if err := get(tx, depositBucket, key, &deposit); err != nil {
+			return err
+		}
+		deposit.Status = status
+		deposit.UpdatedAt = time.Now()
+		return put(tx, depositBucket, key, &deposit)
+	})
+}
+
+func (s *Store) ListDepositsByUser(ctx context.Context, userId string, n int) ([]database.Deposit, error) {
+	result, err := scan(s, depositBucket, func(d *database.Deposit) bool { return d.UserId == userId })
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BlockNumber != result[j].BlockNumber {
+			return result[i].BlockNumber > result[j].BlockNumber
+		}
+		return result[i].LogIndex > result[j].LogIndex
+	})
+	return limit(result, n), err
+}
+
+func (s *Store) ListDepositsByStatus(ctx context.Context, chainId uint64, status string) ([]database.Deposit, error) {
+	result, err := scan(s, depositBucket, func(d *database.Deposit) bool { return d.ChainId == chainId && d.Status == status })
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BlockNumber != result[j].BlockNumber {
+			return result[i].BlockNumber < result[j].BlockNumber
+		}
+		return result[i].LogIndex < result[j].LogIndex
+	})
+	return result, err
+}
+
+func (s *Store) CreateWithdrawal(ctx context.Context, withdrawal *database.Withdrawal) error {
+	now := time.Now()
+	if withdrawal.CreatedAt.IsZero() {
+		withdrawal.CreatedAt = now
+	}
+	withdrawal.UpdatedAt = now
+	return s.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(withdrawalBucket).Get([]byte(withdrawal.Id)) != nil {
+			return database.ErrAlreadyExists
+		}
+		return put(tx, withdrawalBucket, []byte(withdrawal.Id), withdrawal)
+	})
+}
+
+func (s *Store) GetWithdrawal(ctx context.Context, id string) (*database.Withdrawal, error) {
+	var withdrawal database.Withdrawal
+	err := s.view(func(tx *bbolt.Tx) error {
+		return get(tx, withdrawalBucket, []byte(id), &withdrawal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &withdrawal, nil
+}
+
+// UpdateWithdrawal 只更新签名与广播过程中会变化的字段，与 sqlstore 保持一致
+func (s *Store) UpdateWithdrawal(ctx context.Context, withdrawal *database.Withdrawal) error {
+	withdrawal.UpdatedAt = time.Now()
+	return s.update(func(tx *bbolt.Tx) error {
+		var existing database.Withdrawal
+		if err := get(tx, withdrawalBucket, []byte(withdrawal.Id), &existing); err != nil {
+			return err
+		}
+		existing.From = withdrawal.From
+		existing.Nonce = withdrawal.Nonce
+		existing.TxHash = withdrawal.TxHash
+		existing.RawTx = withdrawal.RawTx
+		existing.Status = withdrawal.Status
+		existing.UpdatedAt = withdrawal.UpdatedAt
+		return put(tx, withdrawalBucket, []byte(withdrawal.Id), &existing)
+	})
+}
+
+func (s *Store) ListWithdrawalsByUser(ctx context.Context, userId string, n int) ([]database.Withdrawal, error) {
+	result, err := scan(s, withdrawalBucket, func(w *database.Withdrawal) bool { return w.UserId == userId })
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].Id > result[j].Id
+	})
+	return limit(result, n), err
+}
+
+func (s *Store) ListWithdrawalsByStatus(ctx context.Context, chainId uint64, status string) ([]database.Withdrawal, error) {
+	result, err := scan(s, withdrawalBucket, func(w *database.Withdrawal) bool { return w.ChainId == chainId && w.Status == status })
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].Id < result[j].Id
+	})
+	return result, err
+}
+
+func (s *Store) ReserveNonce(ctx context.Context, chainId uint64, address string, chainNonce uint64) (uint64, error) {
+	var nonce uint64
+	err := s.update(func(tx *bbolt.Tx) error {
+		key := chainKey(chainId, address)
+		var next uint64
+		if data := tx.Bucket(nonceBucket).Get(key); data != nil {
+			next = binary.BigEndian.Uint64(data)
+		}
+		nonce = max(next, chainNonce)
+		return tx.Bucket(nonceBucket).Put(key, binary.BigEndian.AppendUint64(nil, nonce+1))
+	})
+	return nonce, err
+}
+
+func (s *Store) ResetNonce(ctx context.Context, chainId uint64, address string, next uint64) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(nonceBucket).Put(chainKey(chainId, address), binary.BigEndian.AppendUint64(nil, next))
+	})
+}
+
+func (s *Store) SaveEvent(ctx context.Context, event *database.Event) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	return s.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(eventBucket).Get([]byte(event.Id)) != nil {
+			return database.ErrAlreadyExists
+		}
+		return put(tx, eventBucket, []byte(event.Id), event)
+	})
+}
+
+func (s *Store) GetEvent(ctx context.Context, id string) (*database.Event, error) {
+	var event database.Event
+	err := s.view(func(tx *bbolt.Tx) error {
+		return get(tx, eventBucket, []byte(id), &event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ListEvents 时间按秒比较，与 sqlstore 保存的精度一致
+func (s *Store) ListEvents(ctx context.Context, from, to time.Time, n int) ([]database.Event, error) {
+	result, err := scan(s, eventBucket, func(e *database.Event) bool {
+		return e.CreatedAt.Unix() >= from.Unix() && e.CreatedAt.Unix() < to.Unix()
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Unix() != result[j].CreatedAt.Unix() {
+			return result[i].CreatedAt.Unix() < result[j].CreatedAt.Unix()
+		}
+		return result[i].Id < result[j].Id
+	})
+	return limit(result, n), err
+}
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, endpoint *database.WebhookEndpoint) error {
+	if endpoint.CreatedAt.IsZero() {
+		endpoint.CreatedAt = time.Now()
+	}
+	return s.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(endpointBucket).Get([]byte(endpoint.Id)) != nil {
+			return database.ErrAlreadyExists
+		}
+		return put(tx, endpointBucket, []byte(endpoint.Id), endpoint)
+	})
+}
+
+func (s *Store) GetWebhookEndpoint(ctx context.Context, id string) (*database.WebhookEndpoint, error) {
+	var endpoint database.WebhookEndpoint
+	err := s.view(func(tx *bbolt.Tx) error {
+		return get(tx, endpointBucket, []byte(id), &endpoint)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (s *Store) ListWebhookEndpoints(ctx context.Context) ([]database.WebhookEndpoint, error) {
+	result, err := scan(s, endpointBucket, func(*database.WebhookEndpoint) bool { return true })
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].Id < result[j].Id
+	})
+	return result, err
+}
+
+func (s *Store) SetWebhookEndpointActive(ctx context.Context, id string, active bool) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		var endpoint database.WebhookEndpoint
+		if err := get(tx, endpointBucket, []byte(id), &endpoint); err != nil {
+			return err
+		}
+		endpoint.Active = active
+		return put(tx, endpointBucket, []byte(id), &endpoint)
+	})
+}
+
+func (s *Store) CreateDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	return s.update(func(tx *bbolt.Tx) error {
+		key := deliveryKey(delivery.EndpointId, delivery.EventId)
+		if tx.Bucket(deliveryBucket).Get(key) != nil {
+			return database.ErrAlreadyExists
+		}
+		return put(tx, deliveryBucket, key, delivery)
+	})
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	return s.update(func(tx *bbolt.Tx) error {
+		key := deliveryKey(delivery.EndpointId, delivery.EventId)
+		if tx.Bucket(deliveryBucket).Get(key) == nil {
+			return database.ErrNotFound
+		}
+		return put(tx, deliveryBucket, key, delivery)
+	})
+}
+
+func (s *Store) ListDueDeliveries(ctx context.Context, now time.Time, n int) ([]database.WebhookDelivery, error) {
+	result, err := scan(s, deliveryBucket, func(d *database.WebhookDelivery) bool {
+		return d.Status == database.DeliveryStatusPending && d.NextAttemptAt.Unix() <= now.Unix()
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].NextAttemptAt.Unix() != result[j].NextAttemptAt.Unix() {
+			return result[i].NextAttemptAt.Unix() < result[j].NextAttemptAt.Unix()
+		}
+		if result[i].EventId != result[j].EventId {
+			return result[i].EventId < result[j].EventId
+		}
+		return result[i].EndpointId < result[j].EndpointId
+	})
+	return limit(result, n), err
+}
+
+func (s *Store) ListDeliveriesByEvent(ctx context.Context, eventId string) ([]database.WebhookDelivery, error) {
+	result, err := scan(s, deliveryBucket, func(d *database.WebhookDelivery) bool { return d.EventId == eventId })
+	sort.Slice(result, func(i, j int) bool { return result[i].EndpointId < result[j].EndpointId })
+	return result, err
+}
+
+func (s *Store) AddOutboxMessage(ctx context.Context, msg *database.OutboxMessage) error {
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	return s.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(outboxIdBucket).Get([]byte(msg.EventId)) != nil {
+			return database.ErrAlreadyExists
+		}
+		seq, err := tx.Bucket(outboxBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		msg.Sequence = seq
+		key := binary.BigEndian.AppendUint64(nil, seq)
+		if err := tx.Bucket(outboxIdBucket).Put([]byte(msg.EventId), key); err != nil {
+			return err
+		}
+		return put(tx, outboxBucket, key, msg)
+	})
+}
+
+// ListOutboxMessages 键为大端序的 seq，按游标顺序遍历即为发布顺序
+func (s *Store) ListOutboxMessages(ctx context.Context, n int) ([]database.OutboxMessage, error) {
+	var result []database.OutboxMessage
+	err := s.view(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(outboxBucket).Cursor()
+		for k, v := cursor.First(); k != nil && (n <= 0 || len(result) < n); k, v = cursor.Next() {
+			var msg database.OutboxMessage
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return err
+			}
+			result = append(result, msg)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (s *Store) DeleteOutboxMessage(ctx context.Context, sequence uint64) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		key := binary.BigEndian.AppendUint64(nil, sequence)
+		var msg database.OutboxMessage
+		if err := get(tx, outboxBucket, key, &msg); err != nil {
+			return err
+		}
+		if err := tx.Bucket(outboxIdBucket).Delete([]byte(msg.EventId)); err != nil {
+			return err
+		}
+		return tx.Bucket(outboxBucket).Delete(key)
+	})
+}
+
+func (s *Store) RecordOutboxFailure(ctx context.Context, sequence uint64, lastError string) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		key := binary.BigEndian.AppendUint64(nil, sequence)
+		var msg database.OutboxMessage
+		if err := get(tx, outboxBucket, key, &msg); err != nil {
+			return err
+		}
+		msg.Attempts++
+		msg.LastError = lastError
+		return put(tx, outboxBucket, key, &msg)
+	})
+}
+
+func (s *Store) AddLedgerEntry(ctx context.Context, entry *database.LedgerEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return s.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(ledgerIdBucket).Get([]byte(entry.Id)) != nil {
+			return database.ErrAlreadyExists
+		}
+		seq, err := tx.Bucket(ledgerBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		entry.Sequence = seq
+		key := binary.BigEndian.AppendUint64(nil, seq)
+		if err := tx.Bucket(ledgerIdBucket).Put([]byte(entry.Id), key); err != nil {
+			return err
+		}
+		return put(tx, ledgerBucket, key, entry)
+	})
+}
+
+func (s *Store) ListLedgerEntries(ctx context.Context, afterSequence uint64, n int) ([]database.LedgerEntry, error) {
+	var result []database.LedgerEntry
+	err := s.view(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(ledgerBucket).Cursor()
+		for k, v := cursor.Seek(binary.BigEndian.AppendUint64(nil, afterSequence+1)); k != nil && (n <= 0 || len(result) < n); k, v = cursor.Next() {
+			var entry database.LedgerEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			result = append(result, entry)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (s *Store) SaveNftHolding(ctx context.Context, holding *database.NftHolding) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		key := chainKey(holding.ChainId, holding.Contract, holding.TokenId)
+		var existing database.NftHolding
+		switch err := get(tx, nftBucket, key, &existing); {
+		case err == nil:
+			holding.CreatedAt = existing.CreatedAt
+		case errors.Is(err, database.ErrNotFound):
+			if holding.CreatedAt.IsZero() {
+				holding.CreatedAt = time.Now()
+			}
+		default:
+			return err
+		}
+		return put(tx, nftBucket, key, holding)
+	})
+}
+
+func (s *Store) DeleteNftHolding(ctx context.Context, chainId uint64, contract, tokenId string) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		key := chainKey(chainId, contract, tokenId)
+		if tx.Bucket(nftBucket).Get(key) == nil {
+			return database.ErrNotFound
+		}
+		return tx.Bucket(nftBucket).Delete(key)
+	})
+}
+
+func (s *Store) ListNftHoldings(ctx context.Context, chainId uint64) ([]database.NftHolding, error) {
+	result, err := scan(s, nftBucket, func(h *database.NftHolding) bool { return h.ChainId == chainId })
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Contract != result[j].Contract {
+			return result[i].Contract < result[j].Contract
+		}
+		return result[i].TokenId < result[j].TokenId
+	})
+	return result, err
+}
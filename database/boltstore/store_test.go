@@ -0,0 +1,19 @@
+package boltstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Repository {
+		store, err := Open(filepath.Join(t.TempDir(), "wallet.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
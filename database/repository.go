@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// NativeLogIndex 原生币充值的 LogIndex，不与任何日志序号重复；一笔交易至多一条原生币充值
+// 取 int64 最大值，保证可以写入有符号的 BIGINT 列
+const NativeLogIndex uint64 = math.MaxInt64
+
+var (
+	ErrNotFound      = errors.New("record not found")
+	ErrAlreadyExists = errors.New("record already exists")
+)
+
+const (
+	AddressKindDeposit = "deposit"
+	AddressKindHot     = "hot"
+	AddressKindCold    = "cold"
+
+	DepositStatusPending   = "pending"   // 已在区块中发现，确认数不足
+	DepositStatusConfirmed = "confirmed" // 达到确认数，已入账
+	DepositStatusReorged   = "reorged"   // 所在区块被回滚
+
+	WithdrawalStatusCreated   = "created"
+	WithdrawalStatusSigned    = "signed"
+	WithdrawalStatusBroadcast = "broadcast"
+	WithdrawalStatusConfirmed = "confirmed"
+	WithdrawalStatusFailed    = "failed"
+
+	DeliveryStatusPending   = "pending"   // 等待投递或重试
+	DeliveryStatusDelivered = "delivered" // 对端返回 2xx
+	DeliveryStatusFailed    = "failed"    // 超过最大重试次数，需人工重放
+)
+
+// BlockCursor 扫链进度，Name 区分同一条链上的不同扫描任务
+type BlockCursor struct {
+	ChainId   uint64
+	Name      string
+	Number    uint64
+	Hash      string
+	UpdatedAt time.Time
+}
+
+// Address 交易所管理的地址，私钥不入库
+type Address struct {
+	Address   string // 校验和格式，即 common.Address.Hex()
+	Family    string // 链族，EVM 链共用同一地址
+	Kind      string
+	UserId    string
+	CreatedAt time.Time
+}
+
+// Deposit 充值记录，(ChainId, TxHash, LogIndex) 唯一；原生币充值 LogIndex 为 NativeLogIndex
+type Deposit struct {
+	ChainId     uint64
+	TxHash      string
+	LogIndex    uint64
+	BlockNumber uint64
+	BlockHash   string
+	Token       string // 原生币为空
+	From        string
+	To          string
+	Amount      string // 最小单位的十进制字符串
+	UserId      string
+	Status      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Withdrawal 提现记录，Id 为业务方的提现请求 ID，用于幂等
+type Withdrawal struct {
+	Id        string
+	ChainId   uint64
+	UserId    string
+	Token     string
+	From      string
+	To        string
+	Amount    string
+	Nonce     uint64
+	TxHash    string
+	RawTx     string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WebhookEndpoint 下游注册的 webhook 地址，Secret 用于 HMAC 签名，需要以明文保存
+type WebhookEndpoint struct {
+	Id         string
+	URL        string
+	Secret     string
+	EventTypes []string // 订阅的事件类型，为空表示全部
+	Active     bool
+	CreatedAt  time.Time
+}
+
+// Event 持久化的钱包事件，Payload 为事件的 JSON 编码，作为投递的发件箱
+type Event struct {
+	Id        string
+	Type      string
+	ChainId   uint64
+	Payload   string
+	CreatedAt time.Time
+}
+
+// WebhookDelivery 事件到某个 endpoint 的投递状态，(EndpointId, EventId) 唯一
+type WebhookDelivery struct {
+	EndpointId    string
+	EventId       string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	UpdatedAt     time.Time
+}
+
+// OutboxMessage 事务性发件箱中待发布到消息队列的事件，Sequence 由存储分配，按此顺序发布
+type OutboxMessage struct {
+	Sequence     uint64
+	EventId      string
+	Type         string
+	PartitionKey string // 消息队列的分区键，同一键的消息保持顺序
+	Payload      string
+	Attempts     int
+	LastError    string
+	CreatedAt    time.Time
+}
+
+// LedgerEntry 持久化的记账分录，Postings 为分录行的 JSON 编码；Sequence 由存储分配，重建账本时按此顺序过账
+type LedgerEntry struct {
+	Sequence  uint64
+	Id        string
+	Kind      string
+	Reference string
+	Postings  string
+	CreatedAt time.Time
+}
+
+// NftHolding 托管中的 ERC-721 NFT，(ChainId, Contract, TokenId) 唯一；NFT 提出托管后删除
+type NftHolding struct {
+	ChainId     uint64
+	Contract    string // 校验和格式
+	TokenId     string // 十进制字符串
+	UserId      string
+	Holder      string // 持有该 NFT 的交易所地址
+	DepositTx   string
+	BlockNumber uint64
+	CreatedAt   time.Time
+}
+
+type BlockCursorStore interface {
+	GetCursor(ctx context.Context, chainId uint64, name string) (*BlockCursor, error)
+	SaveCursor(ctx context.Context, cursor *BlockCursor) error
+}
+
+type AddressStore interface {
+	// CreateAddress 地址已存在时返回 ErrAlreadyExists
+	CreateAddress(ctx context.Context, address *Address) error
+	GetAddress(ctx context.Context, address string) (*Address, error)
+	ListAddressesByUser(ctx context.Context, userId string) ([]Address, error)
+	ListAddressesByKind(ctx context.Context, family, kind string) ([]Address, error)
+}
+
+type DepositStore interface {
+	// SaveDeposit 按 (ChainId, TxHash, LogIndex) 插入或更新
+	SaveDeposit(ctx context.Context, deposit *Deposit) error
+	GetDeposit(ctx context.Context, chainId uint64, txHash string, logIndex uint64) (*Deposit, error)
+	UpdateDepositStatus(ctx context.Context, chainId uint64, txHash string, logIndex uint64, status string) error
+	// ListDepositsByUser 按区块高度倒序，limit<=0 表示不限制
+	ListDepositsByUser(ctx context.Context, userId string, limit int) ([]Deposit, error)
+	ListDepositsByStatus(ctx context.Context, chainId uint64, status string) ([]Deposit, error)
+}
+
+type WithdrawalStore interface {
+	// CreateWithdrawal Id 已存在时返回 ErrAlreadyExists
+	CreateWithdrawal(ctx context.Context, withdrawal *Withdrawal) error
+	GetWithdrawal(ctx context.Context, id string) (*Withdrawal, error)
+	UpdateWithdrawal(ctx context.Context, withdrawal *Withdrawal) error
+	// ListWithdrawalsByUser 按创建时间倒序，limit<=0 表示不限制
+	ListWithdrawalsByUser(ctx context.Context, userId string, limit int) ([]Withdrawal, error)
+	ListWithdrawalsByStatus(ctx context.Context, chainId uint64, status string) ([]Withdrawal, error)
+}
+
+type NonceStore interface {
+	// ReserveNonce 原子地分配下一个 nonce：max(已分配+1, chainNonce)，chainNonce 为链上 pending nonce
+	ReserveNonce(ctx context.Context, chainId uint64, address string, chainNonce uint64) (uint64, error)
+	// ResetNonce 将下一个可分配的 nonce 重置为 next，用于交易被丢弃后回收
+	ResetNonce(ctx context.Context, chainId uint64, address string, next uint64) error
+}
+
+type EventStore interface {
+	// SaveEvent Id 已存在时返回 ErrAlreadyExists
+	SaveEvent(ctx context.Context, event *Event) error
+	GetEvent(ctx context.Context, id string) (*Event, error)
+	// ListEvents 返回 from <= CreatedAt < to 的事件，按秒比较，按时间（秒）与 Id 正序，limit<=0 表示不限制
+	ListEvents(ctx context.Context, from, to time.Time, limit int) ([]Event, error)
+}
+
+type WebhookStore interface {
+	// CreateWebhookEndpoint Id 已存在时返回 ErrAlreadyExists
+	CreateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error
+	GetWebhookEndpoint(ctx context.Context, id string) (*WebhookEndpoint, error)
+	ListWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error)
+	SetWebhookEndpointActive(ctx context.Context, id string, active bool) error
+	// CreateDelivery (EndpointId, EventId) 已存在时返回 ErrAlreadyExists
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// ListDueDeliveries 返回 NextAttemptAt <= now 的待投递记录，按 NextAttemptAt 正序
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error)
+	ListDeliveriesByEvent(ctx context.Context, eventId string) ([]WebhookDelivery, error)
+}
+
+type OutboxStore interface {
+	// AddOutboxMessage 应在 RunInTx 中与业务数据一同写入，成功后回填 Sequence；EventId 已存在时返回 ErrAlreadyExists
+	AddOutboxMessage(ctx context.Context, msg *OutboxMessage) error
+	// ListOutboxMessages 按 Sequence 正序返回待发布的消息，limit<=0 表示不限制
+	ListOutboxMessages(ctx context.Context, limit int) ([]OutboxMessage, error)
+	// DeleteOutboxMessage 发布成功后删除
+	DeleteOutboxMessage(ctx context.Context, sequence uint64) error
+	// RecordOutboxFailure 记录一次发布失败，Attempts 加一
+	RecordOutboxFailure(ctx context.Context, sequence uint64, lastError string) error
+}
+
+type LedgerStore interface {
+	// AddLedgerEntry 应在 RunInTx 中与业务数据一同写入，成功后回填 Sequence；Id 已存在时返回 ErrAlreadyExists
+	AddLedgerEntry(ctx context.Context, entry *LedgerEntry) error
+	// ListLedgerEntries 按 Sequence 正序返回大于 afterSequence 的分录，limit<=0 表示不限制
+	// 并发事务提交的先后可能与分配的 Sequence 不一致，较小的 Sequence 可能晚于较大的出现
+	ListLedgerEntries(ctx context.Context, afterSequence uint64, limit int) ([]LedgerEntry, error)
+}
+
+type NftStore interface {
+	// SaveNftHolding 按 (ChainId, Contract, TokenId) 插入或更新
+	SaveNftHolding(ctx context.Context, holding *NftHolding) error
+	// DeleteNftHolding 记录不存在时返回 ErrNotFound
+	DeleteNftHolding(ctx context.Context, chainId uint64, contract, tokenId string) error
+	// ListNftHoldings 返回链上全部托管中的 NFT，按合约与 tokenId 排序
+	ListNftHoldings(ctx context.Context, chainId uint64) ([]NftHolding, error)
+}
+
+// Repository 钱包持久化接口，SQL 与嵌入式实现共用
+type Repository interface {
+	BlockCursorStore
+	AddressStore
+	DepositStore
+	WithdrawalStore
+	NonceStore
+	EventStore
+	WebhookStore
+	OutboxStore
+	LedgerStore
+	NftStore
+	// RunInTx 在一个事务中执行 fn，fn 内只应使用传入的 repo，返回错误时全部回滚
+	RunInTx(ctx context.Context, fn func(ctx context.Context, repo Repository) error) error
+	Close() error
+}
@@ -0,0 +1,94 @@
+package sqlstore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strings"
+)
+
+// dialect 屏蔽 PostgreSQL/MySQL/SQLite 之间的语法差异
+type dialect struct {
+	name      string
+	driver    string
+	dollar    bool   // 占位符使用 $1、$2
+	forUpdate string // 行锁语法，SQLite 写事务本身串行，为空
+	returning bool   // 自增主键通过 INSERT ... RETURNING 取回，驱动不支持 LastInsertId
+}
+
+var dialects = map[string]*dialect{
+	"postgres": {name: "postgres", driver: "postgres", dollar: true, forUpdate: " FOR UPDATE", returning: true},
+	"mysql":    {name: "mysql", driver: "mysql", forUpdate: " FOR UPDATE"},
+	"sqlite":   {name: "sqlite", driver: "sqlite"},
+}
+
+// rebind 将 ? 占位符转换为方言的写法
+func (d *dialect) rebind(query string) string {
+	if !d.dollar {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// upsert 生成插入或更新语句，冲突时更新 updates 中的列
+func (d *dialect) upsert(table string, columns []string, keys []string, updates []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+
+	sets := make([]string, len(updates))
+	for i, column := range updates {
+		if d.name == "mysql" {
+			sets[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+		} else {
+			sets[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+		}
+	}
+	if d.name == "mysql" {
+		query += " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	} else {
+		query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ", "), strings.Join(sets, ", "))
+	}
+	return d.rebind(query)
+}
+
+// insertIgnore 生成插入语句，keys 冲突时不做任何修改，MySQL 以更新为自身的方式实现
+func (d *dialect) insertIgnore(table string, columns []string, keys []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+	if d.name == "mysql" {
+		query += fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", keys[0], keys[0])
+	} else {
+		query += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(keys, ", "))
+	}
+	return d.rebind(query)
+}
+
+// isDuplicate 判断是否为主键/唯一索引冲突
+func isDuplicate(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
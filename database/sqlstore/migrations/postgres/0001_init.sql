@@ -0,0 +1,62 @@
+CREATE TABLE block_cursors (
+    chain_id   BIGINT      NOT NULL,
+    name       VARCHAR(64) NOT NULL,
+    number     BIGINT      NOT NULL,
+    hash       VARCHAR(66) NOT NULL,
+    updated_at BIGINT      NOT NULL,
+    PRIMARY KEY (chain_id, name)
+);
+
+CREATE TABLE addresses (
+    address    VARCHAR(42) NOT NULL PRIMARY KEY,
+    family     VARCHAR(32) NOT NULL,
+    kind       VARCHAR(16) NOT NULL,
+    user_id    VARCHAR(64) NOT NULL,
+    created_at BIGINT      NOT NULL
+);
+CREATE INDEX addresses_user_id ON addresses (user_id);
+CREATE INDEX addresses_family_kind ON addresses (family, kind);
+
+CREATE TABLE deposits (
+    chain_id     BIGINT      NOT NULL,
+    tx_hash      VARCHAR(66) NOT NULL,
+    log_index    BIGINT      NOT NULL,
+    block_number BIGINT      NOT NULL,
+    block_hash   VARCHAR(66) NOT NULL,
+    token        VARCHAR(42) NOT NULL,
+    from_address VARCHAR(42) NOT NULL,
+    to_address   VARCHAR(42) NOT NULL,
+    amount       VARCHAR(78) NOT NULL,
+    user_id      VARCHAR(64) NOT NULL,
+    status       VARCHAR(16) NOT NULL,
+    created_at   BIGINT      NOT NULL,
+    updated_at   BIGINT      NOT NULL,
+    PRIMARY KEY (chain_id, tx_hash, log_index)
+);
+CREATE INDEX deposits_user_id ON deposits (user_id, block_number);
+CREATE INDEX deposits_status ON deposits (chain_id, status);
+
+CREATE TABLE withdrawals (
+    id           VARCHAR(64) NOT NULL PRIMARY KEY,
+    chain_id     BIGINT      NOT NULL,
+    user_id      VARCHAR(64) NOT NULL,
+    token        VARCHAR(42) NOT NULL,
+    from_address VARCHAR(42) NOT NULL,
+    to_address   VARCHAR(42) NOT NULL,
+    amount       VARCHAR(78) NOT NULL,
+    nonce        BIGINT      NOT NULL,
+    tx_hash      VARCHAR(66) NOT NULL,
+    raw_tx       TEXT        NOT NULL,
+    status       VARCHAR(16) NOT NULL,
+    created_at   BIGINT      NOT NULL,
+    updated_at   BIGINT      NOT NULL
+);
+CREATE INDEX withdrawals_user_id ON withdrawals (user_id, created_at);
+CREATE INDEX withdrawals_status ON withdrawals (chain_id, status);
+
+CREATE TABLE nonces (
+    chain_id   BIGINT      NOT NULL,
+    address    VARCHAR(42) NOT NULL,
+    next_nonce BIGINT      NOT NULL,
+    PRIMARY KEY (chain_id, address)
+);
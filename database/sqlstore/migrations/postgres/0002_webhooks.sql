@@ -0,0 +1,30 @@
+CREATE TABLE events (
+    id         VARCHAR(64) NOT NULL PRIMARY KEY,
+    type       VARCHAR(64) NOT NULL,
+    chain_id   BIGINT      NOT NULL,
+    payload    TEXT        NOT NULL,
+    created_at BIGINT      NOT NULL
+);
+CREATE INDEX events_created_at ON events (created_at);
+
+CREATE TABLE webhook_endpoints (
+    id          VARCHAR(64)   NOT NULL PRIMARY KEY,
+    url         VARCHAR(2048) NOT NULL,
+    secret      VARCHAR(128)  NOT NULL,
+    event_types VARCHAR(512)  NOT NULL,
+    active      BOOLEAN       NOT NULL,
+    created_at  BIGINT        NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    endpoint_id     VARCHAR(64)  NOT NULL,
+    event_id        VARCHAR(64)  NOT NULL,
+    status          VARCHAR(16)  NOT NULL,
+    attempts        BIGINT       NOT NULL,
+    next_attempt_at BIGINT       NOT NULL,
+    last_error      VARCHAR(512) NOT NULL,
+    updated_at      BIGINT       NOT NULL,
+    PRIMARY KEY (endpoint_id, event_id)
+);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_deliveries_event_id ON webhook_deliveries (event_id);
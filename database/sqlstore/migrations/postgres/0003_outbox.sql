@@ -0,0 +1,11 @@
+CREATE TABLE outbox (
+    seq           BIGSERIAL    NOT NULL PRIMARY KEY,
+    event_id      VARCHAR(64)  NOT NULL,
+    type          VARCHAR(64)  NOT NULL,
+    partition_key VARCHAR(128) NOT NULL,
+    payload       TEXT         NOT NULL,
+    attempts      BIGINT       NOT NULL,
+    last_error    VARCHAR(512) NOT NULL,
+    created_at    BIGINT       NOT NULL
+);
+CREATE UNIQUE INDEX outbox_event_id ON outbox (event_id);
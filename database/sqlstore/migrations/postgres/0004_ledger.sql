@@ -0,0 +1,9 @@
+CREATE TABLE ledger_entries (
+    seq        BIGSERIAL    NOT NULL PRIMARY KEY,
+    id         VARCHAR(191) NOT NULL,
+    kind       VARCHAR(32)  NOT NULL,
+    reference  VARCHAR(128) NOT NULL,
+    postings   TEXT         NOT NULL,
+    created_at BIGINT       NOT NULL
+);
+CREATE UNIQUE INDEX ledger_entries_id ON ledger_entries (id);
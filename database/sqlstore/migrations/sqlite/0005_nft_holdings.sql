@@ -0,0 +1,11 @@
+CREATE TABLE nft_holdings (
+    chain_id     INTEGER     NOT NULL,
+    contract     VARCHAR(42) NOT NULL,
+    token_id     VARCHAR(78) NOT NULL,
+    user_id      VARCHAR(64) NOT NULL,
+    holder       VARCHAR(42) NOT NULL,
+    deposit_tx   VARCHAR(66) NOT NULL,
+    block_number INTEGER     NOT NULL,
+    created_at   INTEGER     NOT NULL,
+    PRIMARY KEY (chain_id, contract, token_id)
+);
@@ -0,0 +1,731 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"strings"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+)
+
+// Store 基于 database/sql 的 Repository 实现，支持 PostgreSQL、MySQL 与 SQLite
+type Store struct {
+	db      *sql.DB
+	q       querier // 事务外为 db，RunInTx 内为 tx
+	tx      *sql.Tx
+	dialect *dialect
+}
+
+// querier *sql.DB 与 *sql.Tx 共有的查询方法
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var _ database.Repository = (*Store)(nil)
+
+// Open 连接数据库并执行迁移，dialectName 为 postgres / mysql / sqlite
+// MySQL 的 DSN 总是开启 clientFoundRows，否则更新未变化的行会被当作 ErrNotFound
+func Open(ctx context.Context, dialectName, dsn string) (*Store, error) {
+	d, ok := dialects[dialectName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sql dialect %q", dialectName)
+	}
+	if d.name == "mysql" {
+		var err error
+		if dsn, err = mysqlDSN(dsn); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if d.name == "sqlite" {
+		// SQLite 只允许单写，避免 SQLITE_BUSY
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrate(ctx, db, d); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, q: db, dialect: d}, nil
+}
+
+// mysqlDSN 开启 clientFoundRows，使 RowsAffected 返回匹配的行数而不是实际改变的行数
+func mysqlDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	cfg.ClientFoundRows = true
+	return cfg.FormatDSN(), nil
+}
+
+// Close 关闭数据库连接，RunInTx 传入的 repo 上调用无效果
+func (s *Store) Close() error {
+	if s.tx != nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// RunInTx 在同一个数据库事务中执行 fn，fn 返回错误时回滚；嵌套调用复用外层事务
+func (s *Store) RunInTx(ctx context.Context, fn func(ctx context.Context, repo database.Repository) error) error {
+	if s.tx != nil {
+		return fn(ctx, s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, &Store{db: s.db, q: tx, tx: tx, dialect: s.dialect}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) GetCursor(ctx context.Context, chainId uint64, name string) (*database.BlockCursor, error) {
+	cursor := &database.BlockCursor{ChainId: chainId, Name: name}
+	var updatedAt int64
+	err := s.q.QueryRowContext(ctx, s.dialect.rebind("SELECT number, hash, updated_at FROM block_cursors WHERE chain_id = ? AND name = ?"), chainId, name).
+		Scan(&cursor.Number, &cursor.Hash, &updatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	cursor.UpdatedAt = time.Unix(updatedAt, 0)
+	return cursor, nil
+}
+
+func (s *Store) SaveCursor(ctx context.Context, cursor *database.BlockCursor) error {
+	if cursor.UpdatedAt.IsZero() {
+		cursor.UpdatedAt = time.Now()
+	}
+	query := s.dialect.upsert("block_cursors",
+		[]string{"chain_id", "name", "number", "hash", "updated_at"},
+		[]string{"chain_id", "name"},
+		[]string{"number", "hash", "updated_at"})
+	_, err := s.q.ExecContext(ctx, query, cursor.ChainId, cursor.Name, cursor.Number, cursor.Hash, cursor.UpdatedAt.Unix())
+	return err
+}
+
+func (s *Store) CreateAddress(ctx context.Context, address *database.Address) error {
+	if address.CreatedAt.IsZero() {
+		address.CreatedAt = time.Now()
+	}
+	_, err := s.q.ExecContext(ctx, s.dialect.rebind("INSERT INTO addresses (address, family, kind, user_id, created_at) VALUES (?, ?, ?, ?, ?)"),
+		address.Address, address.Family, address.Kind, address.UserId, address.CreatedAt.Unix())
+	if isDuplicate(err) {
+		return database.ErrAlreadyExists
+	}
+	return err
+}
+
+const addressColumns = "address, family, kind, user_id, created_at"
+
+func (s *Store) GetAddress(ctx context.Context, address string) (*database.Address, error) {
+	row := s.q.QueryRowContext(ctx, s.dialect.rebind("SELECT "+addressColumns+" FROM addresses WHERE address = ?"), address)
+	result, err := scanAddress(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return result, nil
+}
+
+func (s *Store) ListAddressesByUser(ctx context.Context, userId string) ([]database.Address, error) {
+	return s.queryAddresses(ctx, "SELECT "+addressColumns+" FROM addresses WHERE user_id = ? ORDER BY created_at, address", userId)
+}
+
+func (s *Store) ListAddressesByKind(ctx context.Context, family, kind string) ([]database.Address, error) {
+	return s.queryAddresses(ctx, "SELECT "+addressColumns+" FROM addresses WHERE family = ? AND kind = ? ORDER BY created_at, address", family, kind)
+}
+
+func (s *Store) queryAddresses(ctx context.Context, query string, args ...any) ([]database.Address, error) {
+	rows, err := s.q.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.Address
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *address)
+	}
+	return result, rows.Err()
+}
+
+const depositColumns = "chain_id, tx_hash, log_index, block_number, block_hash, token, from_address, to_address, amount, user_id, status, created_at, updated_at"
+
+func (s *Store) SaveDeposit(ctx context.Context, deposit *database.Deposit) error {
+	now := time.Now()
+	if deposit.CreatedAt.IsZero() {
+		deposit.CreatedAt = now
+	}
+	deposit.UpdatedAt = now
+	query := s.dialect.upsert("deposits",
+		[]string{"chain_id", "tx_hash", "log_index", "block_number", "block_hash", "token", "from_address", "to_address", "amount", "user_id", "status", "created_at", "updated_at"},
+		[]string{"chain_id", "tx_hash", "log_index"},
+		[]string{"block_number", "block_hash", "token", "from_address", "to_address", "amount", "user_id", "status", "updated_at"})
+	_, err := s.q.ExecContext(ctx, query,
+		deposit.ChainId, deposit.TxHash, deposit.LogIndex, deposit.BlockNumber, deposit.BlockHash, deposit.Token,
+		deposit.From, deposit.To, deposit.Amount, deposit.UserId, deposit.Status, deposit.CreatedAt.Unix(), deposit.UpdatedAt.Unix())
+	return err
+}
+
+func (s *Store) GetDeposit(ctx context.Context, chainId uint64, txHash string, logIndex uint64) (*database.Deposit, error) {
+	row := s.q.QueryRowContext(ctx, s.dialect.rebind("SELECT "+depositColumns+" FROM deposits WHERE chain_id = ? AND tx_hash = ? AND log_index = ?"), chainId, txHash, logIndex)
+	deposit, err := scanDeposit(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return deposit, nil
+}
+
+func (s *Store) UpdateDepositStatus(ctx context.Context, chainId uint64, txHash string, logIndex uint64, status string) error {
+	result, err := s.q.ExecContext(ctx, s.dialect.rebind("UPDATE deposits SET status = ?, updated_at = ? WHERE chain_id = ? AND tx_hash = ? AND log_index = ?"),
+		status, time.Now().Unix(), chainId, txHash, logIndex)
+	return checkAffected(result, err)
+}
+
+func (s *Store) ListDepositsByUser(ctx context.Context, userId string, limit int) ([]database.Deposit, error) {
+	query := "SELECT " + depositColumns + " FROM deposits WHERE user_id = ? ORDER BY block_number DESC, log_index DESC"
+	return s.queryDeposits(ctx, withLimit(query, limit), userId)
+}
+
+func (s *Store) ListDepositsByStatus(ctx context.Context, chainId uint64, status string) ([]database.Deposit, error) {
+	query := "SELECT " + depositColumns + " FROM deposits WHERE chain_id = ? AND status = ? ORDER BY block_number, log_index"
+	return s.queryDeposits(ctx, query, chainId, status)
+}
+
+func (s *Store) queryDeposits(ctx context.Context, query string, args ...any) ([]database.Deposit, error) {
+	rows, err := s.q.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.Deposit
+	for rows.Next() {
+		deposit, err := scanDeposit(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *deposit)
+	}
+	return result, rows.Err()
+}
+
+const withdrawalColumns = "id, chain_id, user_id, token, from_address, to_address, amount, nonce, tx_hash, raw_tx, status, created_at, updated_at"
+
+func (s *Store) CreateWithdrawal(ctx context.Context, withdrawal *database.Withdrawal) error {
+	now := time.Now()
+	if withdrawal.CreatedAt.IsZero() {
+		withdrawal.CreatedAt = now
+	}
+	withdrawal.UpdatedAt = now
+	_, err := s.q.ExecContext(ctx, s.dialect.rebind("INSERT INTO withdrawals ("+withdrawalColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		withdrawal.Id, withdrawal.ChainId, withdrawal.UserId, withdrawal.Token, withdrawal.From, withdrawal.To, withdrawal.Amount,
+		withdrawal.Nonce, withdrawal.TxHash, withdrawal.RawTx, withdrawal.Status, withdrawal.CreatedAt.Unix(), withdrawal.UpdatedAt.Unix())
+	if isDuplicate(err) {
+		return database.ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *Store) GetWithdrawal(ctx context.Context, id string) (*database.Withdrawal, error) {
+	row := s.q.QueryRowContext(ctx, s.dialect.rebind("SELECT "+withdrawalColumns+" FROM withdrawals WHERE id = ?"), id)
+	withdrawal, err := scanWithdrawal(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return withdrawal, nil
+}
+
+func (s *Store) UpdateWithdrawal(ctx context.Context, withdrawal *database.Withdrawal) error {
+	withdrawal.UpdatedAt = time.Now()
+	result, err := s.q.ExecContext(ctx, s.dialect.rebind("UPDATE withdrawals SET from_address = ?, nonce = ?, tx_hash = ?, raw_tx = ?, status = ?, updated_at = ? WHERE id = ?"),
+		withdrawal.From, withdrawal.Nonce, withdrawal.TxHash, withdrawal.RawTx, withdrawal.Status, withdrawal.UpdatedAt.Unix(), withdrawal.Id)
+	return checkAffected(result, err)
+}
+
+func (s *Store) ListWithdrawalsByUser(ctx context.Context, userId string, limit int) ([]database.Withdrawal, error) {
+	query := "SELECT " + withdrawalColumns + " FROM withdrawals WHERE user_id = ? ORDER BY created_at DESC, id DESC"
+	return s.queryWithdrawals(ctx, withLimit(query, limit), userId)
+}
+
+func (s *Store) ListWithdrawalsByStatus(ctx context.Context, chainId uint64, status string) ([]database.Withdrawal, error) {
+	query := "SELECT " + withdrawalColumns + " FROM withdrawals WHERE chain_id = ? AND status = ? ORDER BY created_at, id"
+	return s.queryWithdrawals(ctx, query, chainId, status)
+}
+
+func (s *Store) queryWithdrawals(ctx context.Context, query string, args ...any) ([]database.Withdrawal, error) {
+	rows, err := s.q.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.Withdrawal
+	for rows.Next() {
+		withdrawal, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *withdrawal)
+	}
+	return result, rows.Err()
+}
+
+func (s *Store) ReserveNonce(ctx context.Context, chainId uint64, address string, chainNonce uint64) (uint64, error) {
+	var nonce uint64
+	err := s.RunInTx(ctx, func(ctx context.Context, repo database.Repository) error {
+		tx := repo.(*Store).tx
+		// 首次分配时先插入占位行再加锁，否则 SELECT ... FOR UPDATE 锁不到行，并发的首次分配会同时插入而冲突
+		insert := s.dialect.insertIgnore("nonces", []string{"chain_id", "address", "next_nonce"}, []string{"chain_id", "address"})
+		if _, err := tx.ExecContext(ctx, insert, chainId, address, 0); err != nil {
+			return err
+		}
+		var next uint64
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT next_nonce FROM nonces WHERE chain_id = ? AND address = ?"+s.dialect.forUpdate), chainId, address).Scan(&next)
+		if err != nil {
+			return err
+		}
+
+		nonce = max(next, chainNonce)
+		_, err = tx.ExecContext(ctx, s.dialect.rebind("UPDATE nonces SET next_nonce = ? WHERE chain_id = ? AND address = ?"), nonce+1, chainId, address)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return nonce, nil
+}
+
+func (s *Store) ResetNonce(ctx context.Context, chainId uint64, address string, next uint64) error {
+	query := s.dialect.upsert("nonces", []string{"chain_id", "address", "next_nonce"}, []string{"chain_id", "address"}, []string{"next_nonce"})
+	_, err := s.q.ExecContext(ctx, query, chainId, address, next)
+	return err
+}
+
+func (s *Store) SaveEvent(ctx context.Context, event *database.Event) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	_, err := s.q.ExecContext(ctx, s.dialect.rebind("INSERT INTO events ("+eventColumns+") VALUES (?, ?, ?, ?, ?)"),
+		event.Id, event.Type, event.ChainId, event.Payload, event.CreatedAt.Unix())
+	if isDuplicate(err) {
+		return database.ErrAlreadyExists
+	}
+	return err
+}
+
+const eventColumns = "id, type, chain_id, payload, created_at"
+
+func (s *Store) GetEvent(ctx context.Context, id string) (*database.Event, error) {
+	row := s.q.QueryRowContext(ctx, s.dialect.rebind("SELECT "+eventColumns+" FROM events WHERE id = ?"), id)
+	event, err := scanEvent(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return event, nil
+}
+
+func (s *Store) ListEvents(ctx context.Context, from, to time.Time, limit int) ([]database.Event, error) {
+	query := "SELECT " + eventColumns + " FROM events WHERE created_at >= ? AND created_at < ? ORDER BY created_at, id"
+	rows, err := s.q.QueryContext(ctx, s.dialect.rebind(withLimit(query, limit)), from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *event)
+	}
+	return result, rows.Err()
+}
+
+const webhookEndpointColumns = "id, url, secret, event_types, active, created_at"
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, endpoint *database.WebhookEndpoint) error {
+	if endpoint.CreatedAt.IsZero() {
+		endpoint.CreatedAt = time.Now()
+	}
+	_, err := s.q.ExecContext(ctx, s.dialect.rebind("INSERT INTO webhook_endpoints ("+webhookEndpointColumns+") VALUES (?, ?, ?, ?, ?, ?)"),
+		endpoint.Id, endpoint.URL, endpoint.Secret, strings.Join(endpoint.EventTypes, ","), endpoint.Active, endpoint.CreatedAt.Unix())
+	if isDuplicate(err) {
+		return database.ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *Store) GetWebhookEndpoint(ctx context.Context, id string) (*database.WebhookEndpoint, error) {
+	row := s.q.QueryRowContext(ctx, s.dialect.rebind("SELECT "+webhookEndpointColumns+" FROM webhook_endpoints WHERE id = ?"), id)
+	endpoint, err := scanWebhookEndpoint(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return endpoint, nil
+}
+
+func (s *Store) ListWebhookEndpoints(ctx context.Context) ([]database.WebhookEndpoint, error) {
+	rows, err := s.q.QueryContext(ctx, "SELECT "+webhookEndpointColumns+" FROM webhook_endpoints ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *endpoint)
+	}
+	return result, rows.Err()
+}
+
+func (s *Store) SetWebhookEndpointActive(ctx context.Context, id string, active bool) error {
+	result, err := s.q.ExecContext(ctx, s.dialect.rebind("UPDATE webhook_endpoints SET active = ? WHERE id = ?"), active, id)
+	return checkAffected(result, err)
+}
+
+const deliveryColumns = "endpoint_id, event_id, status, attempts, next_attempt_at, last_error, updated_at"
+
+func (s *Store) CreateDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	_, err := s.q.ExecContext(ctx, s.dialect.rebind("INSERT INTO webhook_deliveries ("+deliveryColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		delivery.EndpointId, delivery.EventId, delivery.Status, delivery.Attempts, delivery.NextAttemptAt.Unix(), delivery.LastError, delivery.UpdatedAt.Unix())
+	if isDuplicate(err) {
+		return database.ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	result, err := s.q.ExecContext(ctx, s.dialect.rebind("UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = ? WHERE endpoint_id = ? AND event_id = ?"),
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt.Unix(), delivery.LastError, delivery.UpdatedAt.Unix(), delivery.EndpointId, delivery.EventId)
+	return checkAffected(result, err)
+}
+
+func (s *Store) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]database.WebhookDelivery, error) {
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, event_id, endpoint_id"
+	return s.queryDeliveries(ctx, withLimit(query, limit), database.DeliveryStatusPending, now.Unix())
+}
+
+func (s *Store) ListDeliveriesByEvent(ctx context.Context, eventId string) ([]database.WebhookDelivery, error) {
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE event_id = ? ORDER BY endpoint_id"
+	return s.queryDeliveries(ctx, query, eventId)
+}
+
+func (s *Store) queryDeliveries(ctx context.Context, query string, args ...any) ([]database.WebhookDelivery, error) {
+	rows, err := s.q.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *delivery)
+	}
+	return result, rows.Err()
+}
+
+const outboxColumns = "seq, event_id, type, partition_key, payload, attempts, last_error, created_at"
+
+func (s *Store) AddOutboxMessage(ctx context.Context, msg *database.OutboxMessage) error {
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	query := s.dialect.rebind("INSERT INTO outbox (event_id, type, partition_key, payload, attempts, last_error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	args := []any{msg.EventId, msg.Type, msg.PartitionKey, msg.Payload, msg.Attempts, msg.LastError, msg.CreatedAt.Unix()}
+
+	var err error
+	if s.dialect.returning {
+		err = s.q.QueryRowContext(ctx, query+" RETURNING seq", args...).Scan(&msg.Sequence)
+	} else {
+		var result sql.Result
+		if result, err = s.q.ExecContext(ctx, query, args...); err == nil {
+			var id int64
+			id, err = result.LastInsertId()
+			msg.Sequence = uint64(id)
+		}
+	}
+	if isDuplicate(err) {
+		return database.ErrAlreadyExists
+	}
+	return err
+}
+
+// ListOutboxMessages 并发事务提交的先后可能与分配的 seq 不一致，较小的 seq 可能晚于较大的出现
+func (s *Store) ListOutboxMessages(ctx context.Context, limit int) ([]database.OutboxMessage, error) {
+	rows, err := s.q.QueryContext(ctx, withLimit("SELECT "+outboxColumns+" FROM outbox ORDER BY seq", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.OutboxMessage
+	for rows.Next() {
+		msg, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *msg)
+	}
+	return result, rows.Err()
+}
+
+func (s *Store) DeleteOutboxMessage(ctx context.Context, sequence uint64) error {
+	result, err := s.q.ExecContext(ctx, s.dialect.rebind("DELETE FROM outbox WHERE seq = ?"), sequence)
+	return checkAffected(result, err)
+}
+
+func (s *Store) RecordOutboxFailure(ctx context.Context, sequence uint64, lastError string) error {
+	result, err := s.q.ExecContext(ctx, s.dialect.rebind("UPDATE outbox SET attempts = attempts + 1, last_error = ? WHERE seq = ?"), lastError, sequence)
+	return checkAffected(result, err)
+}
+
+const ledgerColumns = "seq, id, kind, reference, postings, created_at"
+
+func (s *Store) AddLedgerEntry(ctx context.Context, entry *database.LedgerEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	query := s.dialect.rebind("INSERT INTO ledger_entries (id, kind, reference, postings, created_at) VALUES (?, ?, ?, ?, ?)")
+	args := []any{entry.Id, entry.Kind, entry.Reference, entry.Postings, entry.CreatedAt.Unix()}
+
+	var err error
+	if s.dialect.returning {
+		err = s.q.QueryRowContext(ctx, query+" RETURNING seq", args...).Scan(&entry.Sequence)
+	} else {
+		var result sql.Result
+		if result, err = s.q.ExecContext(ctx, query, args...); err == nil {
+			var id int64
+			id, err = result.LastInsertId()
+			entry.Sequence = uint64(id)
+		}
+	}
+	if isDuplicate(err) {
+		return database.ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *Store) ListLedgerEntries(ctx context.Context, afterSequence uint64, limit int) ([]database.LedgerEntry, error) {
+	rows, err := s.q.QueryContext(ctx, withLimit(s.dialect.rebind("SELECT "+ledgerColumns+" FROM ledger_entries WHERE seq > ? ORDER BY seq"), limit), afterSequence)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.LedgerEntry
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *entry)
+	}
+	return result, rows.Err()
+}
+
+const nftHoldingColumns = "chain_id, contract, token_id, user_id, holder, deposit_tx, block_number, created_at"
+
+func (s *Store) SaveNftHolding(ctx context.Context, holding *database.NftHolding) error {
+	if holding.CreatedAt.IsZero() {
+		holding.CreatedAt = time.Now()
+	}
+	query := s.dialect.upsert("nft_holdings",
+		[]string{"chain_id", "contract", "token_id", "user_id", "holder", "deposit_tx", "block_number", "created_at"},
+		[]string{"chain_id", "contract", "token_id"},
+		[]string{"user_id", "holder", "deposit_tx", "block_number"})
+	_, err := s.q.ExecContext(ctx, query,
+		holding.ChainId, holding.Contract, holding.TokenId, holding.UserId, holding.Holder, holding.DepositTx, holding.BlockNumber, holding.CreatedAt.Unix())
+	return err
+}
+
+func (s *Store) DeleteNftHolding(ctx context.Context, chainId uint64, contract, tokenId string) error {
+	result, err := s.q.ExecContext(ctx, s.dialect.rebind("DELETE FROM nft_holdings WHERE chain_id = ? AND contract = ? AND token_id = ?"), chainId, contract, tokenId)
+	return checkAffected(result, err)
+}
+
+func (s *Store) ListNftHoldings(ctx context.Context, chainId uint64) ([]database.NftHolding, error) {
+	rows, err := s.q.QueryContext(ctx, s.dialect.rebind("SELECT "+nftHoldingColumns+" FROM nft_holdings WHERE chain_id = ? ORDER BY contract, token_id"), chainId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []database.NftHolding
+	for rows.Next() {
+		holding, err := scanNftHolding(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *holding)
+	}
+	return result, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAddress(row scanner) (*database.Address, error) {
+	var address database.Address
+	var createdAt int64
+	if err := row.Scan(&address.Address, &address.Family, &address.Kind, &address.UserId, &createdAt); err != nil {
+		return nil, err
+	}
+	address.CreatedAt = time.Unix(createdAt, 0)
+	return &address, nil
+}
+
+func scanDeposit(row scanner) (*database.Deposit, error) {
+	var deposit database.Deposit
+	var createdAt, updatedAt int64
+	err := row.Scan(&deposit.ChainId, &deposit.TxHash, &deposit.LogIndex, &deposit.BlockNumber, &deposit.BlockHash, &deposit.Token,
+		&deposit.From, &deposit.To, &deposit.Amount, &deposit.UserId, &deposit.Status, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	deposit.CreatedAt = time.Unix(createdAt, 0)
+	deposit.UpdatedAt = time.Unix(updatedAt, 0)
+	return &deposit, nil
+}
+
+func scanWithdrawal(row scanner) (*database.Withdrawal, error) {
+	var withdrawal database.Withdrawal
+	var createdAt, updatedAt int64
+	err := row.Scan(&withdrawal.Id, &withdrawal.ChainId, &withdrawal.UserId, &withdrawal.Token, &withdrawal.From, &withdrawal.To, &withdrawal.Amount,
+		&withdrawal.Nonce, &withdrawal.TxHash, &withdrawal.RawTx, &withdrawal.Status, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	withdrawal.CreatedAt = time.Unix(createdAt, 0)
+	withdrawal.UpdatedAt = time.Unix(updatedAt, 0)
+	return &withdrawal, nil
+}
+
+func scanEvent(row scanner) (*database.Event, error) {
+	var event database.Event
+	var createdAt int64
+	if err := row.Scan(&event.Id, &event.Type, &event.ChainId, &event.Payload, &createdAt); err != nil {
+		return nil, err
+	}
+	event.CreatedAt = time.Unix(createdAt, 0)
+	return &event, nil
+}
+
+func scanWebhookEndpoint(row scanner) (*database.WebhookEndpoint, error) {
+	var endpoint database.WebhookEndpoint
+	var eventTypes string
+	var createdAt int64
+	if err := row.Scan(&endpoint.Id, &endpoint.URL, &endpoint.Secret, &eventTypes, &endpoint.Active, &createdAt); err != nil {
+		return nil, err
+	}
+	if eventTypes != "" {
+		endpoint.EventTypes = strings.Split(eventTypes, ",")
+	}
+	endpoint.CreatedAt = time.Unix(createdAt, 0)
+	return &endpoint, nil
+}
+
+func scanDelivery(row scanner) (*database.WebhookDelivery, error) {
+	var delivery database.WebhookDelivery
+	var nextAttemptAt, updatedAt int64
+	err := row.Scan(&delivery.EndpointId, &delivery.EventId, &delivery.Status, &delivery.Attempts, &nextAttemptAt, &delivery.LastError, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.NextAttemptAt = time.Unix(nextAttemptAt, 0)
+	delivery.UpdatedAt = time.Unix(updatedAt, 0)
+	return &delivery, nil
+}
+
+func scanOutboxMessage(row scanner) (*database.OutboxMessage, error) {
+	var msg database.OutboxMessage
+	var createdAt int64
+	err := row.Scan(&msg.Sequence, &msg.EventId, &msg.Type, &msg.PartitionKey, &msg.Payload, &msg.Attempts, &msg.LastError, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	msg.CreatedAt = time.Unix(createdAt, 0)
+	return &msg, nil
+}
+
+func scanLedgerEntry(row scanner) (*database.LedgerEntry, error) {
+	var entry database.LedgerEntry
+	var createdAt int64
+	if err := row.Scan(&entry.Sequence, &entry.Id, &entry.Kind, &entry.Reference, &entry.Postings, &createdAt); err != nil {
+		return nil, err
+	}
+	entry.CreatedAt = time.Unix(createdAt, 0)
+	return &entry, nil
+}
+
+func scanNftHolding(row scanner) (*database.NftHolding, error) {
+	var holding database.NftHolding
+	var createdAt int64
+	err := row.Scan(&holding.ChainId, &holding.Contract, &holding.TokenId, &holding.UserId, &holding.Holder, &holding.DepositTx, &holding.BlockNumber, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	holding.CreatedAt = time.Unix(createdAt, 0)
+	return &holding, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.ErrNotFound
+	}
+	return err
+}
+
+func checkAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return database.ErrNotFound
+	}
+	return nil
+}
+
+func withLimit(query string, limit int) string {
+	if limit <= 0 {
+		return query
+	}
+	return fmt.Sprintf("%s LIMIT %d", query, limit)
+}
@@ -0,0 +1,97 @@
+package sqlstore
+
+import (
+	"context"
+	"github.com/go-sql-driver/mysql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/storetest"
+)
+
+func TestConformanceSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Repository {
+		store, err := Open(context.Background(), "sqlite", filepath.Join(t.TempDir(), "wallet.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
+
+// PostgreSQL 与 MySQL 需要外部数据库，设置环境变量后运行，每个子测试前清空表
+func TestConformanceServers(t *testing.T) {
+	for dialectName, env := range map[string]string{
+		"postgres": "ETHCEX_TEST_POSTGRES_DSN",
+		"mysql":    "ETHCEX_TEST_MYSQL_DSN",
+	} {
+		dsn := os.Getenv(env)
+		if dsn == "" {
+			t.Logf("%s not set, skip %s", env, dialectName)
+			continue
+		}
+		t.Run(dialectName, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) database.Repository {
+				store, err := Open(context.Background(), dialectName, dsn)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, table := range []string{"block_cursors", "addresses", "deposits", "withdrawals", "nonces", "events", "webhook_endpoints", "webhook_deliveries", "outbox", "ledger_entries", "nft_holdings"} {
+					if _, err := store.db.Exec("DELETE FROM " + table); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return store
+			})
+		})
+	}
+}
+
+func TestMigrateIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet.db")
+	for i := 0; i < 2; i++ {
+		store, err := Open(context.Background(), "sqlite", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Close()
+	}
+}
+
+func TestRebind(t *testing.T) {
+	got := dialects["postgres"].rebind("SELECT a FROM t WHERE b = ? AND c = ?")
+	if got != "SELECT a FROM t WHERE b = $1 AND c = $2" {
+		t.Errorf("rebind() = %s", got)
+	}
+}
+
+func TestInsertIgnore(t *testing.T) {
+	columns, keys := []string{"a", "b", "c"}, []string{"a", "b"}
+	if got := dialects["postgres"].insertIgnore("t", columns, keys); got != "INSERT INTO t (a, b, c) VALUES ($1, $2, $3) ON CONFLICT (a, b) DO NOTHING" {
+		t.Errorf("insertIgnore() = %s", got)
+	}
+	if got := dialects["mysql"].insertIgnore("t", columns, keys); got != "INSERT INTO t (a, b, c) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE a = a" {
+		t.Errorf("insertIgnore() = %s", got)
+	}
+}
+
+func TestMysqlDSN(t *testing.T) {
+	for _, dsn := range []string{
+		"wallet:secret@tcp(127.0.0.1:3306)/wallet",
+		"wallet:secret@tcp(127.0.0.1:3306)/wallet?clientFoundRows=false&parseTime=true",
+	} {
+		got, err := mysqlDSN(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := mysql.ParseDSN(got)
+		if err != nil || !cfg.ClientFoundRows || cfg.Passwd != "secret" || cfg.DBName != "wallet" {
+			t.Errorf("mysqlDSN(%q) = %q, err %v", dsn, got, err)
+		}
+	}
+	if _, err := Open(context.Background(), "mysql", "not a dsn"); err == nil {
+		t.Error("expected invalid dsn error")
+	}
+}
@@ -0,0 +1,624 @@
+// Package storetest 提供 database.Repository 实现共用的一致性测试
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+)
+
+// Run 对 open 返回的新 Repository 运行全部一致性测试，每个子测试使用独立的空库
+func Run(t *testing.T, open func(t *testing.T) database.Repository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo database.Repository)
+	}{
+		{"BlockCursor", testBlockCursor},
+		{"Address", testAddress},
+		{"Deposit", testDeposit},
+		{"Withdrawal", testWithdrawal},
+		{"Nonce", testNonce},
+		{"Event", testEvent},
+		{"Webhook", testWebhook},
+		{"Outbox", testOutbox},
+		{"Ledger", testLedger},
+		{"NftHolding", testNftHolding},
+		{"Transaction", testTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := open(t)
+			defer repo.Close()
+			tt.fn(t, repo)
+		})
+	}
+}
+
+func testBlockCursor(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	if _, err := repo.GetCursor(ctx, 1, "deposit"); !errors.Is(err, database.ErrNotFound) {
+		t.Fatalf("GetCursor() on empty store err = %v", err)
+	}
+
+	for _, number := range []uint64{100, 101} {
+		cursor := &database.BlockCursor{ChainId: 1, Name: "deposit", Number: number, Hash: fmt.Sprintf("0x%064x", number)}
+		if err := repo.SaveCursor(ctx, cursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SaveCursor(ctx, &database.BlockCursor{ChainId: 10, Name: "deposit", Number: 5, Hash: "0x05"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := repo.GetCursor(ctx, 1, "deposit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.Number != 101 || cursor.Hash != fmt.Sprintf("0x%064x", 101) || cursor.UpdatedAt.IsZero() {
+		t.Errorf("GetCursor() = %+v", cursor)
+	}
+	if cursor, _ := repo.GetCursor(ctx, 10, "deposit"); cursor == nil || cursor.Number != 5 {
+		t.Errorf("cursors of different chains are not isolated: %+v", cursor)
+	}
+}
+
+func testAddress(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	addresses := []database.Address{
+		{Address: "0xEB80a127b2b763C631D8ADCeBb0976b190C8C227", Family: "evm", Kind: database.AddressKindDeposit, UserId: "u1", CreatedAt: time.Unix(1000, 0)},
+		{Address: "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74", Family: "evm", Kind: database.AddressKindDeposit, UserId: "u1", CreatedAt: time.Unix(2000, 0)},
+		{Address: "0x01C9E6bdb351AD536236b508092f49eDEe5be0e6", Family: "evm", Kind: database.AddressKindHot, CreatedAt: time.Unix(3000, 0)},
+	}
+	for i := range addresses {
+		if err := repo.CreateAddress(ctx, &addresses[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateAddress(ctx, &database.Address{Address: addresses[0].Address, Family: "evm", Kind: database.AddressKindDeposit, UserId: "u2"}); !errors.Is(err, database.ErrAlreadyExists) {
+		t.Errorf("CreateAddress() duplicate err = %v", err)
+	}
+
+	got, err := repo.GetAddress(ctx, addresses[0].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserId != "u1" || got.Kind != database.AddressKindDeposit || got.CreatedAt.Unix() != 1000 {
+		t.Errorf("GetAddress() = %+v", got)
+	}
+	if _, err := repo.GetAddress(ctx, "0x0000000000000000000000000000000000000000"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("GetAddress() missing err = %v", err)
+	}
+
+	byUser, err := repo.ListAddressesByUser(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byUser) != 2 || byUser[0].Address != addresses[0].Address || byUser[1].Address != addresses[1].Address {
+		t.Errorf("ListAddressesByUser() = %+v", byUser)
+	}
+	hot, err := repo.ListAddressesByKind(ctx, "evm", database.AddressKindHot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hot) != 1 || hot[0].Address != addresses[2].Address {
+		t.Errorf("ListAddressesByKind() = %+v", hot)
+	}
+}
+
+func testDeposit(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	for i := uint64(0); i < 3; i++ {
+		deposit := &database.Deposit{
+			ChainId:     1,
+			TxHash:      fmt.Sprintf("0x%064x", i),
+			LogIndex:    i,
+			BlockNumber: 100 + i,
+			BlockHash:   fmt.Sprintf("0x%064x", 100+i),
+			Token:       "0x779877A7B0D9E8603169DdbD7836e478b4624789",
+			From:        "0x01C9E6bdb351AD536236b508092f49eDEe5be0e6",
+			To:          "0xEB80a127b2b763C631D8ADCeBb0976b190C8C227",
+			Amount:      "1000000000000000000000000",
+			UserId:      "u1",
+			Status:      database.DepositStatusPending,
+		}
+		if err := repo.SaveDeposit(ctx, deposit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 同一 (chain, tx, logIndex) 重复保存为更新
+	updated := &database.Deposit{
+		ChainId: 1, TxHash: fmt.Sprintf("0x%064x", 0), LogIndex: 0, BlockNumber: 100, BlockHash: "0xreorged",
+		Token: "", From: "0x01", To: "0x02", Amount: "5", UserId: "u1", Status: database.DepositStatusPending,
+	}
+	if err := repo.SaveDeposit(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetDeposit(ctx, 1, fmt.Sprintf("0x%064x", 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BlockHash != "0xreorged" || got.Amount != "5" {
+		t.Errorf("GetDeposit() after upsert = %+v", got)
+	}
+	if _, err := repo.GetDeposit(ctx, 2, fmt.Sprintf("0x%064x", 0), 0); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("GetDeposit() other chain err = %v", err)
+	}
+
+	if err := repo.UpdateDepositStatus(ctx, 1, fmt.Sprintf("0x%064x", 1), 1, database.DepositStatusConfirmed); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateDepositStatus(ctx, 1, "0xmissing", 0, database.DepositStatusConfirmed); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("UpdateDepositStatus() missing err = %v", err)
+	}
+
+	pending, err := repo.ListDepositsByStatus(ctx, 1, database.DepositStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].BlockNumber != 100 || pending[1].BlockNumber != 102 {
+		t.Errorf("ListDepositsByStatus() = %+v", pending)
+	}
+
+	byUser, err := repo.ListDepositsByUser(ctx, "u1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byUser) != 2 || byUser[0].BlockNumber != 102 || byUser[1].BlockNumber != 101 || byUser[1].Status != database.DepositStatusConfirmed {
+		t.Errorf("ListDepositsByUser() = %+v", byUser)
+	}
+	if byUser[0].Amount != "1000000000000000000000000" {
+		t.Errorf("large amount not preserved: %s", byUser[0].Amount)
+	}
+}
+
+func testWithdrawal(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		withdrawal := &database.Withdrawal{
+			Id:        fmt.Sprintf("w%d", i),
+			ChainId:   1,
+			UserId:    "u1",
+			To:        "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74",
+			Amount:    "1000",
+			Status:    database.WithdrawalStatusCreated,
+			CreatedAt: time.Unix(int64(1000+i), 0),
+		}
+		if err := repo.CreateWithdrawal(ctx, withdrawal); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateWithdrawal(ctx, &database.Withdrawal{Id: "w0", ChainId: 1, UserId: "u2"}); !errors.Is(err, database.ErrAlreadyExists) {
+		t.Errorf("CreateWithdrawal() duplicate err = %v", err)
+	}
+
+	w, err := repo.GetWithdrawal(ctx, "w1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.From = "0xEB80a127b2b763C631D8ADCeBb0976b190C8C227"
+	w.Nonce = 7
+	w.TxHash = "0xabc"
+	w.RawTx = "0x02f8"
+	w.Status = database.WithdrawalStatusBroadcast
+	if err := repo.UpdateWithdrawal(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetWithdrawal(ctx, "w1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Nonce != 7 || got.TxHash != "0xabc" || got.Status != database.WithdrawalStatusBroadcast || got.Amount != "1000" || got.CreatedAt.Unix() != 1001 {
+		t.Errorf("GetWithdrawal() after update = %+v", got)
+	}
+	if err := repo.UpdateWithdrawal(ctx, &database.Withdrawal{Id: "missing"}); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("UpdateWithdrawal() missing err = %v", err)
+	}
+
+	byUser, err := repo.ListWithdrawalsByUser(ctx, "u1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byUser) != 3 || byUser[0].Id != "w2" || byUser[2].Id != "w0" {
+		t.Errorf("ListWithdrawalsByUser() = %+v", byUser)
+	}
+	created, err := repo.ListWithdrawalsByStatus(ctx, 1, database.WithdrawalStatusCreated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || created[0].Id != "w0" || created[1].Id != "w2" {
+		t.Errorf("ListWithdrawalsByStatus() = %+v", created)
+	}
+}
+
+func testNonce(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	address := "0xEB80a127b2b763C631D8ADCeBb0976b190C8C227"
+
+	// 首次分配以链上 nonce 为准
+	nonce, err := repo.ReserveNonce(ctx, 1, address, 5)
+	if err != nil || nonce != 5 {
+		t.Fatalf("ReserveNonce() = %d %v, want 5", nonce, err)
+	}
+	// 链上 nonce 落后于本地分配时继续递增
+	nonce, err = repo.ReserveNonce(ctx, 1, address, 3)
+	if err != nil || nonce != 6 {
+		t.Fatalf("ReserveNonce() = %d %v, want 6", nonce, err)
+	}
+	// 链上 nonce 超前（外部发过交易）时跟随链上
+	nonce, err = repo.ReserveNonce(ctx, 1, address, 10)
+	if err != nil || nonce != 10 {
+		t.Fatalf("ReserveNonce() = %d %v, want 10", nonce, err)
+	}
+	if nonce, _ := repo.ReserveNonce(ctx, 2, address, 0); nonce != 0 {
+		t.Errorf("nonce of different chains are not isolated: %d", nonce)
+	}
+
+	if err := repo.ResetNonce(ctx, 1, address, 8); err != nil {
+		t.Fatal(err)
+	}
+	if nonce, _ := repo.ReserveNonce(ctx, 1, address, 0); nonce != 8 {
+		t.Errorf("ReserveNonce() after reset = %d, want 8", nonce)
+	}
+
+	// 并发分配不重复，包括从未分配过的地址上的并发首次分配
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.ReserveNonce(ctx, 3, address, 0); err != nil {
+				t.Error(err)
+			}
+			nonce, err := repo.ReserveNonce(ctx, 1, address, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[nonce] {
+				t.Errorf("nonce %d reserved twice", nonce)
+			}
+			seen[nonce] = true
+		}()
+	}
+	wg.Wait()
+	if nonce, _ := repo.ReserveNonce(ctx, 3, address, 0); nonce != 20 {
+		t.Errorf("ReserveNonce() after concurrent first use = %d, want 20", nonce)
+	}
+}
+
+func testEvent(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		event := &database.Event{
+			Id:        fmt.Sprintf("e%d", i),
+			Type:      "deposit.confirmed",
+			ChainId:   1,
+			Payload:   fmt.Sprintf(`{"id":"e%d"}`, i),
+			CreatedAt: time.Unix(int64(1000+i), 0),
+		}
+		if err := repo.SaveEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SaveEvent(ctx, &database.Event{Id: "e0", Type: "x"}); !errors.Is(err, database.ErrAlreadyExists) {
+		t.Errorf("SaveEvent() duplicate err = %v", err)
+	}
+
+	event, err := repo.GetEvent(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payload != `{"id":"e1"}` || event.ChainId != 1 || event.CreatedAt.Unix() != 1001 {
+		t.Errorf("GetEvent() = %+v", event)
+	}
+	if _, err := repo.GetEvent(ctx, "missing"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("GetEvent() missing err = %v", err)
+	}
+
+	// 区间左闭右开
+	events, err := repo.ListEvents(ctx, time.Unix(1001, 0), time.Unix(1002, 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Id != "e1" {
+		t.Errorf("ListEvents() = %+v", events)
+	}
+	events, err = repo.ListEvents(ctx, time.Unix(0, 0), time.Unix(2000, 0), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Id != "e0" || events[1].Id != "e1" {
+		t.Errorf("ListEvents() with limit = %+v", events)
+	}
+}
+
+func testWebhook(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	endpoints := []*database.WebhookEndpoint{
+		{Id: "ep1", URL: "https://a.example/hook", Secret: "s1", EventTypes: []string{"deposit.confirmed", "withdrawal.status"}, Active: true, CreatedAt: time.Unix(1000, 0)},
+		{Id: "ep2", URL: "https://b.example/hook", Secret: "s2", Active: true, CreatedAt: time.Unix(1001, 0)},
+	}
+	for _, endpoint := range endpoints {
+		if err := repo.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateWebhookEndpoint(ctx, &database.WebhookEndpoint{Id: "ep1"}); !errors.Is(err, database.ErrAlreadyExists) {
+		t.Errorf("CreateWebhookEndpoint() duplicate err = %v", err)
+	}
+	if err := repo.SetWebhookEndpointActive(ctx, "ep2", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetWebhookEndpointActive(ctx, "missing", false); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("SetWebhookEndpointActive() missing err = %v", err)
+	}
+
+	list, err := repo.ListWebhookEndpoints(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Id != "ep1" || !list[0].Active || list[1].Active || list[1].EventTypes != nil {
+		t.Errorf("ListWebhookEndpoints() = %+v", list)
+	}
+	endpoint, err := repo.GetWebhookEndpoint(ctx, "ep1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.Secret != "s1" || len(endpoint.EventTypes) != 2 || endpoint.EventTypes[1] != "withdrawal.status" {
+		t.Errorf("GetWebhookEndpoint() = %+v", endpoint)
+	}
+
+	for i, endpointId := range []string{"ep1", "ep2"} {
+		delivery := &database.WebhookDelivery{
+			EndpointId:    endpointId,
+			EventId:       "e1",
+			Status:        database.DeliveryStatusPending,
+			NextAttemptAt: time.Unix(int64(1000+i*100), 0),
+		}
+		if err := repo.CreateDelivery(ctx, delivery); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateDelivery(ctx, &database.WebhookDelivery{EndpointId: "ep1", EventId: "e1"}); !errors.Is(err, database.ErrAlreadyExists) {
+		t.Errorf("CreateDelivery() duplicate err = %v", err)
+	}
+
+	due, err := repo.ListDueDeliveries(ctx, time.Unix(1050, 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].EndpointId != "ep1" {
+		t.Fatalf("ListDueDeliveries() = %+v", due)
+	}
+
+	due[0].Status = database.DeliveryStatusDelivered
+	due[0].Attempts = 1
+	if err := repo.UpdateDelivery(ctx, &due[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateDelivery(ctx, &database.WebhookDelivery{EndpointId: "ep1", EventId: "missing"}); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("UpdateDelivery() missing err = %v", err)
+	}
+	// 已投递的记录不再到期
+	due, err = repo.ListDueDeliveries(ctx, time.Unix(2000, 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].EndpointId != "ep2" {
+		t.Errorf("ListDueDeliveries() after delivery = %+v", due)
+	}
+
+	deliveries, err := repo.ListDeliveriesByEvent(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Status != database.DeliveryStatusDelivered || deliveries[0].Attempts != 1 || deliveries[1].Status != database.DeliveryStatusPending {
+		t.Errorf("ListDeliveriesByEvent() = %+v", deliveries)
+	}
+}
+
+func testOutbox(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	var sequences []uint64
+	for i := 0; i < 3; i++ {
+		msg := &database.OutboxMessage{
+			EventId:      fmt.Sprintf("e%d", i),
+			Type:         "deposit.confirmed",
+			PartitionKey: "u1",
+			Payload:      fmt.Sprintf(`{"id":"e%d"}`, i),
+		}
+		if err := repo.AddOutboxMessage(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+		if len(sequences) > 0 && msg.Sequence <= sequences[len(sequences)-1] {
+			t.Fatalf("sequence %d not increasing after %v", msg.Sequence, sequences)
+		}
+		sequences = append(sequences, msg.Sequence)
+	}
+	if err := repo.AddOutboxMessage(ctx, &database.OutboxMessage{EventId: "e0"}); !errors.Is(err, database.ErrAlreadyExists) {
+		t.Errorf("AddOutboxMessage() duplicate err = %v", err)
+	}
+
+	if err := repo.RecordOutboxFailure(ctx, sequences[0], "broker down"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RecordOutboxFailure(ctx, sequences[0], "broker still down"); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := repo.ListOutboxMessages(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].EventId != "e0" || messages[1].EventId != "e1" {
+		t.Fatalf("ListOutboxMessages() = %+v", messages)
+	}
+	if messages[0].Attempts != 2 || messages[0].LastError != "broker still down" || messages[0].PartitionKey != "u1" || messages[0].Payload != `{"id":"e0"}` {
+		t.Errorf("failed message = %+v", messages[0])
+	}
+
+	if err := repo.DeleteOutboxMessage(ctx, sequences[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteOutboxMessage(ctx, sequences[0]); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("DeleteOutboxMessage() twice err = %v", err)
+	}
+	messages, err = repo.ListOutboxMessages(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].EventId != "e1" {
+		t.Errorf("ListOutboxMessages() after delete = %+v", messages)
+	}
+}
+
+func testLedger(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	var sequences []uint64
+	for i := 0; i < 3; i++ {
+		entry := &database.LedgerEntry{
+			Id:        fmt.Sprintf("deposit:1:0x%02d:0", i),
+			Kind:      "deposit",
+			Reference: fmt.Sprintf("0x%02d", i),
+			Postings:  fmt.Sprintf(`[{"Amount":%d}]`, i+1),
+		}
+		if err := repo.AddLedgerEntry(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+		if len(sequences) > 0 && entry.Sequence <= sequences[len(sequences)-1] {
+			t.Fatalf("sequence %d not increasing after %v", entry.Sequence, sequences)
+		}
+		sequences = append(sequences, entry.Sequence)
+	}
+	if err := repo.AddLedgerEntry(ctx, &database.LedgerEntry{Id: "deposit:1:0x00:0", Kind: "deposit"}); !errors.Is(err, database.ErrAlreadyExists) {
+		t.Errorf("AddLedgerEntry() duplicate err = %v", err)
+	}
+
+	entries, err := repo.ListLedgerEntries(ctx, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Sequence != sequences[0] || entries[2].Id != "deposit:1:0x02:0" {
+		t.Fatalf("ListLedgerEntries() = %+v", entries)
+	}
+	if entries[1].Kind != "deposit" || entries[1].Reference != "0x01" || entries[1].Postings != `[{"Amount":2}]` || entries[1].CreatedAt.IsZero() {
+		t.Errorf("entry = %+v", entries[1])
+	}
+	entries, err = repo.ListLedgerEntries(ctx, sequences[0], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Sequence != sequences[1] {
+		t.Errorf("ListLedgerEntries() after %d = %+v", sequences[0], entries)
+	}
+}
+
+func testNftHolding(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	contract := "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74"
+	for _, holding := range []*database.NftHolding{
+		{ChainId: 1, Contract: contract, TokenId: "7", UserId: "u1", Holder: "0x01", DepositTx: "0xaa", BlockNumber: 10},
+		{ChainId: 1, Contract: contract, TokenId: "1", UserId: "u2", Holder: "0x02", DepositTx: "0xbb", BlockNumber: 11},
+		{ChainId: 2, Contract: contract, TokenId: "1", UserId: "u1", Holder: "0x01", DepositTx: "0xcc", BlockNumber: 12},
+	} {
+		if err := repo.SaveNftHolding(ctx, holding); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 同一 NFT 再次转入时覆盖归属
+	if err := repo.SaveNftHolding(ctx, &database.NftHolding{ChainId: 1, Contract: contract, TokenId: "7", UserId: "u3", Holder: "0x03", DepositTx: "0xdd", BlockNumber: 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	holdings, err := repo.ListNftHoldings(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holdings) != 2 || holdings[0].TokenId != "1" || holdings[1].TokenId != "7" {
+		t.Fatalf("ListNftHoldings() = %+v", holdings)
+	}
+	if h := holdings[1]; h.UserId != "u3" || h.Holder != "0x03" || h.DepositTx != "0xdd" || h.BlockNumber != 20 || h.CreatedAt.IsZero() {
+		t.Errorf("holding = %+v", h)
+	}
+
+	if err := repo.DeleteNftHolding(ctx, 1, contract, "7"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteNftHolding(ctx, 1, contract, "7"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("DeleteNftHolding() missing err = %v", err)
+	}
+	if holdings, err := repo.ListNftHoldings(ctx, 1); err != nil || len(holdings) != 1 || holdings[0].UserId != "u2" {
+		t.Errorf("ListNftHoldings() after delete = %+v, err %v", holdings, err)
+	}
+}
+
+func testTransaction(t *testing.T, repo database.Repository) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	// fn 返回错误时业务数据与发件箱消息一起回滚
+	err := repo.RunInTx(ctx, func(ctx context.Context, tx database.Repository) error {
+		if err := tx.CreateWithdrawal(ctx, &database.Withdrawal{Id: "w1", ChainId: 1, UserId: "u1", Status: database.WithdrawalStatusCreated}); err != nil {
+			return err
+		}
+		if err := tx.AddOutboxMessage(ctx, &database.OutboxMessage{EventId: "e1", Type: "withdrawal.status"}); err != nil {
+			return err
+		}
+		if err := tx.AddLedgerEntry(ctx, &database.LedgerEntry{Id: "withdrawal:w1", Kind: "withdrawal", Postings: "[]"}); err != nil {
+			return err
+		}
+		if _, err := tx.GetWithdrawal(ctx, "w1"); err != nil {
+			t.Errorf("GetWithdrawal() inside transaction err = %v", err)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("RunInTx() err = %v", err)
+	}
+	if _, err := repo.GetWithdrawal(ctx, "w1"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("withdrawal visible after rollback, err = %v", err)
+	}
+	if messages, _ := repo.ListOutboxMessages(ctx, 0); len(messages) != 0 {
+		t.Errorf("outbox message visible after rollback: %+v", messages)
+	}
+	if entries, _ := repo.ListLedgerEntries(ctx, 0, 0); len(entries) != 0 {
+		t.Errorf("ledger entry visible after rollback: %+v", entries)
+	}
+
+	var nonce uint64
+	err = repo.RunInTx(ctx, func(ctx context.Context, tx database.Repository) error {
+		if err := tx.CreateWithdrawal(ctx, &database.Withdrawal{Id: "w1", ChainId: 1, UserId: "u1", Status: database.WithdrawalStatusCreated}); err != nil {
+			return err
+		}
+		// 嵌套事务与 ReserveNonce 复用外层事务
+		return tx.RunInTx(ctx, func(ctx context.Context, tx database.Repository) error {
+			var err error
+			nonce, err = tx.ReserveNonce(ctx, 1, "0xEB80a127b2b763C631D8ADCeBb0976b190C8C227", 3)
+			if err != nil {
+				return err
+			}
+			return tx.AddOutboxMessage(ctx, &database.OutboxMessage{EventId: "e1", Type: "withdrawal.status"})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetWithdrawal(ctx, "w1"); err != nil {
+		t.Errorf("GetWithdrawal() after commit err = %v", err)
+	}
+	if messages, _ := repo.ListOutboxMessages(ctx, 0); len(messages) != 1 || messages[0].EventId != "e1" {
+		t.Errorf("ListOutboxMessages() after commit = %+v", messages)
+	}
+	if next, _ := repo.ReserveNonce(ctx, 1, "0xEB80a127b2b763C631D8ADCeBb0976b190C8C227", 0); nonce != 3 || next != 4 {
+		t.Errorf("nonce in transaction = %d, next = %d", nonce, next)
+	}
+}
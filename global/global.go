@@ -3,5 +3,6 @@ package global
 import "github.com/0xweb-3/EthCEXWallet/config"
 
 var (
-	ServerConfig *config.Config = &config.Config{}
+	// ServerConfig 当前生效的配置，热加载时整体替换，读取方每次通过 Current 获取快照
+	ServerConfig = config.NewStore(&config.Config{})
 )
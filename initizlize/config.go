@@ -1,34 +1,18 @@
 package initizlize
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/0xweb-3/EthCEXWallet/config"
 	"github.com/0xweb-3/EthCEXWallet/global"
-	"github.com/fsnotify/fsnotify"
-	"github.com/spf13/viper"
 )
 
-func InitConfig() {
-	v := viper.New()
-	fileName := "../config/conf/config.yaml"
-
-	v.SetConfigFile(fileName)
-	if err := v.ReadInConfig(); err != nil {
-		panic(err)
+// InitConfig 加载并校验配置写入 global.ServerConfig，path 为空时按 config.ResolvePath 查找
+// 之后监听文件变更直到 ctx 取消，变更校验失败时保留原配置并记录日志
+func InitConfig(ctx context.Context, path string) error {
+	path = config.ResolvePath(path)
+	if err := global.ServerConfig.Reload(path); err != nil {
+		return err
 	}
-
-	if err := v.Unmarshal(&global.ServerConfig); err != nil {
-		panic(err)
-	}
-	fmt.Println(global.ServerConfig.Name)
-
-	v.WatchConfig()
-	v.OnConfigChange(func(in fsnotify.Event) {
-		err := v.ReadInConfig()
-		if err != nil {
-			panic(err)
-		}
-		if err := v.Unmarshal(&global.ServerConfig); err != nil {
-			panic(err)
-		}
-	})
+	return global.ServerConfig.Watch(ctx, path)
 }
@@ -1,7 +1,19 @@
 package initizlize
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/global"
+)
 
 func TestInitConfig(t *testing.T) {
-	InitConfig()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := InitConfig(ctx, "../config/conf/config.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if chain, err := global.ServerConfig.Current().Chain("arb"); err != nil || chain.ChainId != 42161 {
+		t.Fatalf("arb chain: %+v, %v", chain, err)
+	}
 }
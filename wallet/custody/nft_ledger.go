@@ -0,0 +1,273 @@
+package custody
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"sort"
+	"sync"
+
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+var (
+	ErrNftNotInCustody = errors.New("nft not in custody")
+	ErrNftNotOwned     = errors.New("nft not owned by user")
+)
+
+// nftKey 以 (合约, tokenId) 唯一标识一个 NFT
+type nftKey struct {
+	contract common.Address
+	tokenId  string
+}
+
+// NftLedger NFT 托管账本，记录每个 (合约, tokenId) 归属的用户
+// 入账与出账经 CreditWith、DebitWith 等与业务数据一同持久化，重启后由 LoadNftLedger 加载
+type NftLedger struct {
+	client node.EthClient
+
+	mu       sync.RWMutex
+	holdings map[nftKey]*WalletTypes.NftHolding
+}
+
+// nftCredit 一笔待入账的 ERC-721 充值
+type nftCredit struct {
+	userId   string
+	transfer *WalletEthereum.Erc721Transfer
+}
+
+func NewNftLedger(client node.EthClient) *NftLedger {
+	return &NftLedger{
+		client:   client,
+		holdings: make(map[nftKey]*WalletTypes.NftHolding),
+	}
+}
+
+// ProcessLogs 从日志中识别转入交易所地址的 ERC-721 充值并入账
+// lookupUser 将链上地址映射为用户 ID，非交易所地址返回 false
+func (l *NftLedger) ProcessLogs(logs []types.Log, lookupUser func(common.Address) (string, bool)) ([]*WalletTypes.NftHolding, error) {
+	return l.ProcessLogsWith(logs, lookupUser, nil)
+}
+
+// ProcessLogsWith 与 ProcessLogs 相同，日志中的充值原子地入账：先调用 persist 持久化本次新入账的 NFT，persist 返回错误时全部不入账
+// persist 在账本锁内执行；全部为重复入账时不调用
+func (l *NftLedger) ProcessLogsWith(logs []types.Log, lookupUser func(common.Address) (string, bool), persist func(credited []*WalletTypes.NftHolding) error) ([]*WalletTypes.NftHolding, error) {
+	var credits []nftCredit
+	for _, log := range logs {
+		if log.Removed || !WalletEthereum.IsErc721TransferLog(log) {
+			continue
+		}
+		transfer, err := WalletEthereum.DecodeErc721TransferLog(log)
+		if err != nil {
+			return nil, err
+		}
+		userId, ok := lookupUser(transfer.To)
+		if !ok {
+			continue
+		}
+		credits = append(credits, nftCredit{userId: userId, transfer: transfer})
+	}
+	return l.credit(credits, persist)
+}
+
+// Credit 将一笔 ERC-721 充值记入用户名下，同一 NFT 重复入账视为成功（日志重放）
+func (l *NftLedger) Credit(userId string, transfer *WalletEthereum.Erc721Transfer) (*WalletTypes.NftHolding, error) {
+	return l.CreditWith(userId, transfer, nil)
+}
+
+// CreditWith 与 Credit 相同，入账前调用 persist 持久化，persist 返回错误时不入账；重复入账不调用 persist
+func (l *NftLedger) CreditWith(userId string, transfer *WalletEthereum.Erc721Transfer, persist func(holding *WalletTypes.NftHolding) error) (*WalletTypes.NftHolding, error) {
+	var persistAll func([]*WalletTypes.NftHolding) error
+	if persist != nil {
+		persistAll = func(credited []*WalletTypes.NftHolding) error { return persist(credited[0]) }
+	}
+	credited, err := l.credit([]nftCredit{{userId: userId, transfer: transfer}}, persistAll)
+	if err != nil {
+		return nil, err
+	}
+	return credited[0], nil
+}
+
+// credit 先在暂存表上入账，全部校验通过且 persist 成功后再落账，返回值与 credits 一一对应
+func (l *NftLedger) credit(credits []nftCredit, persist func(credited []*WalletTypes.NftHolding) error) ([]*WalletTypes.NftHolding, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	staged := make(map[nftKey]*WalletTypes.NftHolding)
+	result := make([]*WalletTypes.NftHolding, 0, len(credits))
+	var fresh []*WalletTypes.NftHolding
+	for _, credit := range credits {
+		transfer := credit.transfer
+		key := nftKey{contract: transfer.Token, tokenId: transfer.TokenId.String()}
+		existing, ok := staged[key]
+		if !ok {
+			existing, ok = l.holdings[key]
+		}
+		if ok {
+			if existing.DepositTx == transfer.TxHash.Hex() {
+				result = append(result, existing)
+				continue
+			}
+			if existing.UserId != credit.userId {
+				return nil, fmt.Errorf("nft %s #%s already held by another user", key.contract.Hex(), key.tokenId)
+			}
+		}
+
+		holding := &WalletTypes.NftHolding{
+			UserId:      credit.userId,
+			Contract:    transfer.Token.Hex(),
+			TokenId:     key.tokenId,
+			Holder:      transfer.To.Hex(),
+			DepositTx:   transfer.TxHash.Hex(),
+			BlockNumber: transfer.BlockNumber,
+		}
+		staged[key] = holding
+		fresh = append(fresh, holding)
+		result = append(result, holding)
+	}
+	if len(fresh) == 0 {
+		return result, nil
+	}
+
+	if persist != nil {
+		if err := persist(fresh); err != nil {
+			return nil, err
+		}
+	}
+	for key, holding := range staged {
+		l.holdings[key] = holding
+	}
+	return result, nil
+}
+
+// Debit 提现完成后将 NFT 从用户名下移除
+func (l *NftLedger) Debit(userId string, contract common.Address, tokenId *big.Int) error {
+	return l.DebitWith(userId, contract, tokenId, nil)
+}
+
+// DebitWith 与 Debit 相同，移除前调用 persist 持久化，persist 返回错误时 NFT 仍在用户名下
+func (l *NftLedger) DebitWith(userId string, contract common.Address, tokenId *big.Int, persist func(holding *WalletTypes.NftHolding) error) error {
+	key := nftKey{contract: contract, tokenId: tokenId.String()}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	holding, ok := l.holdings[key]
+	if !ok {
+		return ErrNftNotInCustody
+	}
+	if holding.UserId != userId {
+		return ErrNftNotOwned
+	}
+	if persist != nil {
+		if err := persist(holding); err != nil {
+			return err
+		}
+	}
+	delete(l.holdings, key)
+	return nil
+}
+
+// RevertAfter 区块回滚时移除在 blockNumber 之后的区块中入账的 NFT，移除前调用 persist 持久化，persist 返回错误时不移除
+// 没有需要移除的 NFT 时不调用 persist；返回移除的 NFT，按合约与 tokenId 排序
+func (l *NftLedger) RevertAfter(blockNumber uint64, persist func(reverted []*WalletTypes.NftHolding) error) ([]*WalletTypes.NftHolding, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var keys []nftKey
+	for key, holding := range l.holdings {
+		if holding.BlockNumber > blockNumber {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].contract != keys[j].contract {
+			return keys[i].contract.Hex() < keys[j].contract.Hex()
+		}
+		return keys[i].tokenId < keys[j].tokenId
+	})
+	reverted := make([]*WalletTypes.NftHolding, len(keys))
+	for i, key := range keys {
+		reverted[i] = l.holdings[key]
+	}
+
+	if persist != nil {
+		if err := persist(reverted); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range keys {
+		delete(l.holdings, key)
+	}
+	return reverted, nil
+}
+
+// OwnerOf 查询 NFT 在账本中的归属
+func (l *NftLedger) OwnerOf(contract common.Address, tokenId *big.Int) (*WalletTypes.NftHolding, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	holding, ok := l.holdings[nftKey{contract: contract, tokenId: tokenId.String()}]
+	if !ok {
+		return nil, false
+	}
+	cpy := *holding
+	return &cpy, true
+}
+
+// Holdings 返回用户持有的全部 NFT，按合约与 tokenId 排序
+func (l *NftLedger) Holdings(userId string) []WalletTypes.NftHolding {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var result []WalletTypes.NftHolding
+	for _, holding := range l.holdings {
+		if holding.UserId == userId {
+			result = append(result, *holding)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Contract != result[j].Contract {
+			return result[i].Contract < result[j].Contract
+		}
+		return result[i].TokenId < result[j].TokenId
+	})
+	return result
+}
+
+// VerifyWithdrawal 提现前校验：账本中归属该用户，且链上 ownerOf 仍为托管地址
+func (l *NftLedger) VerifyWithdrawal(ctx context.Context, userId string, contract common.Address, tokenId *big.Int) (*WalletTypes.NftHolding, error) {
+	holding, ok := l.OwnerOf(contract, tokenId)
+	if !ok {
+		return nil, ErrNftNotInCustody
+	}
+	if holding.UserId != userId {
+		return nil, ErrNftNotOwned
+	}
+
+	data, err := WalletEthereum.BuildErc721OwnerOfData(tokenId)
+	if err != nil {
+		return nil, err
+	}
+	result, err := l.client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := WalletEthereum.DecodeErc721OwnerOfResult(result)
+	if err != nil {
+		return nil, err
+	}
+	if owner != common.HexToAddress(holding.Holder) {
+		return nil, fmt.Errorf("on-chain owner %s differs from custody holder %s", owner.Hex(), holding.Holder)
+	}
+	return holding, nil
+}
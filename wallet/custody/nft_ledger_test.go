@@ -0,0 +1,92 @@
+package custody
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+// ownerOfClient 只实现 CallContract，返回固定的 ownerOf 结果
+type ownerOfClient struct {
+	node.EthClient
+	owner common.Address
+}
+
+func (c *ownerOfClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return common.LeftPadBytes(c.owner.Bytes(), 32), nil
+}
+
+func TestNftLedger(t *testing.T) {
+	nft := common.HexToAddress("0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238")
+	erc20 := common.HexToAddress("0x779877A7B0D9E8603169DdbD7836e478b4624789")
+	sender := common.HexToAddress("0x01C9E6bdb351AD536236b508092f49eDEe5be0e6")
+	deposit := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	transferTopic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+	logs := []types.Log{
+		// ERC-20 Transfer：value 在 data 中，不应入账
+		{
+			Address: erc20,
+			Topics:  []common.Hash{transferTopic, common.BytesToHash(sender.Bytes()), common.BytesToHash(deposit.Bytes())},
+			Data:    common.LeftPadBytes(big.NewInt(100).Bytes(), 32),
+		},
+		// ERC-721 Transfer：tokenId 为第 4 个 topic
+		{
+			Address: nft,
+			Topics:  []common.Hash{transferTopic, common.BytesToHash(sender.Bytes()), common.BytesToHash(deposit.Bytes()), common.BigToHash(big.NewInt(7))},
+			TxHash:  common.HexToHash("0x01"),
+		},
+	}
+
+	client := &ownerOfClient{owner: deposit}
+	ledger := NewNftLedger(client)
+	// 持久化失败时不入账
+	failed := errors.New("db down")
+	if _, err := ledger.ProcessLogsWith(logs, func(common.Address) (string, bool) { return "user-1", true }, func([]*WalletTypes.NftHolding) error { return failed }); err != failed {
+		t.Fatalf("ProcessLogsWith() err = %v", err)
+	}
+	if _, ok := ledger.OwnerOf(nft, big.NewInt(7)); ok {
+		t.Fatal("nft credited although persist failed")
+	}
+	credited, err := ledger.ProcessLogs(logs, func(address common.Address) (string, bool) {
+		return "user-1", address == deposit
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credited) != 1 || credited[0].TokenId != "7" || credited[0].Contract != nft.Hex() {
+		t.Fatalf("credited = %+v", credited)
+	}
+	// 日志重放不会重复入账
+	if _, err := ledger.ProcessLogs(logs, func(common.Address) (string, bool) { return "user-1", true }); err != nil {
+		t.Fatal(err)
+	}
+	if holdings := ledger.Holdings("user-1"); len(holdings) != 1 {
+		t.Fatalf("holdings = %+v", holdings)
+	}
+
+	if _, err := ledger.VerifyWithdrawal(context.Background(), "user-2", nft, big.NewInt(7)); err != ErrNftNotOwned {
+		t.Errorf("verify other user err = %v", err)
+	}
+	if _, err := ledger.VerifyWithdrawal(context.Background(), "user-1", nft, big.NewInt(7)); err != nil {
+		t.Errorf("verify err = %v", err)
+	}
+	client.owner = sender
+	if _, err := ledger.VerifyWithdrawal(context.Background(), "user-1", nft, big.NewInt(7)); err == nil {
+		t.Error("expected on-chain owner mismatch")
+	}
+
+	if err := ledger.Debit("user-1", nft, big.NewInt(7)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ledger.OwnerOf(nft, big.NewInt(7)); ok {
+		t.Error("nft still in custody after debit")
+	}
+}
@@ -0,0 +1,49 @@
+package custody
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+// SaveHolding 写入入账的 NFT，应在 CreditWith 或 ProcessLogsWith 的 persist 中与业务数据在同一事务内调用
+func SaveHolding(ctx context.Context, repo database.NftStore, chainId uint64, holding *WalletTypes.NftHolding) error {
+	return repo.SaveNftHolding(ctx, &database.NftHolding{
+		ChainId:     chainId,
+		Contract:    holding.Contract,
+		TokenId:     holding.TokenId,
+		UserId:      holding.UserId,
+		Holder:      holding.Holder,
+		DepositTx:   holding.DepositTx,
+		BlockNumber: holding.BlockNumber,
+	})
+}
+
+// DeleteHolding 删除提出托管或被回滚的 NFT，应在 DebitWith 或 RevertAfter 的 persist 中调用
+func DeleteHolding(ctx context.Context, repo database.NftStore, chainId uint64, holding *WalletTypes.NftHolding) error {
+	return repo.DeleteNftHolding(ctx, chainId, holding.Contract, holding.TokenId)
+}
+
+// LoadNftLedger 从 repo 加载链上托管中的 NFT，重建进程重启前的托管账本
+func LoadNftLedger(ctx context.Context, client node.EthClient, chainId uint64, repo database.NftStore) (*NftLedger, error) {
+	records, err := repo.ListNftHoldings(ctx, chainId)
+	if err != nil {
+		return nil, err
+	}
+	l := NewNftLedger(client)
+	for _, record := range records {
+		contract := common.HexToAddress(record.Contract)
+		l.holdings[nftKey{contract: contract, tokenId: record.TokenId}] = &WalletTypes.NftHolding{
+			UserId:      record.UserId,
+			Contract:    contract.Hex(),
+			TokenId:     record.TokenId,
+			Holder:      record.Holder,
+			DepositTx:   record.DepositTx,
+			BlockNumber: record.BlockNumber,
+		}
+	}
+	return l, nil
+}
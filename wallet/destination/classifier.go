@@ -0,0 +1,162 @@
+package destination
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"sync"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+type Kind string
+
+const (
+	KindEOA           Kind = "eoa"
+	KindContract      Kind = "contract"
+	KindDelegatedEOA  Kind = "delegated_eoa" // EIP-7702 委托给合约的 EOA
+	KindTokenContract Kind = "token_contract"
+)
+
+type Action string
+
+const (
+	ActionAllow Action = "allow"
+	ActionWarn  Action = "warn"
+	ActionBlock Action = "block"
+)
+
+const (
+	// EOA 以后可能部署 7702 委托，缓存需要较快过期
+	defaultEOACacheTTL = 10 * time.Minute
+	// 合约代码很少变化，但 CREATE2 地址可能在销毁后重新部署，同样需要过期
+	defaultContractCacheTTL = 24 * time.Hour
+	// defaultCacheSize 缓存的地址数上限，查询的地址来自用户输入，超出后淘汰最久未使用的
+	defaultCacheSize = 10000
+)
+
+// Classification 目标地址的分类结果
+type Classification struct {
+	Address  common.Address
+	Kind     Kind
+	Delegate common.Address // KindDelegatedEOA 时的委托合约
+}
+
+// Decision 提现目标检查结果
+type Decision struct {
+	Classification
+	Action Action
+	Reason string
+}
+
+type cacheEntry struct {
+	classification Classification
+	expireAt       time.Time
+}
+
+// Classifier 基于 eth_getCode 的提现目标分类器，每条链一个实例，缓存按链隔离
+type Classifier struct {
+	client  node.EthClient
+	chainId uint64
+	tokens  map[common.Address]bool
+	ttl     time.Duration
+	// contractTTL 合约分类结果的缓存时间
+	contractTTL time.Duration
+
+	mu    sync.Mutex
+	cache lru.BasicLRU[common.Address, cacheEntry]
+}
+
+// NewClassifier tokens 为该链上交易所支持的代币合约地址
+func NewClassifier(client node.EthClient, chainId uint64, tokens []common.Address) *Classifier {
+	c := &Classifier{
+		client:      client,
+		chainId:     chainId,
+		tokens:      make(map[common.Address]bool, len(tokens)),
+		ttl:         defaultEOACacheTTL,
+		contractTTL: defaultContractCacheTTL,
+		cache:       lru.NewBasicLRU[common.Address, cacheEntry](defaultCacheSize),
+	}
+	for _, token := range tokens {
+		c.tokens[token] = true
+	}
+	return c
+}
+
+func (c *Classifier) ChainId() uint64 {
+	return c.chainId
+}
+
+// Classify 判断地址是 EOA、合约、7702 委托 EOA 还是已知代币合约
+func (c *Classifier) Classify(ctx context.Context, address common.Address) (*Classification, error) {
+	if c.tokens[address] {
+		return &Classification{Address: address, Kind: KindTokenContract}, nil
+	}
+
+	c.mu.Lock()
+	entry, ok := c.cache.Get(address)
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expireAt) {
+		classification := entry.classification
+		return &classification, nil
+	}
+
+	code, err := c.client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entry = cacheEntry{classification: Classification{Address: address}}
+	if delegate, ok := types.ParseDelegation(code); ok {
+		entry.classification.Kind = KindDelegatedEOA
+		entry.classification.Delegate = delegate
+		entry.expireAt = time.Now().Add(c.ttl)
+	} else if len(code) > 0 {
+		entry.classification.Kind = KindContract
+		entry.expireAt = time.Now().Add(c.contractTTL)
+	} else {
+		entry.classification.Kind = KindEOA
+		entry.expireAt = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	c.cache.Add(address, entry)
+	c.mu.Unlock()
+
+	classification := entry.classification
+	return &classification, nil
+}
+
+// CheckWithdrawal 检查提现目标，token 为零地址表示原生币
+func (c *Classifier) CheckWithdrawal(ctx context.Context, to common.Address, token common.Address) (*Decision, error) {
+	classification, err := c.Classify(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{Classification: *classification, Action: ActionAllow}
+	switch {
+	case classification.Kind == KindTokenContract || (token != common.Address{} && to == token):
+		decision.Action = ActionBlock
+		decision.Reason = "destination is a token contract, funds sent to it are unrecoverable"
+	case classification.Kind == KindContract && token == common.Address{}:
+		decision.Action = ActionWarn
+		decision.Reason = "destination is a contract and may reject native transfers without a payable fallback"
+	case classification.Kind == KindContract:
+		decision.Action = ActionWarn
+		decision.Reason = "destination is a contract, make sure it can handle received tokens"
+	case classification.Kind == KindDelegatedEOA:
+		decision.Action = ActionWarn
+		decision.Reason = "destination is an EIP-7702 delegated account, its code may reject transfers"
+	}
+	return decision, nil
+}
+
+// Invalidate 清除地址缓存，例如观察到该地址的 7702 授权交易后
+func (c *Classifier) Invalidate(address common.Address) {
+	c.mu.Lock()
+	c.cache.Remove(address)
+	c.mu.Unlock()
+}
@@ -0,0 +1,99 @@
+package destination
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+type codeClient struct {
+	node.EthClient
+	code  map[common.Address][]byte
+	calls int
+}
+
+func (c *codeClient) CodeAt(ctx context.Context, address common.Address, blockNumber *big.Int) ([]byte, error) {
+	c.calls++
+	return c.code[address], nil
+}
+
+func TestClassifier(t *testing.T) {
+	eoa := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	contract := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	delegated := common.HexToAddress("0x01C9E6bdb351AD536236b508092f49eDEe5be0e6")
+	token := common.HexToAddress("0x779877A7B0D9E8603169DdbD7836e478b4624789")
+
+	client := &codeClient{code: map[common.Address][]byte{
+		contract:  {0x60, 0x80, 0x60, 0x40},
+		delegated: types.AddressToDelegation(contract),
+		token:     {0x60, 0x80},
+	}}
+	classifier := NewClassifier(client, 11155111, []common.Address{token})
+
+	tests := []struct {
+		to     common.Address
+		token  common.Address
+		kind   Kind
+		action Action
+	}{
+		{to: eoa, kind: KindEOA, action: ActionAllow},
+		{to: contract, kind: KindContract, action: ActionWarn},
+		{to: delegated, kind: KindDelegatedEOA, action: ActionWarn},
+		{to: token, kind: KindTokenContract, action: ActionBlock},
+		{to: contract, token: contract, kind: KindContract, action: ActionBlock},
+	}
+	for _, tt := range tests {
+		decision, err := classifier.CheckWithdrawal(context.Background(), tt.to, tt.token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision.Kind != tt.kind || decision.Action != tt.action {
+			t.Errorf("CheckWithdrawal(%s) = %s/%s, want %s/%s", tt.to.Hex(), decision.Kind, decision.Action, tt.kind, tt.action)
+		}
+	}
+	if decision, _ := classifier.Classify(context.Background(), delegated); decision.Delegate != contract {
+		t.Errorf("delegate = %s, want %s", decision.Delegate.Hex(), contract.Hex())
+	}
+
+	// eoa/contract/delegated 各查询一次，其余命中缓存
+	if client.calls != 3 {
+		t.Errorf("CodeAt calls = %d, want 3", client.calls)
+	}
+	classifier.Invalidate(eoa)
+	classifier.Classify(context.Background(), eoa)
+	if client.calls != 4 {
+		t.Errorf("CodeAt calls after invalidate = %d, want 4", client.calls)
+	}
+
+	// 合约结果同样会过期
+	classifier.contractTTL = 0
+	classifier.Invalidate(contract)
+	classifier.Classify(context.Background(), contract)
+	classifier.Classify(context.Background(), contract)
+	if client.calls != 6 {
+		t.Errorf("CodeAt calls after contract expiry = %d, want 6", client.calls)
+	}
+}
+
+func TestClassifierCacheBound(t *testing.T) {
+	client := &codeClient{}
+	classifier := NewClassifier(client, 11155111, nil)
+	classifier.cache = lru.NewBasicLRU[common.Address, cacheEntry](2)
+
+	for i := 1; i <= 3; i++ {
+		classifier.Classify(context.Background(), common.BigToAddress(big.NewInt(int64(i))))
+	}
+	if classifier.cache.Len() != 2 {
+		t.Fatalf("cache size = %d, want 2", classifier.cache.Len())
+	}
+	// 最早的地址已被淘汰，需要重新查询
+	classifier.Classify(context.Background(), common.BigToAddress(big.NewInt(1)))
+	if client.calls != 4 {
+		t.Errorf("CodeAt calls = %d, want 4", client.calls)
+	}
+}
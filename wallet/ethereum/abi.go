@@ -0,0 +1,143 @@
+package ethereum
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// 手写的 ABI 编解码工具，只覆盖钱包用到的静态参数、uint256/address 数组与 bytes
+
+// encodeCallData 拼接函数选择器与 ABI 编码后的静态参数
+func encodeCallData(methodID []byte, words ...[]byte) []byte {
+	data := make([]byte, 0, 4+32*len(words))
+	data = append(data, methodID...)
+	for _, word := range words {
+		data = append(data, word...)
+	}
+	return data
+}
+
+// encodeAddress 将地址左补零为 32 字节的 ABI 参数
+func encodeAddress(address common.Address) []byte {
+	return common.LeftPadBytes(address.Bytes(), 32)
+}
+
+// encodeUint256 将金额编码为 32 字节的 ABI 参数，拒绝 nil、负数和溢出
+func encodeUint256(v *big.Int) ([]byte, error) {
+	if v == nil {
+		return nil, errors.New("uint256 parameter is nil")
+	}
+	u, err := bigToUint256(v)
+	if err != nil {
+		return nil, err
+	}
+	b := u.Bytes32()
+	return b[:], nil
+}
+
+// abiArg 调用参数，word 非 nil 时为静态参数，否则 tail 为动态参数的尾部编码
+type abiArg struct {
+	word []byte
+	tail []byte
+}
+
+func staticArg(word []byte) abiArg { return abiArg{word: word} }
+
+func dynamicArg(tail []byte) abiArg { return abiArg{tail: tail} }
+
+// encodeDynamicCallData 按 ABI 头尾结构编码含动态参数的调用数据
+func encodeDynamicCallData(methodID []byte, args ...abiArg) []byte {
+	head := make([]byte, 0, 32*len(args))
+	var tail []byte
+	for _, arg := range args {
+		if arg.word != nil {
+			head = append(head, arg.word...)
+			continue
+		}
+		head = append(head, encodeUint256Word(big.NewInt(int64(32*len(args)+len(tail))))...)
+		tail = append(tail, arg.tail...)
+	}
+	return encodeCallData(methodID, head, tail)
+}
+
+// encodeUint256Word 编码已知非负且不溢出的整数
+func encodeUint256Word(v *big.Int) []byte {
+	return common.LeftPadBytes(v.Bytes(), 32)
+}
+
+// encodeUint256Array 编码 uint256[] 的尾部：长度 + 元素
+func encodeUint256Array(values []*big.Int) ([]byte, error) {
+	data := encodeUint256Word(big.NewInt(int64(len(values))))
+	for _, v := range values {
+		word, err := encodeUint256(v)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, word...)
+	}
+	return data, nil
+}
+
+// encodeBytes 编码 bytes 的尾部：长度 + 右补零到 32 字节整数倍的内容
+func encodeBytes(b []byte) []byte {
+	data := encodeUint256Word(big.NewInt(int64(len(b))))
+	return append(data, common.RightPadBytes(b, (len(b)+31)/32*32)...)
+}
+
+// decodeCallWords 校验函数选择器并按 32 字节切分静态参数
+func decodeCallWords(data []byte, methodID []byte, count int) ([][]byte, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], methodID) {
+		return nil, errors.New("method id mismatch")
+	}
+	if len(data) != 4+32*count {
+		return nil, errors.New("invalid call data length")
+	}
+	words := make([][]byte, count)
+	for i := range words {
+		words[i] = data[4+32*i : 4+32*(i+1)]
+	}
+	return words, nil
+}
+
+// wordToAddress 将 32 字节的 ABI 参数转换为地址，高 12 字节必须为 0
+func wordToAddress(word []byte) (common.Address, error) {
+	for _, b := range word[:12] {
+		if b != 0 {
+			return common.Address{}, errors.New("invalid address parameter")
+		}
+	}
+	return common.BytesToAddress(word[12:]), nil
+}
+
+// decodeUint256ArrayAt 解码 args 中第 index 个参数指向的 uint256[]
+func decodeUint256ArrayAt(args []byte, index int) ([]*big.Int, error) {
+	if len(args) < 32*(index+1) {
+		return nil, errors.New("invalid abi data length")
+	}
+	offset, err := wordToInt(args[32*index : 32*(index+1)])
+	if err != nil || offset+32 > len(args) {
+		return nil, errors.New("invalid array offset")
+	}
+	length, err := wordToInt(args[offset : offset+32])
+	if err != nil || offset+32+32*length > len(args) {
+		return nil, errors.New("invalid array length")
+	}
+
+	values := make([]*big.Int, length)
+	for i := range values {
+		start := offset + 32 + 32*i
+		values[i] = new(big.Int).SetBytes(args[start : start+32])
+	}
+	return values, nil
+}
+
+// wordToInt 将 32 字节的偏移量或长度转换为 int，拒绝超出数据范围的大数
+func wordToInt(word []byte) (int, error) {
+	v := new(big.Int).SetBytes(word)
+	if !v.IsInt64() || v.Int64() > 1<<32 {
+		return 0, errors.New("abi offset too large")
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,102 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"strings"
+
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+var (
+	ErrInvalidAddressFormat = errors.New("invalid address format")
+	ErrInvalidChecksum      = errors.New("invalid address checksum")
+)
+
+const (
+	AddressKindZero       = "zero"
+	AddressKindBurn       = "burn"
+	AddressKindPrecompile = "precompile"
+
+	AddressWarningNoChecksum = "address has no checksum, verify it carefully"
+	AddressWarningReserved   = "address is a zero, burn or precompile address, funds will be lost"
+)
+
+// eip1191ChainIds 使用 EIP-1191 链相关校验和的链（RSK 主网与测试网）
+var eip1191ChainIds = map[uint64]bool{
+	30: true,
+	31: true,
+}
+
+// burnAddresses 常见的销毁地址
+var burnAddresses = map[common.Address]bool{
+	common.HexToAddress("0x000000000000000000000000000000000000dEaD"): true,
+	common.HexToAddress("0xdEAD000000000000000042069420694206942069"): true,
+}
+
+// ChecksumAddress 返回地址的校验和格式，chainId 属于 EIP-1191 链时使用链相关校验和，否则为 EIP-55
+func ChecksumAddress(address common.Address, chainId *big.Int) string {
+	lower := hex.EncodeToString(address.Bytes())
+
+	prefix := ""
+	if chainId != nil && chainId.IsUint64() && eip1191ChainIds[chainId.Uint64()] {
+		prefix = chainId.String() + "0x"
+	}
+	hash := hex.EncodeToString(crypto.Keccak256([]byte(prefix + lower)))
+
+	result := []byte(lower)
+	for i, c := range result {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			result[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(result)
+}
+
+// ValidateAddress 校验用户输入的提现地址
+// 格式错误或大小写混合但校验和不符时返回错误；全小写/全大写与保留地址以警告形式返回
+func ValidateAddress(address string, chainId *big.Int) (*WalletTypes.AddressValidation, error) {
+	address = strings.TrimSpace(address)
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return nil, ErrInvalidAddressFormat
+	}
+	body := address[2:]
+	if _, err := hex.DecodeString(body); err != nil {
+		return nil, ErrInvalidAddressFormat
+	}
+
+	parsed := common.HexToAddress(address)
+	checksummed := ChecksumAddress(parsed, chainId)
+	result := &WalletTypes.AddressValidation{Address: checksummed}
+
+	if body == strings.ToLower(body) || body == strings.ToUpper(body) {
+		result.Warnings = append(result.Warnings, AddressWarningNoChecksum)
+	} else if address != checksummed {
+		return nil, ErrInvalidChecksum
+	}
+
+	if kind := reservedAddressKind(parsed); kind != "" {
+		result.Kind = kind
+		result.Warnings = append(result.Warnings, AddressWarningReserved)
+	}
+	return result, nil
+}
+
+// reservedAddressKind 识别零地址、销毁地址与预编译合约地址
+func reservedAddressKind(address common.Address) string {
+	if address == (common.Address{}) {
+		return AddressKindZero
+	}
+	if burnAddresses[address] {
+		return AddressKindBurn
+	}
+	// 0x01-0x11 为主网预编译（含 Prague BLS12-381），0x100 为 L2 上的 P256VERIFY
+	n := new(big.Int).SetBytes(address.Bytes())
+	if n.Cmp(big.NewInt(0x11)) <= 0 || n.Cmp(big.NewInt(0x100)) == 0 {
+		return AddressKindPrecompile
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestValidateAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		chainId  *big.Int
+		want     string
+		kind     string
+		warnings int
+		wantErr  error
+	}{
+		{name: "eip55", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", want: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{name: "lowercase", address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", want: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", warnings: 1},
+		{name: "uppercase", address: "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", want: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", warnings: 1},
+		{name: "bad checksum", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", wantErr: ErrInvalidChecksum},
+		{name: "eip1191 rsk", address: "0x5aaEB6053f3e94c9b9a09f33669435E7ef1bEAeD", chainId: big.NewInt(30), want: "0x5aaEB6053f3e94c9b9a09f33669435E7ef1bEAeD"},
+		{name: "eip55 on rsk", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", chainId: big.NewInt(30), wantErr: ErrInvalidChecksum},
+		{name: "no prefix", address: "5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed00", wantErr: ErrInvalidAddressFormat},
+		{name: "short", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", wantErr: ErrInvalidAddressFormat},
+		{name: "not hex", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeg", wantErr: ErrInvalidAddressFormat},
+		{name: "zero", address: "0x0000000000000000000000000000000000000000", want: "0x0000000000000000000000000000000000000000", kind: AddressKindZero, warnings: 2},
+		{name: "burn", address: "0x000000000000000000000000000000000000dEaD", want: "0x000000000000000000000000000000000000dEaD", kind: AddressKindBurn, warnings: 1},
+		{name: "precompile", address: "0x0000000000000000000000000000000000000001", want: "0x0000000000000000000000000000000000000001", kind: AddressKindPrecompile, warnings: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateAddress(tt.address, tt.chainId)
+			if err != tt.wantErr {
+				t.Fatalf("ValidateAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Address != tt.want || got.Kind != tt.kind || len(got.Warnings) != tt.warnings {
+				t.Errorf("ValidateAddress() = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package ethereum
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"math/big"
+)
+
+// 每个 field element 只写入 31 字节数据，首字节保持为 0，保证数值小于 BLS12-381 的模数
+const blobUsableBytesPerFieldElement = params.BlobTxBytesPerFieldElement - 1
+
+// BlobDataCapacity 单个 blob 可承载的原始数据字节数
+const BlobDataCapacity = params.BlobTxFieldElementsPerBlob * blobUsableBytesPerFieldElement
+
+// MaxBlobsPerTransaction 单笔交易最多可携带的 blob 数量（Cancun 区块上限为 6）
+// 不从 params 推导：区块 blob 上限随硬分叉变化，go-ethereum 后续版本会将其移入分叉配置
+const MaxBlobsPerTransaction = 6
+
+// EncodeBlobs 将任意数据切分并编码为 blob 列表
+func EncodeBlobs(data []byte) ([]kzg4844.Blob, error) {
+	if len(data) == 0 {
+		return nil, errors.New("blob data is empty")
+	}
+
+	count := (len(data) + BlobDataCapacity - 1) / BlobDataCapacity
+	if count > MaxBlobsPerTransaction {
+		return nil, errors.New("blob data exceeds max blobs per transaction")
+	}
+
+	blobs := make([]kzg4844.Blob, count)
+	for i := range blobs {
+		chunk := data[i*BlobDataCapacity:]
+		if len(chunk) > BlobDataCapacity {
+			chunk = chunk[:BlobDataCapacity]
+		}
+		// 逐个 field element 写入，跳过每个元素的首字节
+		for j := 0; len(chunk) > 0; j++ {
+			n := copy(blobs[i][j*params.BlobTxBytesPerFieldElement+1:(j+1)*params.BlobTxBytesPerFieldElement], chunk)
+			chunk = chunk[n:]
+		}
+	}
+	return blobs, nil
+}
+
+// BuildBlobSidecar 在本地计算 blob 的 KZG 承诺与证明，生成交易 sidecar
+func BuildBlobSidecar(blobs []kzg4844.Blob) (*types.BlobTxSidecar, error) {
+	if len(blobs) == 0 {
+		return nil, errors.New("no blobs provided")
+	}
+	if len(blobs) > MaxBlobsPerTransaction {
+		return nil, errors.New("too many blobs in transaction")
+	}
+
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       blobs,
+		Commitments: make([]kzg4844.Commitment, len(blobs)),
+		Proofs:      make([]kzg4844.Proof, len(blobs)),
+	}
+	for i := range blobs {
+		commitment, err := kzg4844.BlobToCommitment(&blobs[i])
+		if err != nil {
+			return nil, err
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blobs[i], commitment)
+		if err != nil {
+			return nil, err
+		}
+		sidecar.Commitments[i] = commitment
+		sidecar.Proofs[i] = proof
+	}
+	return sidecar, nil
+}
+
+// SuggestBlobFeeCap 根据 eth_blobBaseFee 返回的 blob 基础费给出 maxFeePerBlobGas
+// 取两倍基础费，可以容忍连续数个满 blob 区块带来的涨价
+func SuggestBlobFeeCap(blobBaseFee *big.Int) *big.Int {
+	if blobBaseFee == nil || blobBaseFee.Sign() <= 0 {
+		return big.NewInt(params.BlobTxMinBlobGasprice)
+	}
+	return new(big.Int).Mul(blobBaseFee, big.NewInt(2))
+}
+
+// BuildBlobTx 在 EIP1559 交易参数的基础上构建携带 sidecar 的 BlobTx
+func BuildBlobTx(feeTx *types.DynamicFeeTx, blobFeeCap *big.Int, sidecar *types.BlobTxSidecar) (*types.BlobTx, error) {
+	if feeTx.To == nil {
+		return nil, errors.New("blob transaction cannot be contract creation")
+	}
+	if sidecar == nil || len(sidecar.Blobs) == 0 {
+		return nil, errors.New("blob transaction requires sidecar")
+	}
+	if len(sidecar.Blobs) != len(sidecar.Commitments) || len(sidecar.Blobs) != len(sidecar.Proofs) {
+		return nil, errors.New("sidecar blobs, commitments and proofs length mismatch")
+	}
+
+	blobTx := &types.BlobTx{
+		Nonce:      feeTx.Nonce,
+		Gas:        feeTx.Gas,
+		To:         *feeTx.To,
+		Data:       common.CopyBytes(feeTx.Data),
+		AccessList: feeTx.AccessList,
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	}
+
+	err := setUint256Fields([]uint256Field{
+		{"chain id", &blobTx.ChainID, feeTx.ChainID},
+		{"gas tip cap", &blobTx.GasTipCap, feeTx.GasTipCap},
+		{"gas fee cap", &blobTx.GasFeeCap, feeTx.GasFeeCap},
+		{"value", &blobTx.Value, feeTx.Value},
+		{"blob fee cap", &blobTx.BlobFeeCap, blobFeeCap},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blobTx, nil
+}
+
+// OfflineSignBlobTx 使用 Cancun 签名器离线签名 blob 交易，返回包含 sidecar 的网络编码
+func OfflineSignBlobTx(blobTx *types.BlobTx, privateKeyStr string, chainId *big.Int) (string, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	if err != nil {
+		return "", err
+	}
+
+	signedTx, err := types.SignTx(types.NewTx(blobTx), types.NewCancunSigner(chainId), privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	signedTxBytes, err := signedTx.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return common.Bytes2Hex(signedTxBytes), nil
+}
@@ -0,0 +1,114 @@
+package ethereum
+
+import (
+	"bytes"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"math/big"
+	"testing"
+)
+
+func TestEncodeBlobs(t *testing.T) {
+	data := bytes.Repeat([]byte{0xff}, BlobDataCapacity+1)
+	blobs, err := EncodeBlobs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 2 {
+		t.Fatalf("blob count = %d, want 2", len(blobs))
+	}
+	// 每个 field element 的首字节必须为 0
+	for i := 0; i < len(blobs[0]); i += 32 {
+		if blobs[0][i] != 0 {
+			t.Fatalf("field element %d high byte not zero", i/32)
+		}
+	}
+	if blobs[1][1] != 0xff || blobs[1][2] != 0 {
+		t.Error("second blob content mismatch")
+	}
+}
+
+func TestOfflineSignBlobTx(t *testing.T) {
+	privateKeyStr := "17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4"
+	fromAddress := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	toAddress := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	chainID := big.NewInt(11155111)
+
+	blobs, err := EncodeBlobs([]byte("EthCEXWallet blob data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sidecar, err := BuildBlobSidecar(blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kzg4844.VerifyBlobProof(&sidecar.Blobs[0], sidecar.Commitments[0], sidecar.Proofs[0]); err != nil {
+		t.Fatalf("blob proof verify failed: %v", err)
+	}
+
+	blobTx, err := BuildBlobTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     8,
+		GasTipCap: big.NewInt(1000000000),
+		GasFeeCap: big.NewInt(30000000000),
+		Gas:       21000,
+		To:        &toAddress,
+		Value:     big.NewInt(0),
+	}, SuggestBlobFeeCap(big.NewInt(5)), sidecar)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txHex, err := OfflineSignBlobTx(blobTx, privateKeyStr, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(common.Hex2Bytes(txHex)); err != nil {
+		t.Fatal(err)
+	}
+	if signedTx.Type() != types.BlobTxType {
+		t.Fatalf("tx type = %d, want %d", signedTx.Type(), types.BlobTxType)
+	}
+	if signedTx.BlobTxSidecar() == nil {
+		t.Fatal("sidecar missing from network encoding")
+	}
+	if signedTx.BlobGasFeeCap().Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("blob fee cap = %v, want 10", signedTx.BlobGasFeeCap())
+	}
+	sender, err := types.Sender(types.NewCancunSigner(chainID), signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != fromAddress {
+		t.Errorf("sender = %v, want %v", sender, fromAddress)
+	}
+}
+
+func TestBuildBlobTxContractCreation(t *testing.T) {
+	_, err := BuildBlobTx(&types.DynamicFeeTx{ChainID: big.NewInt(1)}, big.NewInt(1), &types.BlobTxSidecar{})
+	if err == nil {
+		t.Error("expected error for blob tx without recipient")
+	}
+}
+
+func TestBuildBlobTxNegativeValue(t *testing.T) {
+	to := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	sidecar := &types.BlobTxSidecar{Blobs: make([]kzg4844.Blob, 1), Commitments: make([]kzg4844.Commitment, 1), Proofs: make([]kzg4844.Proof, 1)}
+	for _, tc := range []struct {
+		name       string
+		feeTx      types.DynamicFeeTx
+		blobFeeCap *big.Int
+	}{
+		{"value", types.DynamicFeeTx{ChainID: big.NewInt(1), To: &to, Value: big.NewInt(-1)}, big.NewInt(1)},
+		{"gas tip cap", types.DynamicFeeTx{ChainID: big.NewInt(1), To: &to, GasTipCap: big.NewInt(-1)}, big.NewInt(1)},
+		{"gas fee cap", types.DynamicFeeTx{ChainID: big.NewInt(1), To: &to, GasFeeCap: big.NewInt(-1)}, big.NewInt(1)},
+		{"blob fee cap", types.DynamicFeeTx{ChainID: big.NewInt(1), To: &to}, big.NewInt(-1)},
+	} {
+		if _, err := BuildBlobTx(&tc.feeTx, tc.blobFeeCap, sidecar); err == nil {
+			t.Errorf("%s: expected error for negative field", tc.name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package ethereum
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+var (
+	erc1155SafeTransferFromMethodID      = crypto.Keccak256([]byte("safeTransferFrom(address,address,uint256,uint256,bytes)"))[:4]
+	erc1155SafeBatchTransferFromMethodID = crypto.Keccak256([]byte("safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)"))[:4]
+	erc1155BalanceOfBatchMethodID        = crypto.Keccak256([]byte("balanceOfBatch(address[],uint256[])"))[:4]
+
+	// Erc1155TransferSingleTopic TransferSingle 事件签名，用于过滤充值日志
+	Erc1155TransferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	// Erc1155TransferBatchTopic TransferBatch 事件签名，用于过滤充值日志
+	Erc1155TransferBatchTopic = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// Erc1155Transfer 从 TransferSingle/TransferBatch 事件中解析出的转账，单笔转账 Ids/Values 长度为 1
+type Erc1155Transfer struct {
+	Token       common.Address
+	Operator    common.Address
+	From        common.Address
+	To          common.Address
+	Ids         []*big.Int
+	Values      []*big.Int
+	TxHash      common.Hash
+	BlockNumber uint64
+	LogIndex    uint
+}
+
+// BuildErc1155Data 构建 ERC-1155 safeTransferFrom(address,address,uint256,uint256,bytes) 交易数据
+func BuildErc1155Data(fromAddress, toAddress common.Address, tokenID, amount *big.Int, data []byte) ([]byte, error) {
+	dataTokenID, err := encodeUint256(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	dataAmount, err := encodeUint256(amount)
+	if err != nil {
+		return nil, err
+	}
+	return encodeDynamicCallData(erc1155SafeTransferFromMethodID,
+		staticArg(encodeAddress(fromAddress)),
+		staticArg(encodeAddress(toAddress)),
+		staticArg(dataTokenID),
+		staticArg(dataAmount),
+		dynamicArg(encodeBytes(data)),
+	), nil
+}
+
+// BuildErc1155BatchData 构建 ERC-1155 safeBatchTransferFrom(address,address,uint256[],uint256[],bytes) 交易数据
+func BuildErc1155BatchData(fromAddress, toAddress common.Address, tokenIDs, amounts []*big.Int, data []byte) ([]byte, error) {
+	if len(tokenIDs) == 0 || len(tokenIDs) != len(amounts) {
+		return nil, errors.New("token ids and amounts length mismatch")
+	}
+	dataTokenIDs, err := encodeUint256Array(tokenIDs)
+	if err != nil {
+		return nil, err
+	}
+	dataAmounts, err := encodeUint256Array(amounts)
+	if err != nil {
+		return nil, err
+	}
+	return encodeDynamicCallData(erc1155SafeBatchTransferFromMethodID,
+		staticArg(encodeAddress(fromAddress)),
+		staticArg(encodeAddress(toAddress)),
+		dynamicArg(dataTokenIDs),
+		dynamicArg(dataAmounts),
+		dynamicArg(encodeBytes(data)),
+	), nil
+}
+
+// BuildErc1155BalanceOfBatchData 构建 balanceOfBatch(address[],uint256[]) 查询数据，用于 eth_call
+func BuildErc1155BalanceOfBatchData(accounts []common.Address, tokenIDs []*big.Int) ([]byte, error) {
+	if len(accounts) == 0 || len(accounts) != len(tokenIDs) {
+		return nil, errors.New("accounts and token ids length mismatch")
+	}
+	dataAccounts := encodeUint256Word(big.NewInt(int64(len(accounts))))
+	for _, account := range accounts {
+		dataAccounts = append(dataAccounts, encodeAddress(account)...)
+	}
+	dataTokenIDs, err := encodeUint256Array(tokenIDs)
+	if err != nil {
+		return nil, err
+	}
+	return encodeDynamicCallData(erc1155BalanceOfBatchMethodID, dynamicArg(dataAccounts), dynamicArg(dataTokenIDs)), nil
+}
+
+// DecodeErc1155BalanceOfBatchResult 解码 balanceOfBatch 的返回值
+func DecodeErc1155BalanceOfBatchResult(result []byte) ([]*big.Int, error) {
+	return decodeUint256ArrayAt(result, 0)
+}
+
+// DecodeErc1155Data 解码 safeTransferFrom 调用数据
+func DecodeErc1155Data(data []byte) (common.Address, common.Address, *big.Int, *big.Int, error) {
+	if len(data) < 4+32*5 || !bytes.Equal(data[:4], erc1155SafeTransferFromMethodID) {
+		return common.Address{}, common.Address{}, nil, nil, errors.New("method id mismatch")
+	}
+	args := data[4:]
+	from, err := wordToAddress(args[:32])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, nil, err
+	}
+	to, err := wordToAddress(args[32:64])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, nil, err
+	}
+	return from, to, new(big.Int).SetBytes(args[64:96]), new(big.Int).SetBytes(args[96:128]), nil
+}
+
+// DecodeErc1155BatchData 解码 safeBatchTransferFrom 调用数据
+func DecodeErc1155BatchData(data []byte) (common.Address, common.Address, []*big.Int, []*big.Int, error) {
+	if len(data) < 4+32*5 || !bytes.Equal(data[:4], erc1155SafeBatchTransferFromMethodID) {
+		return common.Address{}, common.Address{}, nil, nil, errors.New("method id mismatch")
+	}
+	args := data[4:]
+	from, err := wordToAddress(args[:32])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, nil, err
+	}
+	to, err := wordToAddress(args[32:64])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, nil, err
+	}
+	ids, err := decodeUint256ArrayAt(args, 2)
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, nil, err
+	}
+	values, err := decodeUint256ArrayAt(args, 3)
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, nil, err
+	}
+	if len(ids) != len(values) {
+		return common.Address{}, common.Address{}, nil, nil, errors.New("token ids and amounts length mismatch")
+	}
+	return from, to, ids, values, nil
+}
+
+// DecodeErc1155TransferLog 解码 TransferSingle/TransferBatch 事件日志
+func DecodeErc1155TransferLog(log types.Log) (*Erc1155Transfer, error) {
+	if len(log.Topics) != 4 {
+		return nil, errors.New("not an erc1155 transfer log")
+	}
+
+	transfer := &Erc1155Transfer{
+		Token:       log.Address,
+		Operator:    common.BytesToAddress(log.Topics[1].Bytes()),
+		From:        common.BytesToAddress(log.Topics[2].Bytes()),
+		To:          common.BytesToAddress(log.Topics[3].Bytes()),
+		TxHash:      log.TxHash,
+		BlockNumber: log.BlockNumber,
+		LogIndex:    log.Index,
+	}
+
+	switch log.Topics[0] {
+	case Erc1155TransferSingleTopic:
+		if len(log.Data) != 64 {
+			return nil, errors.New("invalid TransferSingle data length")
+		}
+		transfer.Ids = []*big.Int{new(big.Int).SetBytes(log.Data[:32])}
+		transfer.Values = []*big.Int{new(big.Int).SetBytes(log.Data[32:])}
+	case Erc1155TransferBatchTopic:
+		ids, err := decodeUint256ArrayAt(log.Data, 0)
+		if err != nil {
+			return nil, err
+		}
+		values, err := decodeUint256ArrayAt(log.Data, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) != len(values) {
+			return nil, errors.New("TransferBatch ids and values length mismatch")
+		}
+		transfer.Ids = ids
+		transfer.Values = values
+	default:
+		return nil, errors.New("not an erc1155 transfer log")
+	}
+	return transfer, nil
+}
@@ -0,0 +1,118 @@
+package ethereum
+
+import (
+	"bytes"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const erc1155ABI = `[
+{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}]},
+{"type":"function","name":"safeBatchTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"ids","type":"uint256[]"},{"name":"values","type":"uint256[]"},{"name":"data","type":"bytes"}]},
+{"type":"function","name":"balanceOfBatch","inputs":[{"name":"accounts","type":"address[]"},{"name":"ids","type":"uint256[]"}],"outputs":[{"name":"","type":"uint256[]"}]},
+{"type":"event","name":"TransferBatch","inputs":[{"name":"operator","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"ids","type":"uint256[]"},{"name":"values","type":"uint256[]"}]}
+]`
+
+func TestErc1155CallData(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(erc1155ABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	to := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	ids := []*big.Int{big.NewInt(1), big.NewInt(7)}
+	amounts := []*big.Int{big.NewInt(10), big.NewInt(3)}
+	extra := []byte("deposit memo longer than one abi word!")
+
+	data, err := BuildErc1155Data(from, to, ids[1], amounts[1], extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := parsed.Pack("safeTransferFrom", from, to, ids[1], amounts[1], extra)
+	if !bytes.Equal(data, want) {
+		t.Fatalf("safeTransferFrom data = %x, want %x", data, want)
+	}
+	call, err := DecodeContractCall(data)
+	if err != nil || call == nil || call.Standard != "ERC1155" || call.TokenId != "7" || call.Amount != "3" {
+		t.Errorf("decoded call = %+v %v", call, err)
+	}
+
+	data, err = BuildErc1155BatchData(from, to, ids, amounts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ = parsed.Pack("safeBatchTransferFrom", from, to, ids, amounts, []byte{})
+	if !bytes.Equal(data, want) {
+		t.Fatalf("safeBatchTransferFrom data = %x, want %x", data, want)
+	}
+	gotFrom, gotTo, gotIds, gotAmounts, err := DecodeErc1155BatchData(data)
+	if err != nil || gotFrom != from || gotTo != to || len(gotIds) != 2 || gotIds[1].Cmp(ids[1]) != 0 || gotAmounts[0].Cmp(amounts[0]) != 0 {
+		t.Errorf("decoded batch = %v %v %v %v %v", gotFrom, gotTo, gotIds, gotAmounts, err)
+	}
+
+	data, err = BuildErc1155BalanceOfBatchData([]common.Address{from, to}, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ = parsed.Pack("balanceOfBatch", []common.Address{from, to}, ids)
+	if !bytes.Equal(data, want) {
+		t.Fatalf("balanceOfBatch data = %x, want %x", data, want)
+	}
+	result, _ := parsed.Methods["balanceOfBatch"].Outputs.Pack(amounts)
+	balances, err := DecodeErc1155BalanceOfBatchResult(result)
+	if err != nil || len(balances) != 2 || balances[0].Cmp(amounts[0]) != 0 || balances[1].Cmp(amounts[1]) != 0 {
+		t.Errorf("balances = %v %v", balances, err)
+	}
+}
+
+func TestDecodeErc1155TransferLog(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(erc1155ABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := common.HexToAddress("0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238")
+	operator := common.HexToAddress("0x01C9E6bdb351AD536236b508092f49eDEe5be0e6")
+	from := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	to := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	topics := []common.Hash{
+		{},
+		common.BytesToHash(operator.Bytes()),
+		common.BytesToHash(from.Bytes()),
+		common.BytesToHash(to.Bytes()),
+	}
+
+	topics[0] = Erc1155TransferSingleTopic
+	single, err := DecodeErc1155TransferLog(types.Log{
+		Address: token,
+		Topics:  topics,
+		Data:    append(encodeUint256Word(big.NewInt(5)), encodeUint256Word(big.NewInt(2))...),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single.Token != token || single.Operator != operator || single.From != from || single.To != to || single.Ids[0].Int64() != 5 || single.Values[0].Int64() != 2 {
+		t.Errorf("single transfer = %+v", single)
+	}
+
+	if parsed.Events["TransferBatch"].ID != Erc1155TransferBatchTopic {
+		t.Fatal("TransferBatch topic mismatch")
+	}
+	batchData, _ := parsed.Events["TransferBatch"].Inputs.NonIndexed().Pack([]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(30), big.NewInt(40)})
+	topics[0] = Erc1155TransferBatchTopic
+	batch, err := DecodeErc1155TransferLog(types.Log{Address: token, Topics: topics, Data: batchData})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Ids) != 2 || batch.Ids[1].Int64() != 2 || batch.Values[1].Int64() != 40 {
+		t.Errorf("batch transfer = %+v", batch)
+	}
+
+	topics[0] = common.Hash{}
+	if _, err := DecodeErc1155TransferLog(types.Log{Topics: topics}); err == nil {
+		t.Error("expected error for unknown event")
+	}
+}
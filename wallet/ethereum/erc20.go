@@ -0,0 +1,253 @@
+package ethereum
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math"
+	"math/big"
+)
+
+var (
+	erc20TransferFromMethodID      = crypto.Keccak256([]byte("transferFrom(address,address,uint256)"))[:4]
+	erc20IncreaseAllowanceMethodID = crypto.Keccak256([]byte("increaseAllowance(address,uint256)"))[:4]
+	erc20DecreaseAllowanceMethodID = crypto.Keccak256([]byte("decreaseAllowance(address,uint256)"))[:4]
+	erc20PermitMethodID            = crypto.Keccak256([]byte("permit(address,address,uint256,uint256,uint8,bytes32,bytes32)"))[:4]
+	erc20BalanceOfMethodID         = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+	eip712DomainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	erc20PermitTypeHash  = crypto.Keccak256Hash([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"))
+)
+
+// PermitDomain EIP-2612 代币合约的 EIP-712 域信息
+type PermitDomain struct {
+	Name              string // 代币合约的 name()
+	Version           string // 一般为 "1"，以合约 eip712Domain() 为准
+	ChainId           *big.Int
+	VerifyingContract common.Address // 代币合约地址
+}
+
+// Erc20Permit EIP-2612 permit 授权消息
+type Erc20Permit struct {
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Nonce    *big.Int // 代币合约 nonces(owner)，不会出现在 permit 调用数据中
+	Deadline *big.Int
+}
+
+// BuildErc20ApproveData 构建 ERC-20 approve(address,uint256) 交易数据
+func BuildErc20ApproveData(spender common.Address, amount *big.Int) ([]byte, error) {
+	return buildAddressAmountData(erc20ApproveMethodID, spender, amount)
+}
+
+// BuildErc20TransferFromData 构建 ERC-20 transferFrom(address,address,uint256) 交易数据
+func BuildErc20TransferFromData(fromAddress, toAddress common.Address, amount *big.Int) ([]byte, error) {
+	dataAmount, err := encodeUint256(amount)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCallData(erc20TransferFromMethodID, encodeAddress(fromAddress), encodeAddress(toAddress), dataAmount), nil
+}
+
+// BuildErc20IncreaseAllowanceData 构建 increaseAllowance(address,uint256) 交易数据
+func BuildErc20IncreaseAllowanceData(spender common.Address, addedValue *big.Int) ([]byte, error) {
+	return buildAddressAmountData(erc20IncreaseAllowanceMethodID, spender, addedValue)
+}
+
+// BuildErc20DecreaseAllowanceData 构建 decreaseAllowance(address,uint256) 交易数据
+func BuildErc20DecreaseAllowanceData(spender common.Address, subtractedValue *big.Int) ([]byte, error) {
+	return buildAddressAmountData(erc20DecreaseAllowanceMethodID, spender, subtractedValue)
+}
+
+// Erc20PermitHash 计算 EIP-2612 permit 消息的 EIP-712 签名哈希
+func Erc20PermitHash(domain PermitDomain, permit Erc20Permit) (common.Hash, error) {
+	if domain.ChainId == nil {
+		return common.Hash{}, errors.New("permit domain chain id is required")
+	}
+	chainId, err := encodeUint256(domain.ChainId)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	value, err := encodeUint256(permit.Value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	nonce, err := encodeUint256(permit.Nonce)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	deadline, err := encodeUint256(permit.Deadline)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	domainSeparator := crypto.Keccak256(
+		eip712DomainTypeHash.Bytes(),
+		crypto.Keccak256([]byte(domain.Name)),
+		crypto.Keccak256([]byte(domain.Version)),
+		chainId,
+		encodeAddress(domain.VerifyingContract),
+	)
+	structHash := crypto.Keccak256(
+		erc20PermitTypeHash.Bytes(),
+		encodeAddress(permit.Owner),
+		encodeAddress(permit.Spender),
+		value,
+		nonce,
+		deadline,
+	)
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator, structHash), nil
+}
+
+// SignErc20Permit 使用 owner 私钥签名 permit 消息，返回 65 字节 r||s||v 签名（v 为 27/28）
+func SignErc20Permit(privateKeyStr string, domain PermitDomain, permit Erc20Permit) ([]byte, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	if err != nil {
+		return nil, err
+	}
+	if crypto.PubkeyToAddress(privateKey.PublicKey) != permit.Owner {
+		return nil, errors.New("private key does not match permit owner")
+	}
+
+	hash, err := Erc20PermitHash(domain, permit)
+	if err != nil {
+		return nil, err
+	}
+	return signHashWithKey(privateKeyStr, hash.Bytes())
+}
+
+// RecoverErc20PermitSigner 从 permit 签名中恢复签名地址
+func RecoverErc20PermitSigner(domain PermitDomain, permit Erc20Permit, signature []byte) (common.Address, error) {
+	hash, err := Erc20PermitHash(domain, permit)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverSigner(hash.Bytes(), signature)
+}
+
+// BuildErc20PermitData 构建 permit(address,address,uint256,uint256,uint8,bytes32,bytes32) 交易数据
+func BuildErc20PermitData(permit Erc20Permit, signature []byte) ([]byte, error) {
+	if len(signature) != crypto.SignatureLength {
+		return nil, errors.New("invalid signature length")
+	}
+	value, err := encodeUint256(permit.Value)
+	if err != nil {
+		return nil, err
+	}
+	deadline, err := encodeUint256(permit.Deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	v := signature[crypto.RecoveryIDOffset]
+	if v < 27 {
+		v += 27
+	}
+	return encodeCallData(erc20PermitMethodID,
+		encodeAddress(permit.Owner),
+		encodeAddress(permit.Spender),
+		value,
+		deadline,
+		common.LeftPadBytes([]byte{v}, 32),
+		signature[:32],
+		signature[32:64],
+	), nil
+}
+
+// BuildErc20BalanceOfData 构建 balanceOf(address) 查询数据，用于 eth_call
+func BuildErc20BalanceOfData(owner common.Address) []byte {
+	return encodeCallData(erc20BalanceOfMethodID, encodeAddress(owner))
+}
+
+// DecodeErc20BalanceOfResult 解码 balanceOf 的返回值
+func DecodeErc20BalanceOfResult(result []byte) (*big.Int, error) {
+	if len(result) != 32 {
+		return nil, errors.New("invalid balanceOf result length")
+	}
+	return new(big.Int).SetBytes(result), nil
+}
+
+// DecodeErc20ApproveData 解码 approve 调用数据
+func DecodeErc20ApproveData(data []byte) (common.Address, *big.Int, error) {
+	return decodeAddressAmountData(data, erc20ApproveMethodID)
+}
+
+// DecodeErc20TransferFromData 解码 transferFrom 调用数据
+func DecodeErc20TransferFromData(data []byte) (common.Address, common.Address, *big.Int, error) {
+	words, err := decodeCallWords(data, erc20TransferFromMethodID, 3)
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, err
+	}
+	from, err := wordToAddress(words[0])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, err
+	}
+	to, err := wordToAddress(words[1])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, err
+	}
+	return from, to, new(big.Int).SetBytes(words[2]), nil
+}
+
+// DecodeErc20IncreaseAllowanceData 解码 increaseAllowance 调用数据
+func DecodeErc20IncreaseAllowanceData(data []byte) (common.Address, *big.Int, error) {
+	return decodeAddressAmountData(data, erc20IncreaseAllowanceMethodID)
+}
+
+// DecodeErc20DecreaseAllowanceData 解码 decreaseAllowance 调用数据
+func DecodeErc20DecreaseAllowanceData(data []byte) (common.Address, *big.Int, error) {
+	return decodeAddressAmountData(data, erc20DecreaseAllowanceMethodID)
+}
+
+// DecodeErc20PermitData 解码 permit 调用数据，返回的 permit 不含 Nonce
+func DecodeErc20PermitData(data []byte) (*Erc20Permit, []byte, error) {
+	words, err := decodeCallWords(data, erc20PermitMethodID, 7)
+	if err != nil {
+		return nil, nil, err
+	}
+	owner, err := wordToAddress(words[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	spender, err := wordToAddress(words[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	v := new(big.Int).SetBytes(words[4])
+	if !v.IsUint64() || v.Uint64() > math.MaxUint8 {
+		return nil, nil, errors.New("invalid permit signature v")
+	}
+
+	signature := make([]byte, 0, crypto.SignatureLength)
+	signature = append(signature, words[5]...)
+	signature = append(signature, words[6]...)
+	signature = append(signature, byte(v.Uint64()))
+
+	return &Erc20Permit{
+		Owner:    owner,
+		Spender:  spender,
+		Value:    new(big.Int).SetBytes(words[2]),
+		Deadline: new(big.Int).SetBytes(words[3]),
+	}, signature, nil
+}
+
+func buildAddressAmountData(methodID []byte, address common.Address, amount *big.Int) ([]byte, error) {
+	dataAmount, err := encodeUint256(amount)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCallData(methodID, encodeAddress(address), dataAmount), nil
+}
+
+func decodeAddressAmountData(data []byte, methodID []byte) (common.Address, *big.Int, error) {
+	words, err := decodeCallWords(data, methodID, 2)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	address, err := wordToAddress(words[0])
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return address, new(big.Int).SetBytes(words[1]), nil
+}
@@ -0,0 +1,140 @@
+package ethereum
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"math/big"
+	"testing"
+)
+
+func TestErc20CallDataRoundTrip(t *testing.T) {
+	from := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	to := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	amount := big.NewInt(1000000)
+
+	data, err := BuildErc20ApproveData(to, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spender, got, err := DecodeErc20ApproveData(data)
+	if err != nil || spender != to || got.Cmp(amount) != 0 {
+		t.Errorf("approve round trip = %v %v %v", spender, got, err)
+	}
+
+	data, err = BuildErc20TransferFromData(from, to, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotFrom, gotTo, got, err := DecodeErc20TransferFromData(data)
+	if err != nil || gotFrom != from || gotTo != to || got.Cmp(amount) != 0 {
+		t.Errorf("transferFrom round trip = %v %v %v %v", gotFrom, gotTo, got, err)
+	}
+
+	data, err = BuildErc20IncreaseAllowanceData(to, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := DecodeErc20DecreaseAllowanceData(data); err == nil {
+		t.Error("decreaseAllowance decoder accepted increaseAllowance data")
+	}
+	call, err := DecodeContractCall(data)
+	if err != nil || call == nil || call.Method != "increaseAllowance" {
+		t.Errorf("increaseAllowance call = %+v %v", call, err)
+	}
+
+	if _, err := BuildErc20ApproveData(to, big.NewInt(-1)); err == nil {
+		t.Error("expected error for negative amount")
+	}
+}
+
+func TestErc20Permit(t *testing.T) {
+	privateKeyStr := "17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4"
+	owner := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	sweeper := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	domain := PermitDomain{
+		Name:              "USD Coin",
+		Version:           "2",
+		ChainId:           big.NewInt(11155111),
+		VerifyingContract: common.HexToAddress("0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238"),
+	}
+	permit := Erc20Permit{
+		Owner:    owner,
+		Spender:  sweeper,
+		Value:    big.NewInt(5000000),
+		Nonce:    big.NewInt(3),
+		Deadline: big.NewInt(1893456000),
+	}
+
+	// 与 go-ethereum 的通用 EIP-712 实现对比
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Permit": {
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain: apitypes.TypedDataDomain{
+			Name:              domain.Name,
+			Version:           domain.Version,
+			ChainId:           (*math.HexOrDecimal256)(domain.ChainId),
+			VerifyingContract: domain.VerifyingContract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"owner":    owner.Hex(),
+			"spender":  sweeper.Hex(),
+			"value":    "5000000",
+			"nonce":    "3",
+			"deadline": "1893456000",
+		},
+	}
+	want, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := Erc20PermitHash(domain, permit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != common.BytesToHash(want) {
+		t.Fatalf("permit hash = %x, want %x", hash, want)
+	}
+
+	signature, err := SignErc20Permit(privateKeyStr, domain, permit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := RecoverErc20PermitSigner(domain, permit, signature)
+	if err != nil || signer != owner {
+		t.Errorf("permit signer = %v %v, want %v", signer, err, owner)
+	}
+
+	data, err := BuildErc20PermitData(permit, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, decodedSig, err := DecodeErc20PermitData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Owner != owner || decoded.Spender != sweeper || decoded.Value.Cmp(permit.Value) != 0 || decoded.Deadline.Cmp(permit.Deadline) != 0 {
+		t.Errorf("decoded permit = %+v", decoded)
+	}
+	if common.Bytes2Hex(decodedSig) != common.Bytes2Hex(signature) {
+		t.Errorf("decoded signature = %x, want %x", decodedSig, signature)
+	}
+
+	if _, err := SignErc20Permit(privateKeyStr, domain, Erc20Permit{Owner: sweeper, Spender: owner, Value: big.NewInt(1), Nonce: big.NewInt(0), Deadline: big.NewInt(1)}); err == nil {
+		t.Error("expected owner mismatch error")
+	}
+}
@@ -0,0 +1,66 @@
+package ethereum
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+var (
+	erc721OwnerOfMethodID = crypto.Keccak256([]byte("ownerOf(uint256)"))[:4]
+
+	// TransferTopic ERC-20 与 ERC-721 共用的 Transfer(address,address,uint256) 事件签名
+	TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// Erc721Transfer 从 ERC-721 Transfer 事件中解析出的 NFT 转移
+type Erc721Transfer struct {
+	Token       common.Address
+	From        common.Address
+	To          common.Address
+	TokenId     *big.Int
+	TxHash      common.Hash
+	BlockNumber uint64
+	LogIndex    uint
+}
+
+// IsErc721TransferLog 判断日志是否为 ERC-721 Transfer
+// ERC-20 的 value 放在 data 中只有 3 个 topic，ERC-721 的 tokenId 是第 4 个 indexed topic
+func IsErc721TransferLog(log types.Log) bool {
+	return len(log.Topics) == 4 && log.Topics[0] == TransferTopic && len(log.Data) == 0
+}
+
+// DecodeErc721TransferLog 解码 ERC-721 Transfer 事件日志
+func DecodeErc721TransferLog(log types.Log) (*Erc721Transfer, error) {
+	if !IsErc721TransferLog(log) {
+		return nil, errors.New("not an erc721 transfer log")
+	}
+	return &Erc721Transfer{
+		Token:       log.Address,
+		From:        common.BytesToAddress(log.Topics[1].Bytes()),
+		To:          common.BytesToAddress(log.Topics[2].Bytes()),
+		TokenId:     log.Topics[3].Big(),
+		TxHash:      log.TxHash,
+		BlockNumber: log.BlockNumber,
+		LogIndex:    log.Index,
+	}, nil
+}
+
+// BuildErc721OwnerOfData 构建 ownerOf(uint256) 查询数据，用于 eth_call
+func BuildErc721OwnerOfData(tokenID *big.Int) ([]byte, error) {
+	dataTokenID, err := encodeUint256(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCallData(erc721OwnerOfMethodID, dataTokenID), nil
+}
+
+// DecodeErc721OwnerOfResult 解码 ownerOf 的返回值
+func DecodeErc721OwnerOfResult(result []byte) (common.Address, error) {
+	if len(result) != 32 {
+		return common.Address{}, errors.New("invalid ownerOf result length")
+	}
+	return wordToAddress(result)
+}
@@ -0,0 +1,141 @@
+package ethereum
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"github.com/0xweb-3/EthCEXWallet/wallet/types"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"math/big"
+)
+
+// DefaultHDBasePath 以太坊 BIP44 路径 m/44'/60'/0'/0，地址序号追加在最后一级
+var DefaultHDBasePath = accounts.DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0}
+
+var errInvalidHDChild = errors.New("invalid hd child key, use next index")
+
+// NewHDSeed 生成随机的 32 字节 HD 种子
+func NewHDSeed() ([]byte, error) {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// SeedFromMnemonic 按 BIP39 由助记词与密码计算种子（不校验词表）
+func SeedFromMnemonic(mnemonic, passphrase string) []byte {
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+}
+
+// DeriveHDPrivateKey 按 BIP32 从种子派生路径上的私钥
+func DeriveHDPrivateKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	key, err := DeriveHDKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(key.key)
+}
+
+// HDKey BIP32 扩展私钥，批量派生同一父路径下的地址时只需派生一次父密钥
+type HDKey struct {
+	key       []byte
+	chainCode []byte
+	publicKey []byte // 压缩公钥，非强化派生子密钥时使用
+}
+
+// DeriveHDKey 按 BIP32 从种子派生路径上的扩展私钥
+func DeriveHDKey(seed []byte, path accounts.DerivationPath) (*HDKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("hd seed length must be between 16 and 64 bytes")
+	}
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := sum[:32], sum[32:]
+	if err := checkHDKey(key); err != nil {
+		return nil, err
+	}
+
+	var err error
+	for _, index := range path {
+		if key, chainCode, err = deriveHDChild(key, chainCode, nil, index); err != nil {
+			return nil, err
+		}
+	}
+	privateKey, err := crypto.ToECDSA(key)
+	if err != nil {
+		return nil, err
+	}
+	return &HDKey{key: key, chainCode: chainCode, publicKey: crypto.CompressPubkey(&privateKey.PublicKey)}, nil
+}
+
+// ChildPrivateKey 派生 index 对应的子私钥，可并发调用
+func (k *HDKey) ChildPrivateKey(index uint32) (*ecdsa.PrivateKey, error) {
+	key, _, err := deriveHDChild(k.key, k.chainCode, k.publicKey, index)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(key)
+}
+
+// CreateAddressByHDSeed 通过 HD 种子生成 m/44'/60'/0'/0/index 的地址信息
+func CreateAddressByHDSeed(ctx context.Context, seed []byte, index uint32) (*types.EthAddress, error) {
+	path := append(accounts.DerivationPath{}, DefaultHDBasePath...)
+	privateKey, err := DeriveHDPrivateKey(seed, append(path, index))
+	if err != nil {
+		return nil, err
+	}
+	return CreateAddressByPrivateKey(ctx, privateKey)
+}
+
+// deriveHDChild BIP32 CKDpriv，publicKey 为父密钥的压缩公钥，为空时由 key 计算
+func deriveHDChild(key, chainCode, publicKey []byte, index uint32) ([]byte, []byte, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		// 强化派生：0x00 || k || index
+		data = append([]byte{0x00}, key...)
+	} else if publicKey != nil {
+		data = append([]byte{}, publicKey...)
+	} else {
+		privateKey, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&privateKey.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, nil, errInvalidHDChild
+	}
+	child := il.Add(il, new(big.Int).SetBytes(key))
+	child.Mod(child, crypto.S256().Params().N)
+
+	childKey := make([]byte, 32)
+	child.FillBytes(childKey)
+	if err := checkHDKey(childKey); err != nil {
+		return nil, nil, err
+	}
+	return childKey, sum[32:], nil
+}
+
+func checkHDKey(key []byte) error {
+	k := new(big.Int).SetBytes(key)
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return errInvalidHDChild
+	}
+	return nil
+}
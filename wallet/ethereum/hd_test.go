@@ -0,0 +1,63 @@
+package ethereum
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"testing"
+)
+
+// BIP32 测试向量 1
+func TestDeriveHDPrivateKey(t *testing.T) {
+	seed := common.Hex2Bytes("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "m/0'", want: "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{path: "m/0'/1", want: "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{path: "m/0'/1/2'", want: "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+	}
+	for _, tt := range tests {
+		path, err := accounts.ParseDerivationPath(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := DeriveHDPrivateKey(seed, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := common.Bytes2Hex(crypto.FromECDSA(key)); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHDKeyChildPrivateKey(t *testing.T) {
+	seed := common.Hex2Bytes("000102030405060708090a0b0c0d0e0f")
+	parent, err := DeriveHDKey(seed, accounts.DerivationPath{0x80000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与完整路径派生的结果一致，见 TestDeriveHDPrivateKey 的 m/0'/1
+	key, err := parent.ChildPrivateKey(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := common.Bytes2Hex(crypto.FromECDSA(key)); got != "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368" {
+		t.Errorf("m/0'/1 = %s", got)
+	}
+}
+
+// hardhat 默认助记词的第一个账户
+func TestCreateAddressByHDSeed(t *testing.T) {
+	seed := SeedFromMnemonic("test test test test test test test test test test test junk", "")
+	address, err := CreateAddressByHDSeed(context.Background(), seed, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address.Address != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Errorf("address = %s", address.Address)
+	}
+}
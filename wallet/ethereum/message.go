@@ -0,0 +1,97 @@
+package ethereum
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// SignPersonalMessage EIP-191 personal_sign 签名，返回 65 字节 r||s||v 签名（v 为 27/28）
+func SignPersonalMessage(privateKeyStr string, message []byte) ([]byte, error) {
+	return signHashWithKey(privateKeyStr, accounts.TextHash(message))
+}
+
+// RecoverPersonalMessageSigner 从 personal_sign 签名中恢复签名地址
+func RecoverPersonalMessageSigner(message []byte, signature []byte) (common.Address, error) {
+	return recoverSigner(accounts.TextHash(message), signature)
+}
+
+// VerifyPersonalMessage 校验 personal_sign 签名是否由 address 签出，用于地址所有权证明
+func VerifyPersonalMessage(address common.Address, message []byte, signature []byte) (bool, error) {
+	signer, err := RecoverPersonalMessageSigner(message, signature)
+	if err != nil {
+		return false, err
+	}
+	return signer == address, nil
+}
+
+// TypedDataHash 计算 EIP-712 结构化数据的签名哈希
+func TypedDataHash(typedData apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// SignTypedData EIP-712 结构化数据签名（eth_signTypedData_v4），返回 65 字节 r||s||v 签名
+func SignTypedData(privateKeyStr string, typedData apitypes.TypedData) ([]byte, error) {
+	hash, err := TypedDataHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return signHashWithKey(privateKeyStr, hash.Bytes())
+}
+
+// RecoverTypedDataSigner 从 EIP-712 签名中恢复签名地址
+func RecoverTypedDataSigner(typedData apitypes.TypedData, signature []byte) (common.Address, error) {
+	hash, err := TypedDataHash(typedData)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverSigner(hash.Bytes(), signature)
+}
+
+// VerifyTypedData 校验 EIP-712 签名是否由 address 签出
+func VerifyTypedData(address common.Address, typedData apitypes.TypedData, signature []byte) (bool, error) {
+	signer, err := RecoverTypedDataSigner(typedData, signature)
+	if err != nil {
+		return false, err
+	}
+	return signer == address, nil
+}
+
+// signHashWithKey 对 32 字节哈希签名，v 按以太坊惯例加 27
+func signHashWithKey(privateKeyStr string, hash []byte) ([]byte, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(hash, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// recoverSigner 从签名中恢复地址，v 兼容 0/1 与 27/28 两种写法
+func recoverSigner(hash []byte, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, errors.New("invalid signature length")
+	}
+	sig := common.CopyBytes(signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, errors.New("invalid signature recovery id")
+	}
+	publicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
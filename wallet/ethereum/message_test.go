@@ -0,0 +1,83 @@
+package ethereum
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"math/big"
+	"testing"
+)
+
+func TestSignPersonalMessage(t *testing.T) {
+	// web3.js eth.accounts.sign 文档中的示例
+	privateKeyStr := "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	expectedSignature := "0xb91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c"
+	address := common.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23")
+
+	signature, err := SignPersonalMessage(privateKeyStr, []byte("Some data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hexutil.Encode(signature) != expectedSignature {
+		t.Errorf("signature = %s, want %s", hexutil.Encode(signature), expectedSignature)
+	}
+
+	ok, err := VerifyPersonalMessage(address, []byte("Some data"), signature)
+	if err != nil || !ok {
+		t.Errorf("verify = %v %v", ok, err)
+	}
+	ok, err = VerifyPersonalMessage(address, []byte("Other data"), signature)
+	if err != nil || ok {
+		t.Errorf("verify tampered message = %v %v", ok, err)
+	}
+	if _, err := RecoverPersonalMessageSigner([]byte("Some data"), signature[:64]); err == nil {
+		t.Error("expected error for short signature")
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+	privateKeyStr := "17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4"
+	address := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+			},
+			"Order": {
+				{Name: "maker", Type: "address"},
+				{Name: "symbol", Type: "string"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Order",
+		Domain: apitypes.TypedDataDomain{
+			Name:    "EthCEXWallet",
+			Version: "1",
+			ChainId: (*math.HexOrDecimal256)(big.NewInt(1)),
+		},
+		Message: apitypes.TypedDataMessage{
+			"maker":  address.Hex(),
+			"symbol": "ETH-USDT",
+			"amount": "1000",
+		},
+	}
+
+	signature, err := SignTypedData(privateKeyStr, typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := VerifyTypedData(address, typedData, signature)
+	if err != nil || !ok {
+		t.Errorf("verify = %v %v", ok, err)
+	}
+
+	typedData.Message["amount"] = "1001"
+	ok, err = VerifyTypedData(address, typedData, signature)
+	if err != nil || ok {
+		t.Errorf("verify tampered message = %v %v", ok, err)
+	}
+}
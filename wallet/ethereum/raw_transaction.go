@@ -0,0 +1,157 @@
+package ethereum
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"strings"
+
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+// DecodeRawTransaction 解码签名后的原始交易（可带或不带 0x），恢复发送方并识别代币调用
+func DecodeRawTransaction(rawTx string) (*WalletTypes.TransactionSummary, error) {
+	rawTx = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(rawTx), "0x"), "0X")
+	txBytes, err := hexutil.Decode("0x" + rawTx)
+	if err != nil {
+		return nil, errors.New("invalid raw transaction hex")
+	}
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(txBytes); err != nil {
+		return nil, err
+	}
+	return SummarizeTransaction(tx)
+}
+
+// SummarizeTransaction 为已签名交易生成摘要，如通过 eth_getTransactionByHash 查询到的交易
+func SummarizeTransaction(tx *types.Transaction) (*WalletTypes.TransactionSummary, error) {
+	// 未带链 ID 的老交易 ChainId 为 0，签名器会退回到 Homestead 规则
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &WalletTypes.TransactionSummary{
+		Hash:      tx.Hash().Hex(),
+		Type:      tx.Type(),
+		ChainId:   tx.ChainId().String(),
+		Nonce:     tx.Nonce(),
+		From:      from.Hex(),
+		Value:     tx.Value().String(),
+		Gas:       tx.Gas(),
+		GasPrice:  tx.GasPrice().String(),
+		GasTipCap: tx.GasTipCap().String(),
+		GasFeeCap: tx.GasFeeCap().String(),
+		Data:      hexutil.Encode(tx.Data()),
+	}
+	if tx.To() == nil {
+		// 合约创建交易没有接收方
+		return summary, nil
+	}
+	summary.To = tx.To().Hex()
+
+	// 调用数据不规范（如带有多余字节）时仍返回摘要供审批与审计查看，调用保持未解码
+	call, err := DecodeContractCall(tx.Data())
+	if err != nil {
+		summary.Warnings = append(summary.Warnings, "contract call not decoded: "+err.Error())
+		return summary, nil
+	}
+	if call != nil {
+		call.Token = summary.To
+		summary.Call = call
+	}
+	return summary, nil
+}
+
+// DecodeContractCall 根据函数选择器解码代币调用数据，不认识的选择器返回 nil
+func DecodeContractCall(data []byte) (*WalletTypes.ContractCall, error) {
+	if len(data) < 4 {
+		return nil, nil
+	}
+
+	switch {
+	case bytes.Equal(data[:4], erc20TransferMethodID):
+		to, amount, err := DecodeErc20Data(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC20", Method: "transfer", To: to.Hex(), Amount: amount.String()}, nil
+	case bytes.Equal(data[:4], erc20ApproveMethodID):
+		spender, amount, err := DecodeErc20ApproveData(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC20", Method: "approve", To: spender.Hex(), Amount: amount.String()}, nil
+	case bytes.Equal(data[:4], erc20TransferFromMethodID):
+		from, to, amount, err := DecodeErc20TransferFromData(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC20", Method: "transferFrom", From: from.Hex(), To: to.Hex(), Amount: amount.String()}, nil
+	case bytes.Equal(data[:4], erc20IncreaseAllowanceMethodID):
+		spender, amount, err := DecodeErc20IncreaseAllowanceData(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC20", Method: "increaseAllowance", To: spender.Hex(), Amount: amount.String()}, nil
+	case bytes.Equal(data[:4], erc20DecreaseAllowanceMethodID):
+		spender, amount, err := DecodeErc20DecreaseAllowanceData(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC20", Method: "decreaseAllowance", To: spender.Hex(), Amount: amount.String()}, nil
+	case bytes.Equal(data[:4], erc20PermitMethodID):
+		permit, _, err := DecodeErc20PermitData(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC20", Method: "permit", From: permit.Owner.Hex(), To: permit.Spender.Hex(), Amount: permit.Value.String()}, nil
+	case bytes.Equal(data[:4], erc721SafeTransferFromMethodID):
+		from, to, tokenID, err := DecodeErc721Data(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC721", Method: "safeTransferFrom", From: from.Hex(), To: to.Hex(), TokenId: tokenID.String()}, nil
+	case bytes.Equal(data[:4], erc1155SafeTransferFromMethodID):
+		from, to, tokenID, amount, err := DecodeErc1155Data(data)
+		if err != nil {
+			return nil, err
+		}
+		return &WalletTypes.ContractCall{Standard: "ERC1155", Method: "safeTransferFrom", From: from.Hex(), To: to.Hex(), TokenId: tokenID.String(), Amount: amount.String()}, nil
+	}
+	return nil, nil
+}
+
+// DecodeErc20Data 解码 BuildErc20Data 生成的 transfer 调用数据
+func DecodeErc20Data(data []byte) (common.Address, *big.Int, error) {
+	words, err := decodeCallWords(data, erc20TransferMethodID, 2)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	to, err := wordToAddress(words[0])
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return to, new(big.Int).SetBytes(words[1]), nil
+}
+
+// DecodeErc721Data 解码 BuildErc721Data 生成的 safeTransferFrom 调用数据
+func DecodeErc721Data(data []byte) (common.Address, common.Address, *big.Int, error) {
+	words, err := decodeCallWords(data, erc721SafeTransferFromMethodID, 3)
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, err
+	}
+	from, err := wordToAddress(words[0])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, err
+	}
+	to, err := wordToAddress(words[1])
+	if err != nil {
+		return common.Address{}, common.Address{}, nil, err
+	}
+	return from, to, new(big.Int).SetBytes(words[2]), nil
+}
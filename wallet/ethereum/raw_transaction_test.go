@@ -0,0 +1,111 @@
+package ethereum
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+)
+
+// https/request.http 中广播过的交易
+func TestDecodeRawTransaction(t *testing.T) {
+	rawTx := "0x02f87383aa36a708838e7d0e8434cb895c82cf08948ff44c9b5eab5e5ce8d1d642184b70e9b9587f7487038d7ea4c6800080c001a0a8b28054e039c6eedc1eddd8f554b9f6a8ec24b720515bb11b2930d8f3253352a025b74b06605166fcd40964b442772fbdb360cfed5bda5156e4c86efc4f3c2cf2"
+
+	for _, input := range []string{rawTx, rawTx[2:]} {
+		summary, err := DecodeRawTransaction(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.From != "0xEB80a127b2b763C631D8ADCeBb0976b190C8C227" {
+			t.Errorf("from = %v", summary.From)
+		}
+		if summary.To != "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74" {
+			t.Errorf("to = %v", summary.To)
+		}
+		if summary.ChainId != "11155111" || summary.Nonce != 8 || summary.Value != "1000000000000000" {
+			t.Errorf("unexpected summary %+v", summary)
+		}
+		if summary.Call != nil {
+			t.Errorf("plain transfer decoded as contract call %+v", summary.Call)
+		}
+	}
+}
+
+func TestDecodeRawTransactionErc20(t *testing.T) {
+	privateKeyStr := "17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4"
+	token := common.HexToAddress("0x779877A7B0D9E8603169DdbD7836e478b4624789")
+	toAddress := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	chainID := big.NewInt(11155111)
+
+	data, err := BuildErc20Data(toAddress, big.NewInt(123456))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txHex, err := OfflineSignTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     1,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       60000,
+		To:        &token,
+		Value:     big.NewInt(0),
+		Data:      data,
+	}, privateKeyStr, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := DecodeRawTransaction(txHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := summary.Call
+	if call == nil {
+		t.Fatal("erc20 transfer not decoded")
+	}
+	if call.Standard != "ERC20" || call.Method != "transfer" || call.Token != token.Hex() || call.To != toAddress.Hex() || call.Amount != "123456" {
+		t.Errorf("unexpected call %+v", call)
+	}
+
+	// 选择器已知但调用数据带多余字节时仍返回摘要
+	txHex, err = OfflineSignTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     2,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       60000,
+		To:        &token,
+		Value:     big.NewInt(0),
+		Data:      append(data, 0x01),
+	}, privateKeyStr, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err = DecodeRawTransaction(txHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Call != nil || len(summary.Warnings) != 1 || summary.To != token.Hex() {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestDecodeContractCallErc721(t *testing.T) {
+	from := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	to := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	data, err := BuildErc721Data(from, to, big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := DecodeContractCall(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call == nil || call.Method != "safeTransferFrom" || call.From != from.Hex() || call.To != to.Hex() || call.TokenId != "42" {
+		t.Errorf("unexpected call %+v", call)
+	}
+
+	if _, err := DecodeContractCall(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated call data")
+	}
+}
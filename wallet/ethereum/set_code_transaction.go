@@ -0,0 +1,141 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math"
+	"math/big"
+)
+
+// errAnyChainAuthorization 链 ID 为 0 的授权在所有链上有效，可被重放到其他链
+var errAnyChainAuthorization = errors.New("authorization with chain id 0 is valid on every chain")
+
+// SignSetCodeAuthorization 签名 EIP-7702 授权元组，将 EOA 委托给 delegate 合约
+// 链 ID 为 0 的授权在所有链上有效，热钱包不签名此类授权，chainId 必须为具体的链
+func SignSetCodeAuthorization(privateKeyStr string, chainId *big.Int, delegate common.Address, nonce uint64) (*types.SetCodeAuthorization, error) {
+	if chainId == nil || chainId.Sign() == 0 {
+		return nil, errAnyChainAuthorization
+	}
+	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	if err != nil {
+		return nil, err
+	}
+	if nonce == math.MaxUint64 {
+		return nil, errors.New("authorization nonce overflows")
+	}
+
+	authChainId, err := bigToUint256(chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := types.SignSetCode(privateKey, types.SetCodeAuthorization{
+		ChainID: *authChainId,
+		Address: delegate,
+		Nonce:   nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
+
+// ValidateSetCodeAuthorization 校验授权的链 ID 与 nonce，返回授权账户地址
+// nonceAt 用于查询授权账户当前的 nonce；若授权账户同时是交易发送方，调用方需返回交易 nonce+1
+// 链 ID 为 0 的授权只在 allowAnyChain 为 true 时接受
+func ValidateSetCodeAuthorization(auth types.SetCodeAuthorization, chainId *big.Int, allowAnyChain bool, nonceAt func(common.Address) (uint64, error)) (common.Address, error) {
+	if err := checkAuthorizationChainId(auth, chainId, allowAnyChain); err != nil {
+		return common.Address{}, err
+	}
+	if auth.Nonce == math.MaxUint64 {
+		return common.Address{}, errors.New("authorization nonce overflows")
+	}
+
+	authority, err := auth.Authority()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	nonce, err := nonceAt(authority)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if auth.Nonce != nonce {
+		return common.Address{}, fmt.Errorf("authorization nonce %d mismatch for %s, want %d", auth.Nonce, authority.Hex(), nonce)
+	}
+	return authority, nil
+}
+
+// BuildSetCodeTx 在 EIP1559 交易参数的基础上构建 EIP-7702 SetCodeTx
+// 链 ID 为 0 的授权只在 allowAnyChain 为 true 时接受
+func BuildSetCodeTx(feeTx *types.DynamicFeeTx, authList []types.SetCodeAuthorization, allowAnyChain bool) (*types.SetCodeTx, error) {
+	if feeTx.To == nil {
+		return nil, errors.New("set code transaction cannot be contract creation")
+	}
+	if len(authList) == 0 {
+		return nil, errors.New("set code transaction requires authorization list")
+	}
+	for i, auth := range authList {
+		if err := checkAuthorizationChainId(auth, feeTx.ChainID, allowAnyChain); err != nil {
+			return nil, fmt.Errorf("authorization %d: %w", i, err)
+		}
+	}
+
+	setCodeTx := &types.SetCodeTx{
+		Nonce:      feeTx.Nonce,
+		Gas:        feeTx.Gas,
+		To:         *feeTx.To,
+		Data:       common.CopyBytes(feeTx.Data),
+		AccessList: feeTx.AccessList,
+		AuthList:   authList,
+	}
+
+	err := setUint256Fields([]uint256Field{
+		{"chain id", &setCodeTx.ChainID, feeTx.ChainID},
+		{"gas tip cap", &setCodeTx.GasTipCap, feeTx.GasTipCap},
+		{"gas fee cap", &setCodeTx.GasFeeCap, feeTx.GasFeeCap},
+		{"value", &setCodeTx.Value, feeTx.Value},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return setCodeTx, nil
+}
+
+func checkAuthorizationChainId(auth types.SetCodeAuthorization, chainId *big.Int, allowAnyChain bool) error {
+	if chainId == nil || chainId.Sign() <= 0 {
+		return errors.New("chain id is required")
+	}
+	if auth.ChainID.IsZero() {
+		if !allowAnyChain {
+			return errAnyChainAuthorization
+		}
+		return nil
+	}
+	if auth.ChainID.ToBig().Cmp(chainId) != 0 {
+		return fmt.Errorf("authorization chain id %v mismatch, want %v", auth.ChainID.ToBig(), chainId)
+	}
+	return nil
+}
+
+// OfflineSignSetCodeTx 使用 Prague 签名器离线签名 EIP-7702 交易
+func OfflineSignSetCodeTx(setCodeTx *types.SetCodeTx, privateKeyStr string, chainId *big.Int) (string, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	if err != nil {
+		return "", err
+	}
+
+	signedTx, err := types.SignTx(types.NewTx(setCodeTx), types.NewPragueSigner(chainId), privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	signedTxBytes, err := signedTx.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return common.Bytes2Hex(signedTxBytes), nil
+}
@@ -0,0 +1,109 @@
+package ethereum
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+)
+
+func TestOfflineSignSetCodeTx(t *testing.T) {
+	privateKeyStr := "17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4"
+	fromAddress := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	// 批量付款合约
+	delegate := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	chainID := big.NewInt(11155111)
+	txNonce := uint64(8)
+
+	// 发送方即授权方，授权 nonce 需为交易 nonce+1
+	auth, err := SignSetCodeAuthorization(privateKeyStr, chainID, delegate, txNonce+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authority, err := ValidateSetCodeAuthorization(*auth, chainID, false, func(common.Address) (uint64, error) {
+		return txNonce + 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authority != fromAddress {
+		t.Errorf("authority = %v, want %v", authority, fromAddress)
+	}
+
+	if _, err := ValidateSetCodeAuthorization(*auth, big.NewInt(1), false, func(common.Address) (uint64, error) {
+		return txNonce + 1, nil
+	}); err == nil {
+		t.Error("expected chain id mismatch error")
+	}
+	if _, err := ValidateSetCodeAuthorization(*auth, chainID, false, func(common.Address) (uint64, error) {
+		return txNonce, nil
+	}); err == nil {
+		t.Error("expected nonce mismatch error")
+	}
+
+	setCodeTx, err := BuildSetCodeTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     txNonce,
+		GasTipCap: big.NewInt(1000000000),
+		GasFeeCap: big.NewInt(30000000000),
+		Gas:       100000,
+		To:        &fromAddress,
+		Value:     big.NewInt(0),
+	}, []types.SetCodeAuthorization{*auth}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txHex, err := OfflineSignSetCodeTx(setCodeTx, privateKeyStr, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(common.Hex2Bytes(txHex)); err != nil {
+		t.Fatal(err)
+	}
+	if signedTx.Type() != types.SetCodeTxType {
+		t.Fatalf("tx type = %d, want %d", signedTx.Type(), types.SetCodeTxType)
+	}
+	if len(signedTx.SetCodeAuthorizations()) != 1 {
+		t.Fatal("authorization list missing")
+	}
+	sender, err := types.Sender(types.NewPragueSigner(chainID), signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != fromAddress {
+		t.Errorf("sender = %v, want %v", sender, fromAddress)
+	}
+}
+
+func TestBuildSetCodeTxChainMismatch(t *testing.T) {
+	to := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	auth, err := SignSetCodeAuthorization("17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4", big.NewInt(1), to, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = BuildSetCodeTx(&types.DynamicFeeTx{ChainID: big.NewInt(10), To: &to}, []types.SetCodeAuthorization{*auth}, false)
+	if err == nil {
+		t.Error("expected chain id mismatch error")
+	}
+	if _, err := BuildSetCodeTx(&types.DynamicFeeTx{To: &to}, []types.SetCodeAuthorization{*auth}, false); err == nil {
+		t.Error("expected missing chain id error")
+	}
+	if _, err := ValidateSetCodeAuthorization(*auth, nil, false, nil); err == nil {
+		t.Error("expected missing chain id error")
+	}
+
+	// 链 ID 为 0 的授权需显式允许
+	if _, err := SignSetCodeAuthorization("17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4", big.NewInt(0), to, 0); err == nil {
+		t.Error("expected any-chain authorization to be refused")
+	}
+	anyChain := []types.SetCodeAuthorization{{Address: to}}
+	if _, err := BuildSetCodeTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), To: &to}, anyChain, false); err == nil {
+		t.Error("expected any-chain authorization to be rejected")
+	}
+	if _, err := BuildSetCodeTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), To: &to}, anyChain, true); err != nil {
+		t.Error(err)
+	}
+}
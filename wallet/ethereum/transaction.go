@@ -1,12 +1,55 @@
 package ethereum
 
 import (
+	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
 	"math/big"
 )
 
+var (
+	erc20TransferMethodID          = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+	erc20ApproveMethodID           = crypto.Keccak256([]byte("approve(address,uint256)"))[:4]
+	erc721SafeTransferFromMethodID = crypto.Keccak256([]byte("safeTransferFrom(address,address,uint256)"))[:4]
+)
+
+// bigToUint256 将 big.Int 转换为交易字段使用的 uint256，nil 视为 0
+func bigToUint256(v *big.Int) (*uint256.Int, error) {
+	if v == nil {
+		return new(uint256.Int), nil
+	}
+	if v.Sign() < 0 {
+		return nil, errors.New("transaction field is negative")
+	}
+	u, overflow := uint256.FromBig(v)
+	if overflow {
+		return nil, errors.New("transaction field overflows uint256")
+	}
+	return u, nil
+}
+
+// uint256Field 交易中需要由 big.Int 转换为 uint256 的字段
+type uint256Field struct {
+	name string
+	dst  **uint256.Int
+	src  *big.Int
+}
+
+// setUint256Fields 依次转换 fields，任一字段为负或溢出 uint256 时返回错误
+func setUint256Fields(fields []uint256Field) error {
+	for _, field := range fields {
+		u, err := bigToUint256(field.src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+		*field.dst = u
+	}
+	return nil
+}
+
 // 构建ERC-20的交易数据
 func BuildErc20Data(toAddress common.Address, amount *big.Int) ([]byte, error) {
 	var data []byte
@@ -16,7 +59,7 @@ func BuildErc20Data(toAddress common.Address, amount *big.Int) ([]byte, error) {
 	//hash := sha3.NewLegacyKeccak256()
 	//hash.Write(transferFnSignature)
 	//methodID := hash.Sum(nil)[:4]
-	methodID := crypto.Keccak256Hash([]byte("transfer(address,uint256)")).Bytes()[:4]
+	methodID := erc20TransferMethodID
 
 	// 对 toAddress 和 amount 进行 ABI 编码
 	dataAddress := common.LeftPadBytes(toAddress.Bytes(), 32)
@@ -34,7 +77,7 @@ func BuildErc721Data(fromAddress, toAddress common.Address, tokenID *big.Int) ([
 	var data []byte
 
 	// 获取 safeTransferFrom(address,address,uint256) 函数的签名哈希
-	methodID := crypto.Keccak256Hash([]byte("safeTransferFrom(address,address,uint256)")).Bytes()[:4]
+	methodID := erc721SafeTransferFromMethodID
 
 	// 对 fromAddress, toAddress 和 tokenID 进行 ABI 编码
 	dataFromAddress := common.LeftPadBytes(fromAddress.Bytes(), 32)
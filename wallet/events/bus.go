@@ -0,0 +1,172 @@
+package events
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSequenceExpired 续传位置早于总线保留的最早事件，或大于当前序号（来自重启前的进程），订阅方需要从持久化存储补齐
+	ErrSequenceExpired = errors.New("event sequence no longer retained")
+	// ErrSlowSubscriber 订阅方消费过慢导致缓冲区写满，订阅被关闭，可按最后序号重新订阅
+	ErrSlowSubscriber = errors.New("subscriber too slow, subscription dropped")
+	ErrBusClosed      = errors.New("event bus closed")
+)
+
+// Bus 进程内事件总线，保留最近 historySize 条事件用于断点续传
+type Bus struct {
+	historySize int
+
+	mu          sync.Mutex
+	sequence    uint64
+	history     []Event
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewBus(historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = 10000
+	}
+	return &Bus{
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish 分配序号、ID 与时间后投递给所有订阅方，从不阻塞
+func (b *Bus) Publish(event Event) (Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return Event{}, ErrBusClosed
+	}
+
+	b.sequence++
+	event.Sequence = b.sequence
+	if event.Id == "" {
+		event.Id = uuid.NewString()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+	for sub := range b.subscribers {
+		if !sub.deliver(event) {
+			b.drop(sub, ErrSlowSubscriber)
+		}
+	}
+	return event, nil
+}
+
+// Subscribe 订阅序号大于 afterSequence 的事件，afterSequence 为 0 表示只接收新事件
+// 序号在进程重启后从 1 重新开始，大于当前序号的续传位置无法对应，返回 ErrSequenceExpired
+// types 为空时接收全部类型
+func (b *Bus) Subscribe(afterSequence uint64, buffer int, types ...Type) (*Subscription, error) {
+	if buffer <= 0 {
+		buffer = 256
+	}
+	filter := make(map[Type]bool, len(types))
+	for _, t := range types {
+		filter[t] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+
+	if afterSequence > b.sequence {
+		return nil, ErrSequenceExpired
+	}
+	var replay []Event
+	if afterSequence > 0 && afterSequence < b.sequence {
+		if len(b.history) == 0 || b.history[0].Sequence > afterSequence+1 {
+			return nil, ErrSequenceExpired
+		}
+		replay = b.history[afterSequence+1-b.history[0].Sequence:]
+	}
+
+	// 缓冲区额外容纳待补发的历史事件，补发不会因缓冲区不足失败
+	sub := &Subscription{bus: b, events: make(chan Event, buffer+len(replay)), filter: filter}
+	for _, event := range replay {
+		sub.deliver(event)
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Sequence 返回最近一条事件的序号
+func (b *Bus) Sequence() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sequence
+}
+
+// Close 关闭总线及所有订阅
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for sub := range b.subscribers {
+		b.drop(sub, ErrBusClosed)
+	}
+}
+
+// drop 调用方需持有 b.mu
+func (b *Bus) drop(sub *Subscription, err error) {
+	delete(b.subscribers, sub)
+	sub.err = err
+	close(sub.events)
+}
+
+// Subscription 一个订阅，Events 关闭后通过 Err 获取原因
+type Subscription struct {
+	bus    *Bus
+	events chan Event
+	filter map[Type]bool
+	err    error
+}
+
+// Events 事件通道，订阅关闭后通道关闭
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err 通道关闭的原因，主动 Close 时为 nil
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.err
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subscribers[s]; ok {
+		s.bus.drop(s, nil)
+	}
+}
+
+func (s *Subscription) deliver(event Event) bool {
+	if len(s.filter) > 0 && !s.filter[event.Type] {
+		return true
+	}
+	select {
+	case s.events <- event:
+		return true
+	default:
+		return false
+	}
+}
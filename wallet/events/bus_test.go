@@ -0,0 +1,71 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBusSubscribe(t *testing.T) {
+	bus := NewBus(3)
+	for i := 0; i < 5; i++ {
+		if _, err := bus.Publish(Event{Type: DepositDetected, ChainId: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bus.Sequence() != 5 {
+		t.Fatalf("Sequence() = %d", bus.Sequence())
+	}
+
+	// 历史只保留 3..5
+	if _, err := bus.Subscribe(1, 0); !errors.Is(err, ErrSequenceExpired) {
+		t.Errorf("Subscribe(1) err = %v", err)
+	}
+	// 重启前进程的序号
+	if _, err := bus.Subscribe(6, 0); !errors.Is(err, ErrSequenceExpired) {
+		t.Errorf("Subscribe(6) err = %v", err)
+	}
+	sub, err := bus.Subscribe(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := uint64(3); want <= 5; want++ {
+		if event := <-sub.Events(); event.Sequence != want || event.Id == "" {
+			t.Fatalf("replayed event = %+v, want sequence %d", event, want)
+		}
+	}
+
+	// 类型过滤
+	filtered, err := bus.Subscribe(0, 0, ReorgRollback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(Event{Type: DepositConfirmed})
+	bus.Publish(Event{Type: ReorgRollback, Reorg: &Reorg{ForkBlock: 10}})
+	if event := <-filtered.Events(); event.Type != ReorgRollback || event.Sequence != 7 {
+		t.Errorf("filtered event = %+v", event)
+	}
+	filtered.Close()
+	if _, ok := <-filtered.Events(); ok || filtered.Err() != nil {
+		t.Errorf("closed subscription err = %v", filtered.Err())
+	}
+
+	// 缓冲区写满后订阅被断开，已缓冲的事件仍可读出
+	slow, err := bus.Subscribe(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(Event{Type: WithdrawalStatus})
+	bus.Publish(Event{Type: WithdrawalStatus})
+	if event := <-slow.Events(); event.Sequence != 8 {
+		t.Errorf("buffered event = %+v", event)
+	}
+	if _, ok := <-slow.Events(); ok || !errors.Is(slow.Err(), ErrSlowSubscriber) {
+		t.Errorf("slow subscriber err = %v", slow.Err())
+	}
+	sub.Close()
+
+	bus.Close()
+	if _, err := bus.Publish(Event{Type: DepositDetected}); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("Publish() after close err = %v", err)
+	}
+}
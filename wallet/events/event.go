@@ -0,0 +1,75 @@
+// Package events 钱包业务事件与进程内事件总线，供 gRPC 推送、webhook 与消息队列消费
+package events
+
+import "time"
+
+// Type 事件类型
+type Type string
+
+const (
+	DepositPending        Type = "deposit.pending"         // 充值交易在交易池中尚未上链，上链后由相同 tx_hash 的 deposit.detected 取代
+	DepositPendingDropped Type = "deposit.pending_dropped" // 交易池中的充值未能上链，原因见 Deposit.Reason
+	DepositDetected       Type = "deposit.detected"        // 充值交易已上链，确认数不足
+	DepositConfirmed      Type = "deposit.confirmed"       // 充值达到确认数，可以入账
+	WithdrawalStatus      Type = "withdrawal.status"       // 提现状态变化
+	ReorgRollback         Type = "reorg.rollback"          // 区块回滚，相关充值需要撤销
+)
+
+// 未确认充值失效的原因
+const (
+	DropReasonExpired  = "expired"  // 超过等待时间仍未上链
+	DropReasonEvicted  = "evicted"  // 已不在交易池中，被替换或被节点移除
+	DropReasonReverted = "reverted" // 已上链但执行失败
+)
+
+// Event 钱包事件，Sequence 由总线分配且严格递增，订阅方据此断点续传
+type Event struct {
+	Id         string      `json:"id"`
+	Sequence   uint64      `json:"sequence"`
+	Type       Type        `json:"type"`
+	ChainId    uint64      `json:"chain_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Deposit    *Deposit    `json:"deposit,omitempty"`
+	Withdrawal *Withdrawal `json:"withdrawal,omitempty"`
+	Reorg      *Reorg      `json:"reorg,omitempty"`
+}
+
+// Deposit 充值事件内容
+type Deposit struct {
+	UserId        string `json:"user_id"`
+	TxHash        string `json:"tx_hash"`
+	LogIndex      uint64 `json:"log_index"`
+	BlockNumber   uint64 `json:"block_number"`
+	BlockHash     string `json:"block_hash"`
+	Token         string `json:"token,omitempty"` // 原生币为空
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Amount        string `json:"amount"`
+	Confirmations uint64 `json:"confirmations"`
+	Reason        string `json:"reason,omitempty"` // 仅 deposit.pending_dropped
+}
+
+// Withdrawal 提现事件内容
+type Withdrawal struct {
+	Id     string `json:"id"`
+	UserId string `json:"user_id"`
+	Token  string `json:"token,omitempty"`
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+	Status string `json:"status"`
+	TxHash string `json:"tx_hash,omitempty"`
+}
+
+// DepositRef 被回滚的充值
+type DepositRef struct {
+	UserId   string `json:"user_id"`
+	TxHash   string `json:"tx_hash"`
+	LogIndex uint64 `json:"log_index"`
+}
+
+// Reorg 回滚事件内容，ForkBlock 之后（不含）的区块已被替换
+type Reorg struct {
+	ForkBlock     uint64       `json:"fork_block"`
+	RemovedBlocks []string     `json:"removed_blocks"`
+	Deposits      []DepositRef `json:"deposits,omitempty"`
+}
@@ -0,0 +1,52 @@
+package forwarder
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+// UserSalt 由用户 ID 计算 CREATE2 salt：keccak256(userId)
+func UserSalt(userId string) [32]byte {
+	return crypto.Keccak256Hash([]byte(userId))
+}
+
+// Create2Address 计算 CREATE2 地址 keccak256(0xff ++ factory ++ salt ++ keccak256(initCode))[12:]
+func Create2Address(factory common.Address, salt [32]byte, initCodeHash common.Hash) common.Address {
+	return crypto.CreateAddress2(factory, salt, initCodeHash.Bytes())
+}
+
+// Allocator 为用户分配 CREATE2 充值地址，地址没有私钥，资金只能通过 forwarder 合约转出
+type Allocator struct {
+	factory      common.Address
+	initCodeHash common.Hash
+}
+
+// NewAllocator initCodeHash 为 forwarder 合约创建代码（含构造参数）的 keccak256
+func NewAllocator(factory common.Address, initCodeHash common.Hash) *Allocator {
+	return &Allocator{
+		factory:      factory,
+		initCodeHash: initCodeHash,
+	}
+}
+
+func (a *Allocator) Factory() common.Address {
+	return a.factory
+}
+
+// Address 计算用户的充值地址
+func (a *Allocator) Address(userId string) common.Address {
+	return Create2Address(a.factory, UserSalt(userId), a.initCodeHash)
+}
+
+// Allocate 生成用户的充值地址信息，PrivateKey 与 PublicKey 为空
+func (a *Allocator) Allocate(userId string) (*types.EthAddress, error) {
+	if userId == "" {
+		return nil, errors.New("user id is empty")
+	}
+	return &types.EthAddress{
+		Address: a.Address(userId).Hex(),
+	}, nil
+}
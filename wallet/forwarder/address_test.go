@@ -0,0 +1,78 @@
+package forwarder
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+// EIP-1014 示例 0
+func TestCreate2Address(t *testing.T) {
+	got := Create2Address(common.Address{}, [32]byte{}, crypto.Keccak256Hash([]byte{0x00}))
+	want := common.HexToAddress("0x4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38")
+	if got != want {
+		t.Errorf("Create2Address() = %s, want %s", got.Hex(), want.Hex())
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	allocator := NewAllocator(common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74"), crypto.Keccak256Hash([]byte("forwarder")))
+	a, err := allocator.Allocate("10001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := allocator.Allocate("10001")
+	c, _ := allocator.Allocate("10002")
+	if a.Address != b.Address || a.Address == c.Address {
+		t.Errorf("addresses not deterministic per user: %s %s %s", a.Address, b.Address, c.Address)
+	}
+	if a.PrivateKey != "" || a.PublicKey != "" {
+		t.Error("create2 address must not carry keys")
+	}
+	if _, err := allocator.Allocate(""); err == nil {
+		t.Error("expected error for empty user id")
+	}
+}
+
+type codeClient struct {
+	node.EthClient
+	code []byte
+}
+
+func (c *codeClient) CodeAt(ctx context.Context, address common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.code, nil
+}
+
+func TestBuildSweepTxs(t *testing.T) {
+	factory := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	token := common.HexToAddress("0x779877A7B0D9E8603169DdbD7836e478b4624789")
+	allocator := NewAllocator(factory, crypto.Keccak256Hash([]byte("forwarder")))
+	client := &codeClient{}
+	sweeper := NewSweeper(client, allocator, big.NewInt(11155111))
+	req := SweepRequest{UserId: "10001", Native: true, Tokens: []common.Address{token}}
+
+	// 首次归集需要部署 forwarder
+	txs, err := sweeper.BuildSweepTxs(context.Background(), req, 5, big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 3 || *txs[0].To != factory || txs[0].Nonce != 5 || txs[2].Nonce != 7 {
+		t.Fatalf("first sweep txs = %+v", txs)
+	}
+	if *txs[1].To != allocator.Address("10001") {
+		t.Errorf("flush target = %s", txs[1].To.Hex())
+	}
+
+	client.code = []byte{0x60}
+	txs, err = sweeper.BuildSweepTxs(context.Background(), req, 8, big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || txs[0].Nonce != 8 {
+		t.Fatalf("deployed sweep txs = %+v", txs)
+	}
+}
@@ -0,0 +1,91 @@
+package forwarder
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+// 约定的合约接口：
+//
+//	factory:   createForwarder(bytes32 salt)，以 CREATE2 部署 forwarder，forwarder 的转出目标为热钱包
+//	forwarder: flush() 将原生币转给热钱包；flushTokens(address token) 将 ERC-20 余额转给热钱包
+var (
+	createForwarderMethodID = crypto.Keccak256([]byte("createForwarder(bytes32)"))[:4]
+	flushMethodID           = crypto.Keccak256([]byte("flush()"))[:4]
+	flushTokensMethodID     = crypto.Keccak256([]byte("flushTokens(address)"))[:4]
+)
+
+// 各操作的 gas 上限，按 forwarder 为最小代理合约估算
+const (
+	createForwarderGas = 150000
+	flushGas           = 50000
+	flushTokensGas     = 100000
+)
+
+// SweepRequest 一次归集请求
+type SweepRequest struct {
+	UserId string
+	Native bool             // 是否归集原生币
+	Tokens []common.Address // 需要归集的 ERC-20 合约
+}
+
+// Sweeper 构建充值地址的归集交易，由热钱包签名并支付 gas
+type Sweeper struct {
+	client    node.EthClient
+	allocator *Allocator
+	chainId   *big.Int
+}
+
+func NewSweeper(client node.EthClient, allocator *Allocator, chainId *big.Int) *Sweeper {
+	return &Sweeper{
+		client:    client,
+		allocator: allocator,
+		chainId:   chainId,
+	}
+}
+
+// BuildSweepTxs 构建归集交易列表，nonce 为热钱包的起始 nonce
+// 充值地址尚未部署 forwarder 时，第一笔交易通过工厂合约部署
+func (s *Sweeper) BuildSweepTxs(ctx context.Context, req SweepRequest, nonce uint64, gasTipCap, gasFeeCap *big.Int) ([]*types.DynamicFeeTx, error) {
+	if !req.Native && len(req.Tokens) == 0 {
+		return nil, errors.New("nothing to sweep")
+	}
+
+	depositAddress := s.allocator.Address(req.UserId)
+	code, err := s.client.CodeAt(ctx, depositAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []*types.DynamicFeeTx
+	newTx := func(to common.Address, gas uint64, data []byte) {
+		txs = append(txs, &types.DynamicFeeTx{
+			ChainID:   s.chainId,
+			Nonce:     nonce + uint64(len(txs)),
+			GasTipCap: gasTipCap,
+			GasFeeCap: gasFeeCap,
+			Gas:       gas,
+			To:        &to,
+			Value:     big.NewInt(0),
+			Data:      data,
+		})
+	}
+
+	if len(code) == 0 {
+		salt := UserSalt(req.UserId)
+		newTx(s.allocator.Factory(), createForwarderGas, append(common.CopyBytes(createForwarderMethodID), salt[:]...))
+	}
+	if req.Native {
+		newTx(depositAddress, flushGas, common.CopyBytes(flushMethodID))
+	}
+	for _, token := range req.Tokens {
+		newTx(depositAddress, flushTokensGas, append(common.CopyBytes(flushTokensMethodID), common.LeftPadBytes(token.Bytes(), 32)...))
+	}
+	return txs, nil
+}
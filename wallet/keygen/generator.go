@@ -0,0 +1,210 @@
+package keygen
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+)
+
+// 每个 worker 每批处理的候选数量
+const candidatesPerWorker = 256
+
+// Options 批量生成参数
+type Options struct {
+	Count    int                     // 需要生成的地址总数
+	Workers  int                     // 并行数，默认 CPU 核数
+	Seed     []byte                  // HD 种子，为空时使用随机私钥
+	BasePath accounts.DerivationPath // HD 路径前缀，默认 m/44'/60'/0'/0
+	Prefix   string                  // 地址前缀（不含 0x，大小写不敏感）
+	Suffix   string                  // 地址后缀
+	Progress func(generated, total int)
+}
+
+// Generator 多核并行的批量/靓号地址生成器，结果加密写入 KeyStore
+type Generator struct {
+	store  *KeyStore
+	opts   Options
+	parent *ethereum.HDKey // BasePath 上的父密钥，随机私钥模式为空
+}
+
+type candidate struct {
+	index   uint64
+	address string
+	keyJSON []byte
+}
+
+func NewGenerator(store *KeyStore, opts Options) (*Generator, error) {
+	if opts.Count <= 0 {
+		return nil, errors.New("count must be positive")
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if len(opts.Seed) > 0 && (len(opts.Seed) < 16 || len(opts.Seed) > 64) {
+		return nil, errors.New("hd seed length must be between 16 and 64 bytes")
+	}
+	if len(opts.BasePath) == 0 {
+		opts.BasePath = ethereum.DefaultHDBasePath
+	}
+	opts.Prefix = strings.ToLower(strings.TrimPrefix(opts.Prefix, "0x"))
+	opts.Suffix = strings.ToLower(opts.Suffix)
+	for _, pattern := range []string{opts.Prefix, opts.Suffix} {
+		if strings.Trim(pattern, "0123456789abcdef") != "" {
+			return nil, errors.New("address pattern must be hex")
+		}
+	}
+	if len(opts.Prefix)+len(opts.Suffix) > 40 {
+		return nil, errors.New("address pattern too long")
+	}
+	generator := &Generator{store: store, opts: opts}
+	if len(opts.Seed) > 0 {
+		parent, err := ethereum.DeriveHDKey(opts.Seed, opts.BasePath)
+		if err != nil {
+			return nil, err
+		}
+		generator.parent = parent
+	}
+	return generator, nil
+}
+
+// Run 从 checkpoint 继续生成，直到达到 Count 或 ctx 取消，返回累计生成数量
+// 每写入一个密钥保存一次 checkpoint；HD 模式下序号达到 MaxIndex 时返回错误，随机私钥模式的序号只用于记录进度
+func (g *Generator) Run(ctx context.Context) (int, error) {
+	checkpoint, err := g.store.LoadCheckpoint()
+	if err != nil {
+		return 0, err
+	}
+	if checkpoint, err = g.store.reconcile(checkpoint); err != nil {
+		return 0, err
+	}
+
+	batchSize := uint32(g.opts.Workers * candidatesPerWorker)
+	for checkpoint.Generated < g.opts.Count {
+		if err := ctx.Err(); err != nil {
+			return checkpoint.Generated, err
+		}
+
+		size := batchSize
+		if g.opts.Prefix == "" && g.opts.Suffix == "" && uint32(g.opts.Count-checkpoint.Generated) < size {
+			// 无靓号条件时每个候选都会命中，只扫描还缺的数量，避免多余的 scrypt 加密
+			size = uint32(g.opts.Count - checkpoint.Generated)
+		}
+		if g.parent != nil {
+			if checkpoint.NextIndex >= MaxIndex {
+				return checkpoint.Generated, errors.New("address index exhausted, hardened indexes are not derived")
+			}
+			if remaining := MaxIndex - checkpoint.NextIndex; uint64(size) > remaining {
+				size = uint32(remaining)
+			}
+		}
+		start := checkpoint.NextIndex
+		matches, err := g.scanBatch(ctx, start, size)
+		if err != nil {
+			return checkpoint.Generated, err
+		}
+		// 按序号顺序写入，超出数量的匹配丢弃
+		for _, match := range matches {
+			if checkpoint.Generated >= g.opts.Count {
+				break
+			}
+			if err := g.store.Write(match.index, match.address, match.keyJSON); err != nil {
+				return checkpoint.Generated, err
+			}
+			checkpoint.Generated++
+			checkpoint.NextIndex = match.index + 1
+			if err := g.store.SaveCheckpoint(checkpoint); err != nil {
+				return checkpoint.Generated, err
+			}
+		}
+		if checkpoint.Generated < g.opts.Count {
+			// 批次内其余候选都不匹配
+			checkpoint.NextIndex = start + uint64(size)
+			if err := g.store.SaveCheckpoint(checkpoint); err != nil {
+				return checkpoint.Generated, err
+			}
+		}
+		if g.opts.Progress != nil {
+			g.opts.Progress(checkpoint.Generated, g.opts.Count)
+		}
+	}
+	return checkpoint.Generated, nil
+}
+
+// scanBatch 并行扫描 [start, start+size) 的候选，返回按序号排列的匹配结果
+func (g *Generator) scanBatch(ctx context.Context, start uint64, size uint32) ([]candidate, error) {
+	results := make([]*candidate, size)
+	errs := make([]error, g.opts.Workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < g.opts.Workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := uint32(worker); i < size; i += uint32(g.opts.Workers) {
+				if ctx.Err() != nil {
+					errs[worker] = ctx.Err()
+					return
+				}
+				match, err := g.tryIndex(start + uint64(i))
+				if err != nil {
+					errs[worker] = err
+					return
+				}
+				results[i] = match
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	var matches []candidate
+	for _, match := range results {
+		if match != nil {
+			matches = append(matches, *match)
+		}
+	}
+	return matches, nil
+}
+
+// tryIndex 生成一个候选，不匹配时返回 nil
+func (g *Generator) tryIndex(index uint64) (*candidate, error) {
+	var privateKey *ecdsa.PrivateKey
+	var err error
+	if g.parent != nil {
+		// Run 保证 HD 模式下 index < MaxIndex
+		privateKey, err = g.parent.ChildPrivateKey(uint32(index))
+		if err != nil {
+			// 极小概率的无效子密钥按 BIP32 跳过
+			return nil, nil
+		}
+	} else {
+		privateKey, err = crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+	lower := hex.EncodeToString(address.Bytes())
+	if !strings.HasPrefix(lower, g.opts.Prefix) || !strings.HasSuffix(lower, g.opts.Suffix) {
+		return nil, nil
+	}
+
+	keyJSON, err := g.store.Encrypt(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &candidate{index: index, address: address.Hex(), keyJSON: keyJSON}, nil
+}
@@ -0,0 +1,139 @@
+package keygen
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+)
+
+func newTestStore(t *testing.T) *KeyStore {
+	store, err := NewKeyStore(t.TempDir(), "passphrase", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestGeneratorHDResume(t *testing.T) {
+	store := newTestStore(t)
+	seed := ethereum.SeedFromMnemonic("test test test test test test test test test test test junk", "")
+
+	generator, err := NewGenerator(store, Options{Count: 3, Workers: 2, Seed: seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := generator.Run(context.Background())
+	if err != nil || generated != 3 {
+		t.Fatalf("Run() = %d %v", generated, err)
+	}
+
+	// 提高数量后续跑，从 checkpoint 的序号继续派生
+	var progress []int
+	generator, _ = NewGenerator(store, Options{Count: 5, Workers: 2, Seed: seed, Progress: func(generated, total int) {
+		progress = append(progress, generated)
+	}})
+	generated, err = generator.Run(context.Background())
+	if err != nil || generated != 5 {
+		t.Fatalf("resumed Run() = %d %v", generated, err)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 5 {
+		t.Errorf("progress = %v", progress)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(store.dir, "*.json"))
+	if len(files) != 6 { // 5 个密钥 + checkpoint
+		t.Fatalf("files = %v", files)
+	}
+
+	want, _ := ethereum.CreateAddressByHDSeed(context.Background(), seed, 4)
+	key, err := store.Load(want.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(key.PublicKey).Hex() != want.Address {
+		t.Error("decrypted key does not match derived address")
+	}
+
+	// HD 序号用尽后不进入硬化派生区间
+	if err := store.SaveCheckpoint(Checkpoint{Generated: 5, NextIndex: MaxIndex}); err != nil {
+		t.Fatal(err)
+	}
+	generator, _ = NewGenerator(store, Options{Count: 6, Workers: 2, Seed: seed})
+	if _, err := generator.Run(context.Background()); err == nil {
+		t.Error("expected index exhausted error")
+	}
+}
+
+func TestGeneratorCheckpoint(t *testing.T) {
+	store := newTestStore(t)
+	generator, err := NewGenerator(store, Options{Count: 3, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模拟写入密钥后、保存进度前中断：续跑时计入已写入的密钥，不再多生成
+	key, _ := crypto.GenerateKey()
+	keyJSON, _ := store.Encrypt(key)
+	if err := store.Write(0, crypto.PubkeyToAddress(key.PublicKey).Hex(), keyJSON); err != nil {
+		t.Fatal(err)
+	}
+	if generated, err := generator.Run(context.Background()); err != nil || generated != 3 {
+		t.Fatalf("Run() = %d %v", generated, err)
+	}
+	files, _ := filepath.Glob(filepath.Join(store.dir, "*--*.json"))
+	if len(files) != 3 {
+		t.Fatalf("files = %v", files)
+	}
+	checkpoint, _ := store.LoadCheckpoint()
+	if checkpoint != (Checkpoint{Generated: 3, NextIndex: 3}) {
+		t.Errorf("checkpoint = %+v", checkpoint)
+	}
+
+	// 随机私钥模式的序号只记录进度，不受 HD 序号上限限制
+	if err := store.SaveCheckpoint(Checkpoint{Generated: 3, NextIndex: MaxIndex}); err != nil {
+		t.Fatal(err)
+	}
+	generator, _ = NewGenerator(store, Options{Count: 4, Workers: 1})
+	if generated, err := generator.Run(context.Background()); err != nil || generated != 4 {
+		t.Fatalf("Run() past MaxIndex = %d %v", generated, err)
+	}
+	if checkpoint, _ := store.LoadCheckpoint(); checkpoint != (Checkpoint{Generated: 4, NextIndex: MaxIndex + 1}) {
+		t.Errorf("checkpoint = %+v", checkpoint)
+	}
+}
+
+func TestGeneratorVanity(t *testing.T) {
+	store := newTestStore(t)
+	generator, err := NewGenerator(store, Options{Count: 2, Prefix: "0xA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generator.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(store.dir)
+	count := 0
+	for _, entry := range entries {
+		if entry.Name() == checkpointFile {
+			continue
+		}
+		count++
+		address := strings.TrimSuffix(strings.SplitN(entry.Name(), "--", 2)[1], ".json")
+		if !strings.HasPrefix(address, "a") {
+			t.Errorf("address %s does not match prefix", address)
+		}
+	}
+	if count != 2 {
+		t.Errorf("generated %d files, want 2", count)
+	}
+
+	if _, err := NewGenerator(store, Options{Count: 1, Suffix: "xyz"}); err == nil {
+		t.Error("expected error for non-hex pattern")
+	}
+}
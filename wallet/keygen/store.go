@@ -0,0 +1,142 @@
+package keygen
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const checkpointFile = "checkpoint.json"
+
+// MaxIndex HD 模式的序号上限，HD 派生序号从 2^31 起为硬化派生，超出后不再生成；随机私钥模式不受限制
+const MaxIndex = 1 << 31
+
+// Checkpoint 批量生成的进度，用于中断后续跑
+type Checkpoint struct {
+	Generated int    `json:"generated"`  // 已写入的地址数量
+	NextIndex uint64 `json:"next_index"` // 下一个待扫描的序号（HD 派生序号或随机生成批次序号）
+}
+
+// KeyStore 以 go-ethereum keystore v3 格式加密保存私钥，每个地址一个文件
+type KeyStore struct {
+	dir        string
+	passphrase string
+	scryptN    int
+	scryptP    int
+}
+
+// NewKeyStore scryptN/scryptP 取 keystore.StandardScryptN/StandardScryptP 或测试用的 Light 参数
+func NewKeyStore(dir, passphrase string, scryptN, scryptP int) (*KeyStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("keystore passphrase is empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &KeyStore{
+		dir:        dir,
+		passphrase: passphrase,
+		scryptN:    scryptN,
+		scryptP:    scryptP,
+	}, nil
+}
+
+// Encrypt 加密私钥，scrypt 很慢，由生成 worker 并行调用
+func (ks *KeyStore) Encrypt(privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	return keystore.EncryptKey(&keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, ks.passphrase, ks.scryptN, ks.scryptP)
+}
+
+// Write 写入加密后的密钥文件，先写临时文件再改名，避免中断时留下半个文件
+func (ks *KeyStore) Write(index uint64, address string, keyJSON []byte) error {
+	name := filepath.Join(ks.dir, fmt.Sprintf("%010d--%s.json", index, strings.ToLower(address[2:])))
+	return writeFileAtomic(name, keyJSON)
+}
+
+// Load 解密指定地址的私钥
+func (ks *KeyStore) Load(address string) (*ecdsa.PrivateKey, error) {
+	matches, err := filepath.Glob(filepath.Join(ks.dir, "*--"+strings.ToLower(strings.TrimPrefix(address, "0x"))+".json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, os.ErrNotExist
+	}
+	keyJSON, err := os.ReadFile(matches[0])
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyJSON, ks.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
+
+// LoadCheckpoint 读取进度，不存在时返回零值
+func (ks *KeyStore) LoadCheckpoint() (Checkpoint, error) {
+	var checkpoint Checkpoint
+	data, err := os.ReadFile(filepath.Join(ks.dir, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	} else if err != nil {
+		return checkpoint, err
+	}
+	err = json.Unmarshal(data, &checkpoint)
+	return checkpoint, err
+}
+
+// reconcile 将序号不小于 checkpoint.NextIndex 的已写入密钥计入进度
+// 写入密钥与保存进度之间中断时，续跑不会在同一序号上重复生成随机私钥
+func (ks *KeyStore) reconcile(checkpoint Checkpoint) (Checkpoint, error) {
+	matches, err := filepath.Glob(filepath.Join(ks.dir, "*--*.json"))
+	if err != nil {
+		return checkpoint, err
+	}
+	// 随机私钥模式的序号可能超过文件名的补零宽度，按数值排序
+	var indexes []uint64
+	for _, match := range matches {
+		index, err := strconv.ParseUint(strings.SplitN(filepath.Base(match), "--", 2)[0], 10, 64)
+		if err == nil && index >= checkpoint.NextIndex {
+			indexes = append(indexes, index)
+		}
+	}
+	slices.Sort(indexes)
+	for _, index := range indexes {
+		checkpoint.Generated++
+		checkpoint.NextIndex = index + 1
+	}
+	return checkpoint, nil
+}
+
+func (ks *KeyStore) SaveCheckpoint(checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(ks.dir, checkpointFile), data)
+}
+
+func writeFileAtomic(name string, data []byte) error {
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,84 @@
+package ledger
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AccountType 账户类别，决定账户的余额方向
+type AccountType string
+
+const (
+	AccountUser    AccountType = "user"    // 负债：交易所欠用户的余额
+	AccountDeposit AccountType = "deposit" // 资产：充值地址上尚未归集的资金
+	AccountHot     AccountType = "hot"     // 资产：热钱包
+	AccountCold    AccountType = "cold"    // 资产：冷钱包
+	AccountPayout  AccountType = "payout"  // 负债：已受理、尚未从热钱包转出的提现
+	AccountFee     AccountType = "fee"     // 收入：向用户收取的提现手续费
+	AccountGas     AccountType = "gas"     // 费用：链上支付的 gas
+)
+
+// debitNormal 资产与费用类账户借方增加，负债与收入类账户贷方增加
+func (t AccountType) debitNormal() bool {
+	switch t {
+	case AccountDeposit, AccountHot, AccountCold, AccountGas:
+		return true
+	}
+	return false
+}
+
+func (t AccountType) valid() bool {
+	switch t {
+	case AccountUser, AccountDeposit, AccountHot, AccountCold, AccountPayout, AccountFee, AccountGas:
+		return true
+	}
+	return false
+}
+
+// Asset 以 (链 ID, 代币合约) 标识一种资产，Token 为零地址表示链原生币
+type Asset struct {
+	ChainId uint64
+	Token   common.Address
+}
+
+func (a Asset) String() string {
+	if a.Token == (common.Address{}) {
+		return fmt.Sprintf("%d:native", a.ChainId)
+	}
+	return fmt.Sprintf("%d:%s", a.ChainId, a.Token.Hex())
+}
+
+// Account 账本账户，Owner 对用户账户为用户 ID，对地址类账户为校验和地址，手续费与 gas 账户为空
+type Account struct {
+	Type  AccountType
+	Owner string
+	Asset Asset
+}
+
+func (a Account) String() string {
+	if a.Owner == "" {
+		return fmt.Sprintf("%s@%s", a.Type, a.Asset)
+	}
+	return fmt.Sprintf("%s:%s@%s", a.Type, a.Owner, a.Asset)
+}
+
+func UserAccount(userId string, asset Asset) Account {
+	return Account{Type: AccountUser, Owner: userId, Asset: asset}
+}
+
+// AddressAccount 返回充值、热钱包或冷钱包地址上的资产账户
+func AddressAccount(accountType AccountType, address common.Address, asset Asset) Account {
+	return Account{Type: accountType, Owner: address.Hex(), Asset: asset}
+}
+
+func PayoutAccount(asset Asset) Account {
+	return Account{Type: AccountPayout, Asset: asset}
+}
+
+func FeeAccount(asset Asset) Account {
+	return Account{Type: AccountFee, Asset: asset}
+}
+
+func GasAccount(asset Asset) Account {
+	return Account{Type: AccountGas, Asset: asset}
+}
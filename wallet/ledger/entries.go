@@ -0,0 +1,128 @@
+package ledger
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// DepositEntry 用户充值到账：借记充值地址，贷记用户
+func DepositEntry(userId string, depositAddress common.Address, asset Asset, amount *big.Int, txHash common.Hash, logIndex uint) *Entry {
+	return &Entry{
+		Id:        fmt.Sprintf("%s:%d:%s:%d", EntryDeposit, asset.ChainId, txHash.Hex(), logIndex),
+		Kind:      EntryDeposit,
+		Reference: txHash.Hex(),
+		Postings: []Posting{
+			{Account: AddressAccount(AccountDeposit, depositAddress, asset), Side: Debit, Amount: amount},
+			{Account: UserAccount(userId, asset), Side: Credit, Amount: amount},
+		},
+	}
+}
+
+// WithdrawalEntry 受理用户提现：借记用户 amount，贷记待出款 amount-fee 与手续费收入 fee
+// 受理时不动热钱包，热钱包余额不足不影响受理；转出后由 PayoutEntry 结转
+func WithdrawalEntry(withdrawalId, userId string, asset Asset, amount, fee *big.Int) (*Entry, error) {
+	if amount == nil || fee == nil || fee.Sign() < 0 || fee.Cmp(amount) > 0 {
+		return nil, fmt.Errorf("%w: withdrawal fee %v exceeds amount %v", ErrInvalidEntry, fee, amount)
+	}
+	entry := &Entry{
+		Id:        fmt.Sprintf("%s:%s", EntryWithdrawal, withdrawalId),
+		Kind:      EntryWithdrawal,
+		Reference: withdrawalId,
+		Postings: []Posting{
+			{Account: UserAccount(userId, asset), Side: Debit, Amount: amount},
+		},
+	}
+	if payout := new(big.Int).Sub(amount, fee); payout.Sign() > 0 {
+		entry.Postings = append(entry.Postings, Posting{Account: PayoutAccount(asset), Side: Credit, Amount: payout})
+	}
+	if fee.Sign() > 0 {
+		entry.Postings = append(entry.Postings, Posting{Account: FeeAccount(asset), Side: Credit, Amount: fee})
+	}
+	return entry, nil
+}
+
+// PayoutEntry 提现交易上链后结转：借记待出款，贷记热钱包，amount 为实际转出金额
+func PayoutEntry(withdrawalId string, hotAddress common.Address, asset Asset, amount *big.Int, txHash common.Hash) *Entry {
+	return &Entry{
+		Id:        fmt.Sprintf("%s:%s", EntryPayout, withdrawalId),
+		Kind:      EntryPayout,
+		Reference: txHash.Hex(),
+		Postings: []Posting{
+			{Account: PayoutAccount(asset), Side: Debit, Amount: amount},
+			{Account: AddressAccount(AccountHot, hotAddress, asset), Side: Credit, Amount: amount},
+		},
+	}
+}
+
+// ReversalEntry 冲正一笔已过账的分录：借贷方向互换，Id 为 "reversal:<原分录 Id>"
+// 冲正不受余额限制，如充值回滚前用户已提现，用户账户余额为负，需要人工追回
+func ReversalEntry(original *Entry) *Entry {
+	postings := make([]Posting, len(original.Postings))
+	for i, posting := range original.Postings {
+		side := Credit
+		if posting.Side == Credit {
+			side = Debit
+		}
+		postings[i] = Posting{Account: posting.Account, Side: side, Amount: new(big.Int).Set(posting.Amount)}
+	}
+	return &Entry{
+		Id:        ReversalId(original.Id),
+		Kind:      EntryReversal,
+		Reference: original.Id,
+		Postings:  postings,
+	}
+}
+
+// ReversalId 冲正 id 对应分录的分录 Id
+func ReversalId(id string) string {
+	return fmt.Sprintf("%s:%s", EntryReversal, id)
+}
+
+// SweepEntry 充值地址归集到热钱包：借记热钱包，贷记充值地址
+func SweepEntry(depositAddress, hotAddress common.Address, asset Asset, amount *big.Int, txHash common.Hash) *Entry {
+	return transferEntry(EntrySweep, txHash, AddressAccount(AccountDeposit, depositAddress, asset), AddressAccount(AccountHot, hotAddress, asset), amount)
+}
+
+// GasTopUpEntry 热钱包向充值地址补充原生币用于支付归集 gas
+func GasTopUpEntry(hotAddress, depositAddress common.Address, chainId uint64, amount *big.Int, txHash common.Hash) *Entry {
+	native := Asset{ChainId: chainId}
+	return transferEntry(EntryGasTopUp, txHash, AddressAccount(AccountHot, hotAddress, native), AddressAccount(AccountDeposit, depositAddress, native), amount)
+}
+
+// RebalanceEntry 冷热钱包之间调拨，from 与 to 需为热钱包或冷钱包账户
+func RebalanceEntry(from, to Account, amount *big.Int, txHash common.Hash) (*Entry, error) {
+	for _, account := range []Account{from, to} {
+		if account.Type != AccountHot && account.Type != AccountCold {
+			return nil, fmt.Errorf("%w: rebalance account %s is not hot or cold", ErrInvalidEntry, account)
+		}
+	}
+	return transferEntry(EntryRebalance, txHash, from, to, amount), nil
+}
+
+// NetworkFeeEntry 链上交易实际消耗的 gas（gasUsed * effectiveGasPrice），由发送地址承担
+func NetworkFeeEntry(payer Account, gasCost *big.Int, txHash common.Hash) *Entry {
+	gas := GasAccount(Asset{ChainId: payer.Asset.ChainId})
+	payer.Asset = gas.Asset
+	return &Entry{
+		Id:        fmt.Sprintf("%s:%d:%s", EntryNetworkFee, payer.Asset.ChainId, txHash.Hex()),
+		Kind:      EntryNetworkFee,
+		Reference: txHash.Hex(),
+		Postings: []Posting{
+			{Account: gas, Side: Debit, Amount: gasCost},
+			{Account: payer, Side: Credit, Amount: gasCost},
+		},
+	}
+}
+
+func transferEntry(kind EntryKind, txHash common.Hash, from, to Account, amount *big.Int) *Entry {
+	return &Entry{
+		Id:        fmt.Sprintf("%s:%d:%s", kind, from.Asset.ChainId, txHash.Hex()),
+		Kind:      kind,
+		Reference: txHash.Hex(),
+		Postings: []Posting{
+			{Account: to, Side: Debit, Amount: amount},
+			{Account: from, Side: Credit, Amount: amount},
+		},
+	}
+}
@@ -0,0 +1,356 @@
+// Package ledger 交易所内部复式记账账本，每笔分录按 (链, 代币) 借贷平衡
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidEntry        = errors.New("invalid ledger entry")
+	ErrUnbalancedEntry     = errors.New("ledger entry debits and credits not balanced")
+	ErrInsufficientBalance = errors.New("insufficient account balance")
+	ErrEntryConflict       = errors.New("ledger entry id already used by a different entry")
+)
+
+// EntryKind 分录业务类型
+type EntryKind string
+
+const (
+	EntryDeposit    EntryKind = "deposit"     // 用户充值到账
+	EntryWithdrawal EntryKind = "withdrawal"  // 用户提现，含提现手续费
+	EntryPayout     EntryKind = "payout"      // 提现从热钱包转出
+	EntrySweep      EntryKind = "sweep"       // 充值地址归集到热钱包
+	EntryGasTopUp   EntryKind = "gas_top_up"  // 热钱包向充值地址补充 gas
+	EntryNetworkFee EntryKind = "network_fee" // 链上交易消耗的 gas
+	EntryRebalance  EntryKind = "rebalance"   // 冷热钱包之间调拨
+	EntryReversal   EntryKind = "reversal"    // 冲正已过账的分录，如充值所在区块被回滚
+)
+
+// Side 记账方向
+type Side string
+
+const (
+	Debit  Side = "debit"
+	Credit Side = "credit"
+)
+
+// Posting 分录中的一行，对一个账户借记或贷记正数金额
+type Posting struct {
+	Account Account
+	Side    Side
+	Amount  *big.Int
+}
+
+// Entry 一笔记账分录，Id 为业务幂等键，如 "deposit:1:<txHash>:<logIndex>"
+type Entry struct {
+	Id        string
+	Kind      EntryKind
+	Reference string // 关联的链上交易哈希或提现单号
+	Postings  []Posting
+	Sequence  uint64
+	CreatedAt time.Time
+}
+
+// Balance 账户在其余额方向上的余额
+type Balance struct {
+	Account Account
+	Amount  *big.Int
+}
+
+// Ledger 内存复式记账账本，分录一经过账不可修改，冲正需要再记一笔反向分录
+// 分录经 PostWith 与业务数据一同持久化，重启后由 Load 重放
+type Ledger struct {
+	syncer   syncState
+	mu       sync.RWMutex
+	entries  []*Entry
+	byId     map[string]*Entry
+	balances map[Account]*big.Int // 借方减贷方的净额
+	history  map[Account][]int    // 账户涉及的分录下标
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{
+		byId:     make(map[string]*Entry),
+		balances: make(map[Account]*big.Int),
+		history:  make(map[Account][]int),
+	}
+}
+
+// Post 校验并过账一笔分录：每种资产借贷相等，且过账后所有账户余额不为负
+// 相同 Id 的相同分录重复过账视为成功（事件重放），内容不同则返回 ErrEntryConflict
+func (l *Ledger) Post(entry *Entry) (*Entry, error) {
+	return l.PostWith(entry, nil)
+}
+
+// PostWith 与 Post 相同，校验通过后先调用 persist 持久化分录，persist 返回错误时分录不落账
+// persist 在账本锁内执行，持久化顺序即过账顺序；重复过账的分录不会再次调用 persist
+func (l *Ledger) PostWith(entry *Entry, persist func(posted *Entry) error) (*Entry, error) {
+	var persistAll func([]*Entry) error
+	if persist != nil {
+		persistAll = func(posted []*Entry) error { return persist(posted[0]) }
+	}
+	posted, err := l.PostAll([]*Entry{entry}, persistAll)
+	if err != nil {
+		return nil, err
+	}
+	return posted[0], nil
+}
+
+// PostAll 按顺序原子地过账多笔分录，任一分录校验失败或 persist 返回错误时全部不落账
+// persist 只收到本次新过账的分录，全部为重复分录时不调用；返回值与 entries 一一对应
+func (l *Ledger) PostAll(entries []*Entry, persist func(posted []*Entry) error) ([]*Entry, error) {
+	return l.post(entries, persist, false)
+}
+
+// post 过账分录，replay 为 true 时重放已持久化的分录，不检查余额
+func (l *Ledger) post(entries []*Entry, persist func(posted []*Entry) error, replay bool) ([]*Entry, error) {
+	for _, entry := range entries {
+		if err := validateEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 先在暂存的净额上计算，全部分录校验通过后再落账，保证原子性
+	result := make([]*Entry, len(entries))
+	staged := make(map[Account]*big.Int)
+	batch := make(map[string]*Entry)
+	var posted []*Entry
+	for i, entry := range entries {
+		existing, ok := l.byId[entry.Id]
+		if !ok {
+			existing, ok = batch[entry.Id]
+		}
+		if ok {
+			if existing.Kind != entry.Kind || !reflect.DeepEqual(existing.Postings, entry.Postings) {
+				return nil, fmt.Errorf("%w: %s", ErrEntryConflict, entry.Id)
+			}
+			result[i] = existing
+			continue
+		}
+
+		deltas := make(map[Account]*big.Int)
+		for _, posting := range entry.Postings {
+			delta, ok := deltas[posting.Account]
+			if !ok {
+				delta = new(big.Int)
+				deltas[posting.Account] = delta
+			}
+			if posting.Side == Debit {
+				delta.Add(delta, posting.Amount)
+			} else {
+				delta.Sub(delta, posting.Amount)
+			}
+		}
+		for account, delta := range deltas {
+			net, ok := staged[account]
+			if !ok {
+				net = l.net(account)
+			}
+			net = new(big.Int).Add(net, delta)
+			// 冲正还原已过账的分录，不受余额限制，余额为负的账户需要人工追回
+			if normalBalance(account, net).Sign() < 0 && entry.Kind != EntryReversal && !replay {
+				return nil, fmt.Errorf("%w: %s", ErrInsufficientBalance, account)
+			}
+			staged[account] = net
+		}
+
+		entryPosted := &Entry{
+			Id:        entry.Id,
+			Kind:      entry.Kind,
+			Reference: entry.Reference,
+			Postings:  copyPostings(entry.Postings),
+			Sequence:  uint64(len(l.entries)+len(posted)) + 1,
+			CreatedAt: entry.CreatedAt,
+		}
+		if entryPosted.CreatedAt.IsZero() {
+			entryPosted.CreatedAt = time.Now()
+		}
+		batch[entryPosted.Id] = entryPosted
+		posted = append(posted, entryPosted)
+		result[i] = entryPosted
+	}
+	if len(posted) == 0 {
+		return result, nil
+	}
+
+	if persist != nil {
+		if err := persist(posted); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range posted {
+		index := len(l.entries)
+		l.entries = append(l.entries, entry)
+		l.byId[entry.Id] = entry
+		seen := make(map[Account]bool, len(entry.Postings))
+		for _, posting := range entry.Postings {
+			if !seen[posting.Account] {
+				seen[posting.Account] = true
+				l.history[posting.Account] = append(l.history[posting.Account], index)
+			}
+		}
+	}
+	for account, net := range staged {
+		l.balances[account] = net
+	}
+	return result, nil
+}
+
+func validateEntry(entry *Entry) error {
+	if entry == nil || entry.Id == "" || entry.Kind == "" {
+		return fmt.Errorf("%w: missing id or kind", ErrInvalidEntry)
+	}
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("%w: %s has less than two postings", ErrInvalidEntry, entry.Id)
+	}
+
+	sums := make(map[Asset]*big.Int)
+	for i, posting := range entry.Postings {
+		if !posting.Account.Type.valid() {
+			return fmt.Errorf("%w: %s posting %d unknown account type %q", ErrInvalidEntry, entry.Id, i, posting.Account.Type)
+		}
+		if posting.Amount == nil || posting.Amount.Sign() <= 0 {
+			return fmt.Errorf("%w: %s posting %d amount must be positive", ErrInvalidEntry, entry.Id, i)
+		}
+		sum, ok := sums[posting.Account.Asset]
+		if !ok {
+			sum = new(big.Int)
+			sums[posting.Account.Asset] = sum
+		}
+		switch posting.Side {
+		case Debit:
+			sum.Add(sum, posting.Amount)
+		case Credit:
+			sum.Sub(sum, posting.Amount)
+		default:
+			return fmt.Errorf("%w: %s posting %d unknown side %q", ErrInvalidEntry, entry.Id, i, posting.Side)
+		}
+	}
+	for asset, sum := range sums {
+		if sum.Sign() != 0 {
+			return fmt.Errorf("%w: %s asset %s off by %v", ErrUnbalancedEntry, entry.Id, asset, sum)
+		}
+	}
+	return nil
+}
+
+func copyPostings(postings []Posting) []Posting {
+	copied := make([]Posting, len(postings))
+	for i, posting := range postings {
+		copied[i] = Posting{Account: posting.Account, Side: posting.Side, Amount: new(big.Int).Set(posting.Amount)}
+	}
+	return copied
+}
+
+func (l *Ledger) net(account Account) *big.Int {
+	if net, ok := l.balances[account]; ok {
+		return net
+	}
+	return new(big.Int)
+}
+
+// normalBalance 将借方净额换算为账户余额方向上的余额
+func normalBalance(account Account, net *big.Int) *big.Int {
+	if account.Type.debitNormal() {
+		return new(big.Int).Set(net)
+	}
+	return new(big.Int).Neg(net)
+}
+
+// Balance 返回账户余额，未发生过业务的账户余额为 0
+func (l *Ledger) Balance(account Account) *big.Int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return normalBalance(account, l.net(account))
+}
+
+// UserBalances 返回用户在各链各代币上的余额
+func (l *Ledger) UserBalances(userId string) []Balance {
+	return l.Balances(func(account Account) bool {
+		return account.Type == AccountUser && account.Owner == userId
+	})
+}
+
+// Balances 返回满足 filter 的账户余额，filter 为 nil 时返回全部账户，结果按账户排序
+func (l *Ledger) Balances(filter func(Account) bool) []Balance {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var balances []Balance
+	for account, net := range l.balances {
+		if filter != nil && !filter(account) {
+			continue
+		}
+		balances = append(balances, Balance{Account: account, Amount: normalBalance(account, net)})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Account.String() < balances[j].Account.String()
+	})
+	return balances
+}
+
+// History 返回账户最近的分录，按过账顺序倒序，limit <= 0 时返回全部
+func (l *Ledger) History(account Account, limit int) []*Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	indexes := l.history[account]
+	if limit <= 0 || limit > len(indexes) {
+		limit = len(indexes)
+	}
+	entries := make([]*Entry, 0, limit)
+	for i := len(indexes) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, l.entries[indexes[i]])
+	}
+	return entries
+}
+
+// Entry 按幂等键查询分录
+func (l *Ledger) Entry(id string) (*Entry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entry, ok := l.byId[id]
+	return entry, ok
+}
+
+// Entries 返回序号大于 afterSequence 的分录，用于持久化与下游同步
+func (l *Ledger) Entries(afterSequence uint64) []*Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if afterSequence >= uint64(len(l.entries)) {
+		return nil
+	}
+	return append([]*Entry(nil), l.entries[afterSequence:]...)
+}
+
+// Verify 检查整本账的试算平衡：每种资产所有账户借方净额之和为 0
+func (l *Ledger) Verify() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	sums := make(map[Asset]*big.Int)
+	for account, net := range l.balances {
+		sum, ok := sums[account.Asset]
+		if !ok {
+			sum = new(big.Int)
+			sums[account.Asset] = sum
+		}
+		sum.Add(sum, net)
+	}
+	for asset, sum := range sums {
+		if sum.Sign() != 0 {
+			return fmt.Errorf("%w: trial balance of %s off by %v", ErrUnbalancedEntry, asset, sum)
+		}
+	}
+	return nil
+}
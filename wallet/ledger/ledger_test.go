@@ -0,0 +1,319 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+)
+
+var (
+	testHot     = common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	testDeposit = common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	testUsdt    = Asset{ChainId: 1, Token: common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")}
+	testEth     = Asset{ChainId: 1}
+)
+
+func mustPost(t *testing.T, l *Ledger, entry *Entry) {
+	t.Helper()
+	if _, err := l.Post(entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLedgerLifecycle(t *testing.T) {
+	l := NewLedger()
+	depositTx := common.HexToHash("0x01")
+
+	mustPost(t, l, DepositEntry("u1", testDeposit, testUsdt, big.NewInt(1000), depositTx, 3))
+	// 日志重放重复入账不改变余额
+	mustPost(t, l, DepositEntry("u1", testDeposit, testUsdt, big.NewInt(1000), depositTx, 3))
+	if _, err := l.Post(DepositEntry("u1", testDeposit, testUsdt, big.NewInt(999), depositTx, 3)); !errors.Is(err, ErrEntryConflict) {
+		t.Errorf("Post() conflicting replay err = %v", err)
+	}
+
+	// 热钱包先归集一笔原生币，用于补充 gas
+	mustPost(t, l, DepositEntry("treasury", testDeposit, testEth, big.NewInt(1e18), common.HexToHash("0x02"), 0))
+	mustPost(t, l, SweepEntry(testDeposit, testHot, testEth, big.NewInt(1e18), common.HexToHash("0x05")))
+	mustPost(t, l, GasTopUpEntry(testHot, testDeposit, 1, big.NewInt(1e15), common.HexToHash("0x03")))
+	mustPost(t, l, NetworkFeeEntry(AddressAccount(AccountHot, testHot, testEth), big.NewInt(21000), common.HexToHash("0x03")))
+
+	sweepTx := common.HexToHash("0x04")
+	mustPost(t, l, SweepEntry(testDeposit, testHot, testUsdt, big.NewInt(1000), sweepTx))
+	mustPost(t, l, NetworkFeeEntry(AddressAccount(AccountDeposit, testDeposit, testEth), big.NewInt(50000), sweepTx))
+
+	withdrawal, err := WithdrawalEntry("w1", "u1", testUsdt, big.NewInt(600), big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustPost(t, l, withdrawal)
+	if got := l.Balance(PayoutAccount(testUsdt)); got.Int64() != 590 {
+		t.Errorf("payout balance = %v, want 590", got)
+	}
+	mustPost(t, l, PayoutEntry("w1", testHot, testUsdt, big.NewInt(590), common.HexToHash("0x06")))
+
+	checks := []struct {
+		account Account
+		want    int64
+	}{
+		{UserAccount("u1", testUsdt), 400},
+		{AddressAccount(AccountDeposit, testDeposit, testUsdt), 0},
+		{AddressAccount(AccountHot, testHot, testUsdt), 410},
+		{PayoutAccount(testUsdt), 0},
+		{FeeAccount(testUsdt), 10},
+		{AddressAccount(AccountDeposit, testDeposit, testEth), 1e15 - 50000},
+		{AddressAccount(AccountHot, testHot, testEth), 1e18 - 1e15 - 21000},
+		{GasAccount(testEth), 71000},
+	}
+	for _, c := range checks {
+		if got := l.Balance(c.account); got.Cmp(big.NewInt(c.want)) != 0 {
+			t.Errorf("Balance(%s) = %v, want %d", c.account, got, c.want)
+		}
+	}
+	if err := l.Verify(); err != nil {
+		t.Error(err)
+	}
+
+	balances := l.UserBalances("u1")
+	if len(balances) != 1 || balances[0].Amount.Int64() != 400 {
+		t.Errorf("UserBalances() = %+v", balances)
+	}
+	history := l.History(UserAccount("u1", testUsdt), 0)
+	if len(history) != 2 || history[0].Kind != EntryWithdrawal || history[1].Kind != EntryDeposit {
+		t.Errorf("History() = %+v", history)
+	}
+	if len(l.Entries(0)) != 9 || len(l.Entries(8)) != 1 {
+		t.Errorf("Entries() returned wrong count")
+	}
+}
+
+func TestLedgerInvariants(t *testing.T) {
+	l := NewLedger()
+	mustPost(t, l, DepositEntry("u1", testDeposit, testUsdt, big.NewInt(100), common.HexToHash("0x01"), 0))
+
+	unbalanced := &Entry{Id: "bad", Kind: EntryDeposit, Postings: []Posting{
+		{Account: AddressAccount(AccountDeposit, testDeposit, testUsdt), Side: Debit, Amount: big.NewInt(100)},
+		{Account: UserAccount("u1", testUsdt), Side: Credit, Amount: big.NewInt(99)},
+	}}
+	if _, err := l.Post(unbalanced); !errors.Is(err, ErrUnbalancedEntry) {
+		t.Errorf("Post() unbalanced err = %v", err)
+	}
+
+	// 不同资产之间不能相互抵平
+	crossAsset := &Entry{Id: "cross", Kind: EntryDeposit, Postings: []Posting{
+		{Account: AddressAccount(AccountDeposit, testDeposit, testUsdt), Side: Debit, Amount: big.NewInt(100)},
+		{Account: UserAccount("u1", testEth), Side: Credit, Amount: big.NewInt(100)},
+	}}
+	if _, err := l.Post(crossAsset); !errors.Is(err, ErrUnbalancedEntry) {
+		t.Errorf("Post() cross asset err = %v", err)
+	}
+
+	// 提现超过用户余额时整笔分录不落账
+	withdrawal, _ := WithdrawalEntry("w1", "u1", testUsdt, big.NewInt(150), big.NewInt(0))
+	if _, err := l.Post(withdrawal); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("Post() overdraft err = %v", err)
+	}
+	if got := l.Balance(UserAccount("u1", testUsdt)); got.Int64() != 100 {
+		t.Errorf("user balance changed by rejected entry: %v", got)
+	}
+	if _, err := WithdrawalEntry("w2", "u1", testUsdt, big.NewInt(10), big.NewInt(11)); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("WithdrawalEntry() fee > amount err = %v", err)
+	}
+	if _, err := RebalanceEntry(UserAccount("u1", testUsdt), AddressAccount(AccountCold, testHot, testUsdt), big.NewInt(1), common.Hash{}); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("RebalanceEntry() from user err = %v", err)
+	}
+	if _, err := l.Post(&Entry{Id: "neg", Kind: EntryDeposit, Postings: []Posting{
+		{Account: UserAccount("u1", testUsdt), Side: Debit, Amount: big.NewInt(-1)},
+		{Account: UserAccount("u2", testUsdt), Side: Credit, Amount: big.NewInt(-1)},
+	}}); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("Post() negative amount err = %v", err)
+	}
+}
+
+func TestLedgerConcurrentWithdrawals(t *testing.T) {
+	l := NewLedger()
+	mustPost(t, l, DepositEntry("u1", testDeposit, testUsdt, big.NewInt(100), common.HexToHash("0x01"), 0))
+	mustPost(t, l, SweepEntry(testDeposit, testHot, testUsdt, big.NewInt(100), common.HexToHash("0x02")))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			entry, _ := WithdrawalEntry(string(rune('a'+i)), "u1", testUsdt, big.NewInt(10), big.NewInt(1))
+			if _, err := l.Post(entry); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if succeeded != 10 {
+		t.Errorf("succeeded withdrawals = %d, want 10", succeeded)
+	}
+	if got := l.Balance(UserAccount("u1", testUsdt)); got.Sign() != 0 {
+		t.Errorf("user balance = %v, want 0", got)
+	}
+	if err := l.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLedgerLoad(t *testing.T) {
+	repo, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	ctx := context.Background()
+	persist := func(posted *Entry) error { return SaveEntry(ctx, repo, posted) }
+
+	l := NewLedger()
+	if _, err := l.PostWith(DepositEntry("u1", testDeposit, testUsdt, big.NewInt(1000), common.HexToHash("0x01"), 3), persist); err != nil {
+		t.Fatal(err)
+	}
+	withdrawal, _ := WithdrawalEntry("w1", "u1", testUsdt, big.NewInt(600), big.NewInt(10))
+	if _, err := l.PostWith(withdrawal, persist); err != nil {
+		t.Fatal(err)
+	}
+	// 持久化失败的分录不落账
+	failed, _ := WithdrawalEntry("w2", "u1", testUsdt, big.NewInt(400), big.NewInt(10))
+	errWrite := errors.New("write failed")
+	if _, err := l.PostWith(failed, func(*Entry) error { return errWrite }); !errors.Is(err, errWrite) {
+		t.Fatalf("PostWith() err = %v", err)
+	}
+	if _, ok := l.Entry(failed.Id); ok {
+		t.Error("entry posted after persist failure")
+	}
+
+	loaded, err := Load(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Balance(UserAccount("u1", testUsdt)); got.Int64() != 400 {
+		t.Errorf("reloaded user balance = %v, want 400", got)
+	}
+	if got := loaded.Balance(PayoutAccount(testUsdt)); got.Int64() != 590 {
+		t.Errorf("reloaded payout balance = %v, want 590", got)
+	}
+	if entries := loaded.Entries(0); len(entries) != 2 || entries[1].Id != withdrawal.Id {
+		t.Errorf("reloaded entries = %+v", entries)
+	}
+
+	// 另一个进程写入的分录在 Sync 后可见，本账本自己写入的分录读回时跳过
+	if _, err := l.PostWith(DepositEntry("u1", testDeposit, testUsdt, big.NewInt(50), common.HexToHash("0x02"), 0), persist); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Sync(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Sync(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := loaded.Balance(UserAccount("u1", testUsdt)), l.Balance(UserAccount("u1", testUsdt)); a.Int64() != 450 || b.Int64() != 450 {
+		t.Errorf("balances after sync = %v, %v, want 450", a, b)
+	}
+}
+
+func TestLedgerReversal(t *testing.T) {
+	l := NewLedger()
+	deposit := DepositEntry("u1", testDeposit, testUsdt, big.NewInt(100), common.HexToHash("0x01"), 0)
+	mustPost(t, l, deposit)
+	withdrawal, _ := WithdrawalEntry("w1", "u1", testUsdt, big.NewInt(80), big.NewInt(0))
+	mustPost(t, l, withdrawal)
+
+	// 一批分录中任一笔失败时全部不落账
+	overdraft, _ := WithdrawalEntry("w2", "u1", testUsdt, big.NewInt(30), big.NewInt(0))
+	if _, err := l.PostAll([]*Entry{DepositEntry("u2", testDeposit, testUsdt, big.NewInt(5), common.HexToHash("0x02"), 0), overdraft}, nil); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("PostAll() err = %v", err)
+	}
+	if got := l.Balance(UserAccount("u2", testUsdt)); got.Sign() != 0 {
+		t.Errorf("u2 balance after failed batch = %v", got)
+	}
+
+	// 充值回滚前用户已提现，冲正后用户余额为负
+	original, _ := l.Entry(deposit.Id)
+	reversal := ReversalEntry(original)
+	if reversal.Id != "reversal:"+deposit.Id || reversal.Reference != deposit.Id {
+		t.Errorf("ReversalEntry() = %+v", reversal)
+	}
+	mustPost(t, l, reversal)
+	if got := l.Balance(UserAccount("u1", testUsdt)); got.Int64() != -80 {
+		t.Errorf("user balance after reversal = %v, want -80", got)
+	}
+	if got := l.Balance(AddressAccount(AccountDeposit, testDeposit, testUsdt)); got.Sign() != 0 {
+		t.Errorf("deposit address balance after reversal = %v", got)
+	}
+	if err := l.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+// gapStore 只返回 visible 中的分录，模拟并发事务提交顺序与序号不一致
+type gapStore struct {
+	database.LedgerStore
+	entries []database.LedgerEntry
+	visible map[uint64]bool
+}
+
+func (s *gapStore) add(t *testing.T, seq uint64, entry *Entry) {
+	t.Helper()
+	postings, err := json.Marshal(entry.Postings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.entries = append(s.entries, database.LedgerEntry{Sequence: seq, Id: entry.Id, Kind: string(entry.Kind), Postings: string(postings)})
+}
+
+func (s *gapStore) ListLedgerEntries(ctx context.Context, afterSequence uint64, limit int) ([]database.LedgerEntry, error) {
+	var result []database.LedgerEntry
+	for _, entry := range s.entries {
+		if entry.Sequence > afterSequence && s.visible[entry.Sequence] {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+func TestLedgerSyncGaps(t *testing.T) {
+	ctx := context.Background()
+	store := &gapStore{visible: map[uint64]bool{1: true, 3: true, 4: true, 5: true}}
+	withdrawal, _ := WithdrawalEntry("w1", "u1", testUsdt, big.NewInt(30), big.NewInt(0))
+	store.add(t, 1, withdrawal)
+	store.add(t, 2, DepositEntry("u1", testDeposit, testUsdt, big.NewInt(50), common.HexToHash("0x02"), 0))
+	store.add(t, 3, DepositEntry("u1", testDeposit, testUsdt, big.NewInt(100), common.HexToHash("0x01"), 0))
+	store.entries = append(store.entries, database.LedgerEntry{Sequence: 4, Id: "broken", Kind: string(EntryDeposit), Postings: "{"})
+	store.add(t, 5, DepositEntry("u1", testDeposit, testUsdt, big.NewInt(7), common.HexToHash("0x03"), 0))
+
+	// 按存储的内容重放，先于充值可见的提现不因余额不足失败；无法解析的分录跳过，不阻塞之后的分录
+	l, err := Load(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Balance(UserAccount("u1", testUsdt)); got.Int64() != 77 {
+		t.Fatalf("balance = %v, want 77", got)
+	}
+
+	// 晚提交的较小序号在之后的同步中读到
+	store.visible[2] = true
+	if err := l.Sync(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Balance(UserAccount("u1", testUsdt)); got.Int64() != 127 {
+		t.Fatalf("balance after late commit = %v, want 127", got)
+	}
+	if _, ok := l.Entry("broken"); ok {
+		t.Error("broken entry posted")
+	}
+}
@@ -0,0 +1,121 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+)
+
+const (
+	// loadBatch 重建账本时每次读取的分录数
+	loadBatch = 1000
+	// syncGapTimeout 未读到的序号超过该时间仍不可见时视为回滚事务占用的序号，不再重新读取
+	syncGapTimeout = 5 * time.Minute
+)
+
+// SaveEntry 写入已过账的分录，应在 PostWith 的 persist 中与业务数据在同一事务内调用
+func SaveEntry(ctx context.Context, repo database.LedgerStore, entry *Entry) error {
+	postings, err := json.Marshal(entry.Postings)
+	if err != nil {
+		return err
+	}
+	return repo.AddLedgerEntry(ctx, &database.LedgerEntry{
+		Id:        entry.Id,
+		Kind:      string(entry.Kind),
+		Reference: entry.Reference,
+		Postings:  string(postings),
+		CreatedAt: entry.CreatedAt,
+	})
+}
+
+// Load 按持久化顺序重放 repo 中的分录，重建进程重启前的账本
+func Load(ctx context.Context, repo database.LedgerStore) (*Ledger, error) {
+	l := NewLedger()
+	if err := l.Sync(ctx, repo); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// syncState Sync 的读取进度
+// 并发提交的事务可能让较小的序号晚于较大的序号可见，未读到的序号记为空洞，在 syncGapTimeout 内每次同步都重新读取
+type syncState struct {
+	mu      sync.Mutex
+	synced  uint64               // 不大于该序号的分录都已读取，或空洞已超时
+	highest uint64               // 已读到的最大序号
+	gaps    map[uint64]time.Time // 大于 synced 且尚未读到的序号，值为发现的时间
+	skipped map[uint64]bool      // 无法重放而跳过的分录
+}
+
+// Sync 过账 repo 中尚未读取的分录，用于读取共用存储的其他进程（如单独运行的扫链）写入的分录
+// 本账本经 PostWith 写入的分录会被读回，按重复过账跳过；分录按持久化的内容重放，不检查余额
+// 无法解析或与已过账分录冲突的分录记录日志后跳过，不影响之后的分录
+func (l *Ledger) Sync(ctx context.Context, repo database.LedgerStore) error {
+	state := &l.syncer
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.gaps == nil {
+		state.gaps = make(map[uint64]time.Time)
+		state.skipped = make(map[uint64]bool)
+	}
+
+	now := time.Now()
+	after := state.synced
+	for {
+		records, err := repo.ListLedgerEntries(ctx, after, loadBatch)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			for seq := max(after, state.highest) + 1; seq < record.Sequence; seq++ {
+				state.gaps[seq] = now
+			}
+			delete(state.gaps, record.Sequence)
+			state.highest = max(state.highest, record.Sequence)
+			after = record.Sequence
+			if state.skipped[record.Sequence] {
+				continue
+			}
+			if err := l.replay(record); err != nil {
+				state.skipped[record.Sequence] = true
+				log.Printf("ledger: skip entry %d (%s): %v", record.Sequence, record.Id, err)
+			}
+		}
+		if len(records) < loadBatch {
+			break
+		}
+	}
+
+	for state.synced < state.highest {
+		next := state.synced + 1
+		if seen, ok := state.gaps[next]; ok {
+			if now.Sub(seen) < syncGapTimeout {
+				break
+			}
+			delete(state.gaps, next)
+		}
+		delete(state.skipped, next)
+		state.synced = next
+	}
+	return nil
+}
+
+// replay 按持久化的内容过账一条分录
+func (l *Ledger) replay(record database.LedgerEntry) error {
+	entry := &Entry{
+		Id:        record.Id,
+		Kind:      EntryKind(record.Kind),
+		Reference: record.Reference,
+		CreatedAt: record.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(record.Postings), &entry.Postings); err != nil {
+		return fmt.Errorf("decode postings: %w", err)
+	}
+	_, err := l.post([]*Entry{entry}, nil, true)
+	return err
+}
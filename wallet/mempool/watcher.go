@@ -0,0 +1,367 @@
+// Package mempool 监听节点交易池中转入充值地址的交易，在上链前发出未确认充值事件
+//
+// 未确认充值只保存在内存中，不入账也不写入消息队列发件箱，配置了 webhook 时写入 webhook 投递：上链后由扫链发出相同 tx_hash 的 deposit.detected，
+// 超时未上链、被移出交易池或执行失败时发出 deposit.pending_dropped
+package mempool
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/webhook"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+const (
+	// evictAfterMisses 连续多次查询不到交易才认为已被移出交易池，避免负载均衡后的节点交易池不一致导致误判
+	evictAfterMisses = 3
+	// checkBatch 每轮检查的未确认充值上限，按上次检查的时间轮转，跟踪较多时分多轮检查完
+	checkBatch = 256
+	// checkConcurrency 检查时并发查询节点的上限
+	checkConcurrency = 8
+)
+
+// Config 单条链的交易池监听配置
+type Config struct {
+	ChainId       uint64
+	Family        string           // 充值地址所属链族，默认 "evm"
+	Tokens        []common.Address // 匹配 transfer 与 transferFrom 调用的代币合约，为空时只匹配原生币转账
+	Expiry        time.Duration    // 未确认充值的最长等待时间，默认 1h
+	CheckInterval time.Duration    // 检查未确认充值是否已上链并刷新充值地址的间隔，默认 15s
+	MaxPending    int              // 同时跟踪的未确认充值上限，超出后忽略新的交易，默认 10000
+}
+
+// Options 监听器依赖，Webhooks 可为空
+type Options struct {
+	Client     node.EthClient
+	Repository database.Repository
+	Events     *events.Bus
+	Webhooks   *webhook.Notifier
+}
+
+// Watcher 单条链的交易池监听器
+type Watcher struct {
+	opts      Options
+	config    Config
+	signer    types.Signer
+	tokens    map[common.Address]bool
+	addresses map[common.Address]string
+	pending   map[common.Hash]*pending
+}
+
+// pending 跟踪中的未确认充值
+type pending struct {
+	deposit events.Deposit
+	seen    time.Time
+	checked time.Time // 上次检查的时间
+	misses  int       // 连续查询不到交易的次数
+}
+
+// checkItem 交给检查协程的未确认充值
+type checkItem struct {
+	hash common.Hash
+	seen time.Time
+}
+
+// checkResult 单条未确认充值的检查结果，由主循环应用
+type checkResult struct {
+	hash    common.Hash
+	receipt *types.Receipt // 已上链时的回执
+	expired bool           // 未上链且已超时
+	inPool  bool           // 未上链时交易仍在交易池中
+	err     error
+}
+
+func NewWatcher(opts Options, config Config) (*Watcher, error) {
+	if opts.Client == nil || opts.Repository == nil || opts.Events == nil {
+		return nil, errors.New("mempool: client, repository and events are required")
+	}
+	if config.ChainId == 0 {
+		return nil, errors.New("mempool: chain id is required")
+	}
+	if config.Family == "" {
+		config.Family = "evm"
+	}
+	if config.Expiry <= 0 {
+		config.Expiry = time.Hour
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = 15 * time.Second
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = 10000
+	}
+	tokens := make(map[common.Address]bool, len(config.Tokens))
+	for _, token := range config.Tokens {
+		tokens[token] = true
+	}
+	return &Watcher{
+		opts:    opts,
+		config:  config,
+		signer:  types.LatestSignerForChainID(new(big.Int).SetUint64(config.ChainId)),
+		tokens:  tokens,
+		pending: make(map[common.Hash]*pending),
+	}, nil
+}
+
+// Run 监听交易池直到 ctx 取消或事件总线关闭；节点不支持交易池订阅（如 HTTP 节点）时返回错误
+func (w *Watcher) Run(ctx context.Context) error {
+	if err := w.loadAddresses(ctx); err != nil {
+		return err
+	}
+	txs := make(chan *types.Transaction, 256)
+	sub, err := w.opts.Client.SubscribePendingTransactions(ctx, txs)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	detected, err := w.opts.Events.Subscribe(0, 0, events.DepositDetected)
+	if err != nil {
+		return err
+	}
+	defer func() { detected.Close() }()
+
+	ticker := time.NewTicker(w.config.CheckInterval)
+	defer ticker.Stop()
+	// 检查在单独的协程中查询节点，主循环继续接收交易；同一时间只有一轮检查
+	checks := make(chan []checkResult, 1)
+	checking := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case tx := <-txs:
+			w.handle(ctx, tx)
+		case event, ok := <-detected.Events():
+			if ok {
+				w.mined(event)
+				continue
+			}
+			if errors.Is(detected.Err(), events.ErrBusClosed) {
+				return nil
+			}
+			// 消费过慢被总线断开，期间上链的充值在下一轮检查中通过回执确认
+			if detected, err = w.opts.Events.Subscribe(0, 0, events.DepositDetected); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := w.loadAddresses(ctx); err != nil {
+				log.Printf("mempool: chain %d: load deposit addresses: %v", w.config.ChainId, err)
+			}
+			if !checking {
+				if batch := w.checkBatch(); len(batch) > 0 {
+					checking = true
+					go func() { checks <- w.check(ctx, batch) }()
+				}
+			}
+		case results := <-checks:
+			checking = false
+			w.apply(ctx, results)
+		}
+	}
+}
+
+// loadAddresses 加载充值地址到用户 ID 的映射，定期刷新以包含新分配的地址
+func (w *Watcher) loadAddresses(ctx context.Context) error {
+	records, err := w.opts.Repository.ListAddressesByKind(ctx, w.config.Family, database.AddressKindDeposit)
+	if err != nil {
+		return err
+	}
+	addresses := make(map[common.Address]string, len(records))
+	for _, record := range records {
+		addresses[common.HexToAddress(record.Address)] = record.UserId
+	}
+	w.addresses = addresses
+	return nil
+}
+
+// handle 匹配新进入交易池的交易，发出未确认充值事件
+func (w *Watcher) handle(ctx context.Context, tx *types.Transaction) {
+	hash := tx.Hash()
+	if _, ok := w.pending[hash]; ok || tx.To() == nil {
+		return
+	}
+	deposit := w.match(tx)
+	if deposit == nil {
+		return
+	}
+	if len(w.pending) >= w.config.MaxPending {
+		log.Printf("mempool: chain %d: %d pending deposits tracked, ignoring %s", w.config.ChainId, len(w.pending), hash.Hex())
+		return
+	}
+	sender, err := types.Sender(w.signer, tx)
+	if err != nil {
+		// 签名无效或属于其他链
+		return
+	}
+	if deposit.From == "" {
+		deposit.From = sender.Hex()
+	}
+	deposit.TxHash = hash.Hex()
+	w.pending[hash] = &pending{deposit: *deposit, seen: time.Now()}
+	w.publish(ctx, events.DepositPending, *deposit)
+}
+
+// match 匹配转入充值地址的原生币转账，以及监听代币的 transfer、transferFrom 调用
+// 调用数据只说明交易的意图，实际到账以上链后的 Transfer 事件为准
+func (w *Watcher) match(tx *types.Transaction) *events.Deposit {
+	to := *tx.To()
+	if userId, ok := w.addresses[to]; ok {
+		if tx.Value().Sign() <= 0 {
+			return nil
+		}
+		return &events.Deposit{UserId: userId, To: to.Hex(), Amount: tx.Value().String()}
+	}
+	if !w.tokens[to] {
+		return nil
+	}
+
+	var from string
+	recipient, amount, err := WalletEthereum.DecodeErc20Data(tx.Data())
+	if err != nil {
+		var owner common.Address
+		if owner, recipient, amount, err = WalletEthereum.DecodeErc20TransferFromData(tx.Data()); err != nil {
+			return nil
+		}
+		from = owner.Hex()
+	}
+	userId, ok := w.addresses[recipient]
+	if !ok || amount.Sign() == 0 {
+		return nil
+	}
+	return &events.Deposit{UserId: userId, Token: to.Hex(), From: from, To: recipient.Hex(), Amount: amount.String()}
+}
+
+// mined 扫链发现充值后不再跟踪，deposit.detected 取代之前的未确认充值事件
+func (w *Watcher) mined(event events.Event) {
+	if event.ChainId != w.config.ChainId || event.Deposit == nil {
+		return
+	}
+	delete(w.pending, common.HexToHash(event.Deposit.TxHash))
+}
+
+// checkBatch 取出最久未检查的至多 checkBatch 条未确认充值，并记为已检查
+func (w *Watcher) checkBatch() []checkItem {
+	hashes := make([]common.Hash, 0, len(w.pending))
+	for hash := range w.pending {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return w.pending[hashes[i]].checked.Before(w.pending[hashes[j]].checked) })
+	now := time.Now()
+	batch := make([]checkItem, 0, min(len(hashes), checkBatch))
+	for _, hash := range hashes[:min(len(hashes), checkBatch)] {
+		p := w.pending[hash]
+		p.checked = now
+		batch = append(batch, checkItem{hash: hash, seen: p.seen})
+	}
+	return batch
+}
+
+// check 并发查询回执确认未确认充值是否已上链，未上链且未超时的再查询是否仍在交易池中
+// 只读取 batch，不访问 w.pending，结果由主循环通过 apply 应用
+func (w *Watcher) check(ctx context.Context, batch []checkItem) []checkResult {
+	results := make([]checkResult, len(batch))
+	slots := make(chan struct{}, checkConcurrency)
+	var wg sync.WaitGroup
+	for i, item := range batch {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			result := checkResult{hash: item.hash}
+			receipt, err := w.opts.Client.TxReceiptByHash(ctx, item.hash)
+			switch {
+			case err == nil:
+				result.receipt = receipt
+			case !errors.Is(err, ethereum.NotFound):
+				result.err = err
+			case time.Since(item.seen) > w.config.Expiry:
+				result.expired = true
+			default:
+				_, err := w.opts.Client.TxByHash(ctx, item.hash)
+				switch {
+				case err == nil:
+					result.inPool = true
+				case !errors.Is(err, ethereum.NotFound):
+					result.err = err
+				}
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// apply 应用检查结果，超时、执行失败或已不在交易池中的发出失效事件；检查期间已被扫链确认的忽略
+func (w *Watcher) apply(ctx context.Context, results []checkResult) {
+	var failed int
+	var lastErr error
+	for _, result := range results {
+		p, ok := w.pending[result.hash]
+		if !ok {
+			continue
+		}
+		switch {
+		case result.err != nil:
+			failed, lastErr = failed+1, result.err
+		case result.receipt != nil:
+			delete(w.pending, result.hash)
+			if result.receipt.Status != types.ReceiptStatusSuccessful {
+				w.drop(ctx, p, events.DropReasonReverted)
+			}
+			// 执行成功的由扫链发出 deposit.detected
+		case result.expired:
+			delete(w.pending, result.hash)
+			w.drop(ctx, p, events.DropReasonExpired)
+		case result.inPool:
+			p.misses = 0
+		default:
+			p.misses++
+			if p.misses >= evictAfterMisses {
+				delete(w.pending, result.hash)
+				w.drop(ctx, p, events.DropReasonEvicted)
+			}
+		}
+	}
+	if failed > 0 && ctx.Err() == nil {
+		log.Printf("mempool: chain %d: check failed for %d of %d pending deposits: %v", w.config.ChainId, failed, len(results), lastErr)
+	}
+}
+
+func (w *Watcher) drop(ctx context.Context, p *pending, reason string) {
+	deposit := p.deposit
+	deposit.Reason = reason
+	w.publish(ctx, events.DepositPendingDropped, deposit)
+}
+
+// publish 先写入 webhook 投递再发布到总线，两者使用同一事件 ID
+func (w *Watcher) publish(ctx context.Context, eventType events.Type, deposit events.Deposit) {
+	event := events.Event{Type: eventType, ChainId: w.config.ChainId, Deposit: &deposit}
+	if w.opts.Webhooks != nil {
+		added, err := w.opts.Webhooks.AddEvent(ctx, w.opts.Repository, event)
+		if err != nil {
+			log.Printf("mempool: chain %d: add webhook event %s: %v", w.config.ChainId, eventType, err)
+		} else {
+			event = added
+			w.opts.Webhooks.Notify()
+		}
+	}
+	if _, err := w.opts.Events.Publish(event); err != nil {
+		log.Printf("mempool: publish %s: %v", eventType, err)
+	}
+}
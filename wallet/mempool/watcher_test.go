@@ -0,0 +1,238 @@
+package mempool
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+const chainId = 11155111
+
+var (
+	depositAddress = common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	usdt           = common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+)
+
+// fakeMempool 交易池与已上链交易的回执，pool 中的交易可通过哈希查询
+type fakeMempool struct {
+	node.EthClient
+	mu       sync.Mutex
+	ch       chan<- *types.Transaction
+	pool     map[common.Hash]*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+}
+
+func (f *fakeMempool) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ch = ch
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+func (f *fakeMempool) TxReceiptByHash(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if receipt, ok := f.receipts[hash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (f *fakeMempool) TxByHash(ctx context.Context, hash common.Hash) (*types.Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if tx, ok := f.pool[hash]; ok {
+		return tx, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+// broadcast 交易进入交易池并推送给订阅方
+func (f *fakeMempool) broadcast(t *testing.T, tx *types.Transaction) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.mu.Lock()
+		ch := f.ch
+		f.pool[tx.Hash()] = tx
+		f.mu.Unlock()
+		if ch != nil {
+			ch <- tx
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watcher did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (f *fakeMempool) evict(hash common.Hash) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.pool, hash)
+}
+
+func (f *fakeMempool) mine(hash common.Hash, status uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.receipts[hash] = &types.Receipt{Status: status}
+}
+
+func signTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, to common.Address, value int64, data []byte) *types.Transaction {
+	t.Helper()
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(chainId)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(chainId),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       60000,
+		To:        &to,
+		Value:     big.NewInt(value),
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func nextEvent(t *testing.T, sub *events.Subscription) events.Event {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected event")
+		return events.Event{}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	err = store.CreateAddress(ctx, &database.Address{Address: depositAddress.Hex(), Family: "evm", Kind: database.AddressKindDeposit, UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeMempool{pool: make(map[common.Hash]*types.Transaction), receipts: make(map[common.Hash]*types.Receipt)}
+	bus := events.NewBus(100)
+	sub, err := bus.Subscribe(0, 100, events.DepositPending, events.DepositPendingDropped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWatcher(Options{Client: client, Repository: store, Events: bus}, Config{
+		ChainId:       chainId,
+		Tokens:        []common.Address{usdt},
+		CheckInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	transfer, _ := WalletEthereum.BuildErc20Data(depositAddress, big.NewInt(500))
+	native := signTx(t, key, 0, depositAddress, 1000, nil)
+	token := signTx(t, key, 1, usdt, 0, transfer)
+	evicted := signTx(t, key, 2, depositAddress, 2000, nil)
+
+	client.broadcast(t, signTx(t, key, 3, common.HexToAddress("0x01"), 1000, nil))
+	client.broadcast(t, native)
+	client.broadcast(t, token)
+	client.broadcast(t, evicted)
+
+	event := nextEvent(t, sub)
+	if event.Type != events.DepositPending || event.Deposit.TxHash != native.Hash().Hex() ||
+		event.Deposit.From != sender.Hex() || event.Deposit.Amount != "1000" || event.Deposit.UserId != "u1" {
+		t.Fatalf("native pending: %+v %+v", event, event.Deposit)
+	}
+	event = nextEvent(t, sub)
+	if event.Deposit.TxHash != token.Hash().Hex() || event.Deposit.Token != usdt.Hex() || event.Deposit.Amount != "500" {
+		t.Fatalf("token pending: %+v", event.Deposit)
+	}
+	event = nextEvent(t, sub)
+	if event.Deposit.TxHash != evicted.Hash().Hex() {
+		t.Fatalf("pending: %+v", event.Deposit)
+	}
+
+	// 扫链发现的充值不再跟踪，即使之后查询不到交易也不会发出失效事件
+	if _, err := bus.Publish(events.Event{Type: events.DepositDetected, ChainId: chainId, Deposit: &events.Deposit{TxHash: native.Hash().Hex()}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	client.evict(native.Hash())
+	client.mine(token.Hash(), types.ReceiptStatusFailed)
+	client.evict(evicted.Hash())
+
+	reasons := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		event := nextEvent(t, sub)
+		if event.Type != events.DepositPendingDropped {
+			t.Fatalf("unexpected event %+v", event)
+		}
+		reasons[event.Deposit.TxHash] = event.Deposit.Reason
+	}
+	if reasons[token.Hash().Hex()] != events.DropReasonReverted || reasons[evicted.Hash().Hex()] != events.DropReasonEvicted {
+		t.Fatalf("drop reasons: %v", reasons)
+	}
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %+v %+v", event, event.Deposit)
+	default:
+	}
+}
+
+func TestWatcherCheckBatch(t *testing.T) {
+	w := &Watcher{pending: make(map[common.Hash]*pending)}
+	for i := range checkBatch + 10 {
+		w.pending[common.BigToHash(big.NewInt(int64(i)))] = &pending{seen: time.Now()}
+	}
+	// 每轮至多检查 checkBatch 条，下一轮先检查上一轮未检查的
+	first := w.checkBatch()
+	if len(first) != checkBatch {
+		t.Fatalf("first batch %d, want %d", len(first), checkBatch)
+	}
+	checked := make(map[common.Hash]bool, len(first))
+	for _, item := range first {
+		checked[item.hash] = true
+	}
+	second := w.checkBatch()
+	for _, item := range second[:10] {
+		if checked[item.hash] {
+			t.Fatalf("%s checked again before the rest of the pending deposits", item.hash)
+		}
+	}
+}
@@ -2,7 +2,8 @@ package node
 
 import (
 	"context"
-	"github.com/0xweb-3/EthCEXWallet/global"
+	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -43,6 +44,23 @@ type EthClient interface {
 	SuggestGasPrice(ctx context.Context) (*big.Int, error)
 	// 获取当前网络上建议的 优先费
 	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	// BlobBaseFee 获取下一个区块的 blob 基础费（EIP-4844）
+	BlobBaseFee(ctx context.Context) (*big.Int, error)
+	// CodeAt 获取地址在指定区块的合约代码，EOA 返回空
+	CodeAt(ctx context.Context, address common.Address, blockNumber *big.Int) ([]byte, error)
+	// CallContract 执行只读合约调用（eth_call），如 balanceOf/balanceOfBatch
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	// BatchCallContract 在一次批量请求中对同一区块执行多个 eth_call，结果与 msgs 一一对应
+	BatchCallContract(ctx context.Context, msgs []ethereum.CallMsg, blockNumber *big.Int) ([][]byte, error)
+	// BalancesAt 在一次批量请求中查询多个地址在同一区块的原生币余额
+	BalancesAt(ctx context.Context, addresses []common.Address, blockNumber *big.Int) ([]*big.Int, error)
+
+	// SubscribeNewHead 订阅新区块头，断线后自动重新订阅并补齐期间漏掉的区块，HTTP 节点退化为轮询
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	// SubscribeFilterLogs 订阅匹配 q 的日志，断线后自动重新订阅并补齐期间漏掉的日志，HTTP 节点退化为轮询
+	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+	// SubscribePendingTransactions 订阅进入交易池的交易，只支持 WebSocket 与 IPC 节点
+	SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error)
 }
 
 type RPC interface {
@@ -51,8 +69,14 @@ type RPC interface {
 	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
 }
 
+// SubscriptionRPC 支持 eth_subscribe 的连接，HTTP 连接返回 rpc.ErrNotificationsUnsupported
+type SubscriptionRPC interface {
+	EthSubscribe(ctx context.Context, channel any, args ...any) (ethereum.Subscription, error)
+}
+
 type clnt struct {
-	rpc RPC
+	rpc  RPC
+	opts *options
 }
 
 func toBlockNumArg(number *big.Int) string {
@@ -65,12 +89,29 @@ func toBlockNumArg(number *big.Int) string {
 	return rpc.BlockNumber(number.Int64()).String()
 }
 
-func (c *clnt) BlockHeaderByNumber(ctx context.Context, blockNUmber *big.Int) (*types.Header, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
+func toCallArg(msg ethereum.CallMsg) any {
+	arg := map[string]any{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	return arg
+}
 
+func (c *clnt) BlockHeaderByNumber(ctx context.Context, blockNUmber *big.Int) (*types.Header, error) {
 	var header *types.Header
-	err := c.rpc.CallContext(ctx, &header, "eth_getBlockByNumber", toBlockNumArg(blockNUmber), false)
+	err := c.call(ctx, ClassRead, &header, "eth_getBlockByNumber", toBlockNumArg(blockNUmber), false)
 	if err != nil {
 		return nil, err
 	} else if header == nil {
@@ -80,11 +121,8 @@ func (c *clnt) BlockHeaderByNumber(ctx context.Context, blockNUmber *big.Int) (*
 }
 
 func (c *clnt) BlockByNumber(ctx context.Context, blockNUmber *big.Int) (*WalletTypes.RpcBlock, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
-
 	var block *WalletTypes.RpcBlock
-	err := c.rpc.CallContext(ctx, &block, "eth_getBlockByNumber", toBlockNumArg(blockNUmber), true)
+	err := c.call(ctx, ClassRead, &block, "eth_getBlockByNumber", toBlockNumArg(blockNUmber), true)
 	if err != nil {
 		return nil, err
 	} else if block == nil {
@@ -95,11 +133,8 @@ func (c *clnt) BlockByNumber(ctx context.Context, blockNUmber *big.Int) (*Wallet
 }
 
 func (c *clnt) SafeBlockHeaderByNumber(ctx context.Context) (*types.Header, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
-
 	var header *types.Header
-	err := c.rpc.CallContext(ctx, &header, "eth_getBlockByNumber", "safe", false)
+	err := c.call(ctx, ClassRead, &header, "eth_getBlockByNumber", "safe", false)
 	if err != nil {
 		return nil, err
 	} else if header == nil {
@@ -109,11 +144,8 @@ func (c *clnt) SafeBlockHeaderByNumber(ctx context.Context) (*types.Header, erro
 }
 
 func (c *clnt) FinalizedBlockHeaderByNumber(ctx context.Context) (*types.Header, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
-
 	var header *types.Header
-	err := c.rpc.CallContext(ctx, &header, "eth_getBlockByNumber", "finalized", false)
+	err := c.call(ctx, ClassRead, &header, "eth_getBlockByNumber", "finalized", false)
 	if err != nil {
 		return nil, err
 	} else if header == nil {
@@ -123,27 +155,61 @@ func (c *clnt) FinalizedBlockHeaderByNumber(ctx context.Context) (*types.Header,
 }
 
 func (c *clnt) BlockHeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
-
 	var head *types.Header
-	err := c.rpc.CallContext(ctx, &head, "eth_getBlockByHash", hash, false)
+	err := c.call(ctx, ClassRead, &head, "eth_getBlockByHash", hash, false)
 	if err == nil && head == nil {
 		err = ethereum.NotFound
 	}
 	return head, err
 }
 
-func (c *clnt) BlockHeadersByRange(ctx context.Context, start *big.Int, end *big.Int, uint2 uint) ([]types.Header, error) {
-	//TODO implement me
-	panic("implement me")
+// headersBatchSize 按区间获取区块头时单个批量请求包含的区块数，部分节点服务商限制批量请求的大小
+const headersBatchSize = 100
+
+// maxHeadersRange 单次按区间获取的最大区块数，超出的区间需由调用方分段获取
+const maxHeadersRange = 10000
+
+// BlockHeadersByRange 获取 [start, end] 区间的区块头并按父哈希校验连续性，区间超出节点当前高度时只返回已有的部分
+// 区间最多 maxHeadersRange 个区块，结果按批次追加，不按区间大小预先分配
+// chainId 预留给批量请求大小受限的链，目前所有链使用相同的分组大小
+func (c *clnt) BlockHeadersByRange(ctx context.Context, start *big.Int, end *big.Int, chainId uint) ([]types.Header, error) {
+	if start == nil || end == nil || start.Sign() < 0 || start.Cmp(end) > 0 {
+		return nil, fmt.Errorf("invalid block range [%v, %v]", start, end)
+	}
+	span := new(big.Int).Sub(end, start)
+	if !span.IsUint64() || span.Uint64() >= maxHeadersRange {
+		return nil, fmt.Errorf("block range [%v, %v] exceeds %d blocks", start, end, maxHeadersRange)
+	}
+	count := span.Uint64() + 1
+	headers := make([]types.Header, 0, min(count, headersBatchSize))
+	results := make([]*types.Header, min(count, headersBatchSize))
+	for offset := uint64(0); offset < count; offset += headersBatchSize {
+		size := min(headersBatchSize, count-offset)
+		batchElems := make([]rpc.BatchElem, size)
+		for i := range batchElems {
+			results[i] = nil
+			number := new(big.Int).Add(start, new(big.Int).SetUint64(offset+uint64(i)))
+			batchElems[i] = rpc.BatchElem{Method: "eth_getBlockByNumber", Args: []any{toBlockNumArg(number), false}, Result: &results[i]}
+		}
+		if err := c.batchCall(ctx, "eth_getBlockByNumber", batchElems); err != nil {
+			return nil, err
+		}
+		for _, header := range results[:size] {
+			if header == nil {
+				return headers, nil
+			}
+			if n := len(headers); n > 0 && header.ParentHash != headers[n-1].Hash() {
+				return nil, fmt.Errorf("header %s does not follow parent %s", header.Number, headers[n-1].Hash())
+			}
+			headers = append(headers, *header)
+		}
+	}
+	return headers, nil
 }
 
 func (c *clnt) TxByHash(ctx context.Context, hash common.Hash) (*types.Transaction, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
 	var tx *types.Transaction
-	err := c.rpc.CallContext(ctx, &tx, "eth_getTransactionByHash", hash)
+	err := c.call(ctx, ClassRead, &tx, "eth_getTransactionByHash", hash)
 	if err != nil {
 		return nil, err
 	} else if tx == nil {
@@ -154,7 +220,7 @@ func (c *clnt) TxByHash(ctx context.Context, hash common.Hash) (*types.Transacti
 
 func (c *clnt) TxReceiptByHash(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
 	var r *types.Receipt
-	err := c.rpc.CallContext(ctx, &r, "eth_getTransactionReceipt", hash)
+	err := c.call(ctx, ClassRead, &r, "eth_getTransactionReceipt", hash)
 	if err == nil && r == nil {
 		return nil, ethereum.NotFound
 	}
@@ -162,11 +228,8 @@ func (c *clnt) TxReceiptByHash(ctx context.Context, hash common.Hash) (*types.Re
 }
 
 func (c *clnt) StorageHash(ctx context.Context, address common.Address, blockNumber *big.Int) (common.Hash, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
-
 	proof := struct{ StorageHash common.Hash }{}
-	err := c.rpc.CallContext(ctx, &proof, "eth_getProof", address, nil, toBlockNumArg(blockNumber))
+	err := c.call(ctx, ClassRead, &proof, "eth_getProof", address, nil, toBlockNumArg(blockNumber))
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -175,62 +238,231 @@ func (c *clnt) StorageHash(ctx context.Context, address common.Address, blockNum
 }
 
 func (c *clnt) GetAddressNonce(ctx context.Context, address common.Address) (hexutil.Uint64, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
-
 	var result hexutil.Uint64
-	err := c.rpc.CallContext(ctx, &result, "eth_getTransactionCount", address, "pending")
+	err := c.call(ctx, ClassRead, &result, "eth_getTransactionCount", address, "pending")
 	return result, err
 }
 
 func (c *clnt) SendRawTransaction(ctx context.Context, rawTx string) error {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
-
-	return c.rpc.CallContext(ctx, nil, "eth_sendRawTransaction", rawTx)
+	return c.call(ctx, ClassSend, nil, "eth_sendRawTransaction", rawTx)
 }
 
 func (c *clnt) FilterLogs(filterQuery ethereum.FilterQuery, chainID *big.Int) (WalletTypes.Logs, error) {
-	//TODO implement me
-	panic("implement me")
+	arg, err := toFilterArg(filterQuery)
+	if err != nil {
+		return WalletTypes.Logs{}, err
+	}
+
+	// 同一批次中获取日志与 ToBlock 的块头，保证二者来自同一视图
+	var logs []types.Log
+	var header *types.Header
+	batchElems := []rpc.BatchElem{
+		{Method: "eth_getBlockByNumber", Args: []any{toBlockNumArg(filterQuery.ToBlock), false}, Result: &header},
+		{Method: "eth_getLogs", Args: []any{arg}, Result: &logs},
+	}
+	if err := c.batchCall(context.Background(), "eth_getLogs", batchElems); err != nil {
+		return WalletTypes.Logs{}, err
+	}
+	if header == nil {
+		return WalletTypes.Logs{}, ethereum.NotFound
+	}
+	return WalletTypes.Logs{Logs: logs, BlockHeader: header}, nil
 }
 
-func (c *clnt) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
+func toFilterArg(q ethereum.FilterQuery) (any, error) {
+	arg := map[string]any{
+		"address": q.Addresses,
+		"topics":  q.Topics,
+	}
+	if q.BlockHash != nil {
+		arg["blockHash"] = *q.BlockHash
+		if q.FromBlock != nil || q.ToBlock != nil {
+			return nil, errors.New("cannot specify both BlockHash and FromBlock/ToBlock")
+		}
+	} else {
+		if q.FromBlock == nil {
+			arg["fromBlock"] = "0x0"
+		} else {
+			arg["fromBlock"] = toBlockNumArg(q.FromBlock)
+		}
+		arg["toBlock"] = toBlockNumArg(q.ToBlock)
+	}
+	return arg, nil
+}
 
+func (c *clnt) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	var hex hexutil.Big
-	if err := c.rpc.CallContext(ctx, &hex, "eth_gasPrice"); err != nil {
+	if err := c.call(ctx, ClassRead, &hex, "eth_gasPrice"); err != nil {
 		return nil, err
 	}
 	return (*big.Int)(&hex), nil
 }
 
 func (c *clnt) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(global.ServerConfig.MaxRequestTime))
-	defer cancel()
+	var hex hexutil.Big
+	if err := c.call(ctx, ClassRead, &hex, "eth_maxPriorityFeePerGas"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&hex), nil
+}
 
+func (c *clnt) BlobBaseFee(ctx context.Context) (*big.Int, error) {
 	var hex hexutil.Big
-	if err := c.rpc.CallContext(ctx, &hex, "eth_maxPriorityFeePerGas"); err != nil {
+	if err := c.call(ctx, ClassRead, &hex, "eth_blobBaseFee"); err != nil {
 		return nil, err
 	}
 	return (*big.Int)(&hex), nil
 }
 
-func DailEthClient(ctx context.Context, rpcUrl string) (EthClient, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+func (c *clnt) CodeAt(ctx context.Context, address common.Address, blockNumber *big.Int) ([]byte, error) {
+	var code hexutil.Bytes
+	if err := c.call(ctx, ClassRead, &code, "eth_getCode", address, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (c *clnt) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var hex hexutil.Bytes
+	if err := c.call(ctx, ClassRead, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return hex, nil
+}
+
+func (c *clnt) BatchCallContract(ctx context.Context, msgs []ethereum.CallMsg, blockNumber *big.Int) ([][]byte, error) {
+	results := make([]hexutil.Bytes, len(msgs))
+	batchElems := make([]rpc.BatchElem, len(msgs))
+	for i, msg := range msgs {
+		batchElems[i] = rpc.BatchElem{Method: "eth_call", Args: []any{toCallArg(msg), toBlockNumArg(blockNumber)}, Result: &results[i]}
+	}
+	if err := c.batchCall(ctx, "eth_call", batchElems); err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, len(results))
+	for i, result := range results {
+		data[i] = result
+	}
+	return data, nil
+}
+
+func (c *clnt) BalancesAt(ctx context.Context, addresses []common.Address, blockNumber *big.Int) ([]*big.Int, error) {
+	results := make([]hexutil.Big, len(addresses))
+	batchElems := make([]rpc.BatchElem, len(addresses))
+	for i, address := range addresses {
+		batchElems[i] = rpc.BatchElem{Method: "eth_getBalance", Args: []any{address, toBlockNumArg(blockNumber)}, Result: &results[i]}
+	}
+	if err := c.batchCall(ctx, "eth_getBalance", batchElems); err != nil {
+		return nil, err
+	}
+
+	balances := make([]*big.Int, len(results))
+	for i := range results {
+		balances[i] = (*big.Int)(&results[i])
+	}
+	return balances, nil
+}
+
+// batchCall 执行批量请求，任一子请求失败即返回该错误，method 用于日志与指标
+func (c *clnt) batchCall(ctx context.Context, method string, batchElems []rpc.BatchElem) error {
+	if len(batchElems) == 0 {
+		return nil
+	}
+	err := c.do(ctx, ClassBatch, method, func(ctx context.Context) error {
+		for i := range batchElems {
+			batchElems[i].Error = nil
+		}
+		return c.rpc.BatchCallContext(ctx, batchElems)
+	})
+	if err != nil {
+		return err
+	}
+	for _, elem := range batchElems {
+		if elem.Error != nil {
+			return elem.Error
+		}
+	}
+	return nil
+}
+
+// call 执行单个请求
+func (c *clnt) call(ctx context.Context, class MethodClass, result any, method string, args ...any) error {
+	return c.do(ctx, class, method, func(ctx context.Context) error {
+		return c.rpc.CallContext(ctx, result, method, args...)
+	})
+}
+
+// do 按方法类别设置单次请求超时，记录指标，并按重试策略重试可重试的错误
+func (c *clnt) do(ctx context.Context, class MethodClass, method string, request func(ctx context.Context) error) error {
+	policy := c.opts.retry
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = 200 * time.Millisecond
+	}
+	maxBackoff := policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout := c.opts.timeout(class); timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		start := time.Now()
+		err := request(attemptCtx)
+		cancel()
+		if c.opts.metrics != nil {
+			c.opts.metrics.ObserveRequest(method, class, time.Since(start), err)
+		}
+
+		if err == nil || class == ClassSend || attempt >= policy.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+		c.logf("node: %s attempt %d/%d failed, retry in %s: %v", method, attempt, policy.MaxAttempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// DailEthClient 连接节点，rpcUrl 支持 http(s)、ws(s) 与 IPC 路径
+func DailEthClient(ctx context.Context, rpcUrl string, opts ...Option) (EthClient, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.dialTimeout)
 	defer cancel()
 
-	client, err := rpc.DialContext(ctx, rpcUrl)
+	client, err := rpc.DialOptions(ctx, rpcUrl, o.rpcOptions()...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &clnt{
-		rpc: NewRPC(client), // 使用初始化的 rpc.Client 创建 RPC 实例
+		rpc:  NewRPC(client), // 使用初始化的 rpc.Client 创建 RPC 实例
+		opts: o,
 	}, nil
 }
 
+// NewEthClient 基于已有的 RPC 连接创建客户端，连接相关的选项（请求头、认证、连接超时）不生效
+func NewEthClient(rpc RPC, opts ...Option) EthClient {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &clnt{rpc: rpc, opts: o}
+}
+
 type rpcClent struct {
 	rpc *rpc.Client
 }
@@ -249,6 +481,14 @@ func (r rpcClent) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
 	return err
 }
 
+func (r rpcClent) EthSubscribe(ctx context.Context, channel any, args ...any) (ethereum.Subscription, error) {
+	sub, err := r.rpc.EthSubscribe(ctx, channel, args...)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
 func NewRPC(client *rpc.Client) RPC {
 	return &rpcClent{
 		rpc: client,
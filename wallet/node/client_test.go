@@ -0,0 +1,130 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRPC 按顺序返回预设错误，记录每次调用的方法与超时
+type fakeRPC struct {
+	RPC
+	errs      []error
+	calls     []string
+	deadlines []time.Duration
+}
+
+func (f *fakeRPC) CallContext(ctx context.Context, result any, method string, args ...any) error {
+	f.calls = append(f.calls, method)
+	if deadline, ok := ctx.Deadline(); ok {
+		f.deadlines = append(f.deadlines, time.Until(deadline))
+	}
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	return nil
+}
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (m *recordingMetrics) ObserveRequest(method string, class MethodClass, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, method+"/"+class.String())
+}
+
+func TestClientTimeoutsAndRetry(t *testing.T) {
+	fake := &fakeRPC{errs: []error{
+		rpc.HTTPError{StatusCode: http.StatusServiceUnavailable},
+		io.ErrUnexpectedEOF,
+	}}
+	metrics := &recordingMetrics{}
+	client := NewEthClient(fake,
+		WithTimeout(time.Minute),
+		WithMethodTimeout(ClassSend, time.Second),
+		WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}),
+		WithLogger(nil),
+		WithMetrics(metrics),
+	)
+
+	// 两次可重试的失败后成功
+	if _, err := client.GetAddressNonce(context.Background(), common.Address{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.calls) != 3 || len(metrics.requests) != 3 || metrics.requests[0] != "eth_getTransactionCount/read" {
+		t.Fatalf("calls %v, metrics %v", fake.calls, metrics.requests)
+	}
+	if fake.deadlines[0] < 50*time.Second {
+		t.Fatalf("read timeout %s", fake.deadlines[0])
+	}
+
+	// 广播交易不重试，使用单独的超时
+	fake.calls, fake.deadlines = nil, nil
+	fake.errs = []error{rpc.HTTPError{StatusCode: http.StatusBadGateway}}
+	if err := client.SendRawTransaction(context.Background(), "0x00"); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(fake.calls) != 1 || fake.deadlines[0] > time.Second {
+		t.Fatalf("send calls %v, deadlines %v", fake.calls, fake.deadlines)
+	}
+
+	// 节点返回的执行错误不重试
+	fake.calls = nil
+	fake.errs = []error{errors.New("execution reverted")}
+	if _, err := client.CodeAt(context.Background(), common.Address{}, nil); err == nil || len(fake.calls) != 1 {
+		t.Fatalf("err %v, calls %v", err, fake.calls)
+	}
+
+	// 超时函数优先于静态设置，用于配置热更新
+	fake.deadlines = nil
+	client = NewEthClient(fake, WithTimeoutFunc(func(class MethodClass) time.Duration { return 2 * time.Second }))
+	if _, err := client.SuggestGasPrice(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.deadlines[0] > 2*time.Second {
+		t.Fatalf("dynamic timeout %s", fake.deadlines[0])
+	}
+}
+
+func TestDialHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		var req struct {
+			Id json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.Id, "result": "0x2a"})
+	}))
+	defer server.Close()
+
+	client, err := DailEthClient(context.Background(), server.URL,
+		WithUserAgent("ethcexwallet-test"),
+		WithBearerToken("secret"),
+		WithHeader("X-Api-Key", "key"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := client.GetAddressNonce(context.Background(), common.Address{})
+	if err != nil || nonce != 42 {
+		t.Fatalf("nonce %d, %v", nonce, err)
+	}
+	if header.Get("User-Agent") != "ethcexwallet-test" || header.Get("Authorization") != "Bearer secret" || header.Get("X-Api-Key") != "key" {
+		t.Fatalf("headers %v", header)
+	}
+}
@@ -0,0 +1,203 @@
+package node
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/ethereum/go-ethereum/rpc"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// MethodClass 按请求的耗时特征划分的方法类别，用于分别设置超时
+type MethodClass int
+
+const (
+	// ClassRead 单个查询：区块、交易、回执、nonce、gas、合约只读调用等
+	ClassRead MethodClass = iota
+	// ClassBatch 批量查询与日志：BatchCallContract、BalancesAt、FilterLogs
+	ClassBatch
+	// ClassSend 广播交易
+	ClassSend
+
+	classCount
+)
+
+func (c MethodClass) String() string {
+	switch c {
+	case ClassRead:
+		return "read"
+	case ClassBatch:
+		return "batch"
+	case ClassSend:
+		return "send"
+	}
+	return "unknown"
+}
+
+const (
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultReadTimeout  = 10 * time.Second
+	DefaultBatchTimeout = 30 * time.Second
+	DefaultSendTimeout  = 10 * time.Second
+	// DefaultPollInterval 节点不支持订阅时轮询新区块的间隔
+	DefaultPollInterval = 2 * time.Second
+)
+
+// RetryPolicy 请求失败后的重试策略，超时按每次尝试单独计算
+// 广播交易不重试：重复广播可能返回 already known 或 nonce too low，需要调用方结合交易状态判断
+type RetryPolicy struct {
+	MaxAttempts int              // 含首次请求的总次数，不大于 1 时不重试
+	Backoff     time.Duration    // 首次重试间隔，之后每次翻倍，默认 200ms
+	MaxBackoff  time.Duration    // 重试间隔上限，默认 5s
+	Retryable   func(error) bool // 判断错误是否可重试，默认 IsRetryable
+}
+
+// Metrics 接收每次请求尝试的结果，用于接入 Prometheus 等监控，实现需并发安全
+type Metrics interface {
+	ObserveRequest(method string, class MethodClass, duration time.Duration, err error)
+}
+
+// Option 节点客户端选项
+type Option func(*options)
+
+type options struct {
+	dialTimeout  time.Duration
+	timeouts     [classCount]time.Duration
+	timeoutFunc  func(MethodClass) time.Duration
+	headers      http.Header
+	retry        RetryPolicy
+	pollInterval time.Duration
+	logger       *log.Logger
+	metrics      Metrics
+}
+
+func defaultOptions() *options {
+	return &options{
+		dialTimeout:  DefaultDialTimeout,
+		timeouts:     [classCount]time.Duration{DefaultReadTimeout, DefaultBatchTimeout, DefaultSendTimeout},
+		headers:      make(http.Header),
+		retry:        RetryPolicy{MaxAttempts: 1},
+		pollInterval: DefaultPollInterval,
+		logger:       log.Default(),
+	}
+}
+
+// WithDialTimeout 设置建立连接的超时
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithTimeout 为所有类别的方法设置同一超时
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		for class := range o.timeouts {
+			o.timeouts[class] = timeout
+		}
+	}
+}
+
+// WithMethodTimeout 设置某一类方法的超时
+func WithMethodTimeout(class MethodClass, timeout time.Duration) Option {
+	return func(o *options) {
+		if class >= 0 && class < classCount {
+			o.timeouts[class] = timeout
+		}
+	}
+}
+
+// WithTimeoutFunc 每次请求时调用 fn 获取超时，用于随配置热更新调整；返回值不大于 0 时使用静态设置
+func WithTimeoutFunc(fn func(MethodClass) time.Duration) Option {
+	return func(o *options) {
+		o.timeoutFunc = fn
+	}
+}
+
+// WithUserAgent 设置 HTTP 与 WebSocket 握手请求的 User-Agent
+func WithUserAgent(userAgent string) Option {
+	return WithHeader("User-Agent", userAgent)
+}
+
+// WithHeader 为 HTTP 与 WebSocket 握手请求添加请求头，如节点服务商要求的 API Key 头
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		o.headers.Set(key, value)
+	}
+}
+
+// WithBasicAuth 使用 HTTP Basic 认证
+func WithBasicAuth(username, password string) Option {
+	return WithHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+}
+
+// WithBearerToken 使用 Bearer Token 认证
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithRetry 设置重试策略，默认不重试
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithPollInterval 设置节点不支持订阅（HTTP）时 SubscribeNewHead 与 SubscribeFilterLogs 的轮询间隔
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		if interval > 0 {
+			o.pollInterval = interval
+		}
+	}
+}
+
+// WithLogger 设置记录重试与重新订阅的日志，nil 时不记录
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMetrics 设置请求指标的接收方
+func WithMetrics(metrics Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+func (o *options) timeout(class MethodClass) time.Duration {
+	if o.timeoutFunc != nil {
+		if timeout := o.timeoutFunc(class); timeout > 0 {
+			return timeout
+		}
+	}
+	return o.timeouts[class]
+}
+
+func (o *options) rpcOptions() []rpc.ClientOption {
+	if len(o.headers) == 0 {
+		return nil
+	}
+	return []rpc.ClientOption{rpc.WithHeaders(o.headers)}
+}
+
+// IsRetryable 默认的可重试判断：网络错误、单次请求超时、HTTP 429 与 5xx、节点限流
+// JSON-RPC 返回的其他错误（参数错误、执行回滚等）重试也不会成功
+func IsRetryable(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32005 limit exceeded
+		return rpcErr.ErrorCode() == -32005
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
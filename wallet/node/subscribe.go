@@ -0,0 +1,437 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	// maxBackfill 单次补齐的区块数上限，断线更久时只补齐最近的区块，更早的部分由扫链游标补扫
+	maxBackfill = 1024
+	// logsBackfillRange 补齐日志时单次 eth_getLogs 覆盖的区块数
+	logsBackfillRange = 128
+	// resubscribeBackoff 重新订阅的最大退避间隔
+	resubscribeBackoff = 30 * time.Second
+	// pendingLookups 只订阅哈希时并发查询交易的上限，全部占满时暂停读取通知
+	pendingLookups = 16
+)
+
+// errSubscriptionClosed 节点关闭了订阅但没有给出原因
+var errSubscriptionClosed = errors.New("subscription closed by node")
+
+// stream 一路订阅的状态，跨重新订阅保留，用于补齐断线期间漏掉的数据
+type stream interface {
+	// subscribe 建立 eth_subscribe 订阅，返回的订阅在出错或 Unsubscribe 时结束
+	subscribe(ctx context.Context, subRPC SubscriptionRPC) (event.Subscription, error)
+	// poll 节点不支持订阅时轮询，返回的订阅在出错或 Unsubscribe 时结束；无法轮询时返回 rpc.ErrNotificationsUnsupported
+	poll() (event.Subscription, error)
+}
+
+// SubscribeNewHead 订阅新区块头，订阅建立后先发送当前最新区块头
+// 发送的区块头按高度连续：断线或漏掉通知时先按区间补齐缺失的区块头；发生重组时会再次发送相同高度的区块头
+// ctx 只用于建立首次订阅，之后订阅持续到 Unsubscribe，返回的 Err 通道只在 Unsubscribe 后关闭
+func (c *clnt) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return c.keepSubscribed(ctx, "newHeads", &headStream{client: c, out: ch})
+}
+
+// SubscribeFilterLogs 订阅匹配 q.Addresses 与 q.Topics 的日志；q.FromBlock 不为空时先补发从该区块开始的历史日志
+// 断线期间的日志在重新订阅后通过 eth_getLogs 补发，同一条日志可能重复发送，消费方需按交易哈希与日志序号去重
+// 轮询模式不会发送重组移除的日志（Removed 为 true），消费方应结合确认数处理重组
+func (c *clnt) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if q.BlockHash != nil || q.ToBlock != nil {
+		return nil, errors.New("log subscription does not support BlockHash or ToBlock")
+	}
+	s := &logStream{client: c, query: q, out: ch}
+	if q.FromBlock != nil {
+		if q.FromBlock.Sign() < 0 {
+			return nil, errors.New("log subscription requires a non-negative FromBlock")
+		}
+		s.started, s.next = true, q.FromBlock.Uint64()
+	}
+	return c.keepSubscribed(ctx, "logs", s)
+}
+
+// SubscribePendingTransactions 订阅进入交易池的交易，节点支持 newPendingTransactions 的完整交易模式时直接推送交易，否则按哈希并发查询，推送顺序不保证与通知一致
+// 断线后自动重新订阅，断线期间进入交易池的交易不会补发；HTTP 节点不支持交易池订阅，返回 rpc.ErrNotificationsUnsupported
+func (c *clnt) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ethereum.Subscription, error) {
+	return c.keepSubscribed(ctx, "newPendingTransactions", &pendingStream{client: c, out: ch})
+}
+
+// keepSubscribed 同步建立首次订阅以便返回连接错误，之后交给 event.ResubscribeErr 在出错后按退避重新订阅
+// HTTP 连接不支持订阅，改为按 pollInterval 轮询，无法轮询的订阅返回 rpc.ErrNotificationsUnsupported
+func (c *clnt) keepSubscribed(ctx context.Context, name string, s stream) (ethereum.Subscription, error) {
+	subRPC, ok := c.rpc.(SubscriptionRPC)
+	var first event.Subscription
+	if ok {
+		var err error
+		first, err = s.subscribe(ctx, subRPC)
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			ok = false
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		var err error
+		if first, err = s.poll(); err != nil {
+			return nil, err
+		}
+		c.logf("node: %s subscription is not supported by the endpoint, polling every %s", name, c.opts.pollInterval)
+	}
+
+	return event.ResubscribeErr(resubscribeBackoff, func(ctx context.Context, lastErr error) (event.Subscription, error) {
+		if first != nil {
+			sub := first
+			first = nil
+			return sub, nil
+		}
+		c.logf("node: %s subscription failed, resubscribing: %v", name, lastErr)
+		if ok {
+			return s.subscribe(ctx, subRPC)
+		}
+		return s.poll()
+	}), nil
+}
+
+func (c *clnt) logf(format string, args ...any) {
+	if c.opts.logger != nil {
+		c.opts.logger.Printf(format, args...)
+	}
+}
+
+// quitContext 返回在 quit 关闭时取消的 context，用于订阅协程中的请求
+func quitContext(quit <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// headStream 区块头订阅，last 为最后发送的区块头
+type headStream struct {
+	client *clnt
+	out    chan<- *types.Header
+	last   *types.Header
+}
+
+func (s *headStream) subscribe(ctx context.Context, subRPC SubscriptionRPC) (event.Subscription, error) {
+	raw := make(chan *types.Header, 16)
+	sub, err := subRPC.EthSubscribe(ctx, raw, "newHeads")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		ctx, cancel := quitContext(quit)
+		defer cancel()
+
+		// 断线期间可能一直没有新区块通知，主动获取最新区块头以便立即补齐
+		header, err := s.client.BlockHeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := s.forward(ctx, header); err != nil {
+			return err
+		}
+		for {
+			select {
+			case header := <-raw:
+				if err := s.forward(ctx, header); err != nil {
+					return err
+				}
+			case err := <-sub.Err():
+				if err == nil {
+					err = errSubscriptionClosed
+				}
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+func (s *headStream) poll() (event.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ctx, cancel := quitContext(quit)
+		defer cancel()
+		ticker := time.NewTicker(s.client.opts.pollInterval)
+		defer ticker.Stop()
+		for {
+			header, err := s.client.BlockHeaderByNumber(ctx, nil)
+			if err != nil {
+				return err
+			}
+			// 负载均衡后的节点高度可能不一致，忽略比已发送的更低的区块头
+			if s.last == nil || header.Number.Cmp(s.last.Number) >= 0 {
+				if err := s.forward(ctx, header); err != nil {
+					return err
+				}
+			}
+			select {
+			case <-quit:
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}), nil
+}
+
+// forward 发送区块头，与上一个区块头之间有缺口时先按区间补齐
+func (s *headStream) forward(ctx context.Context, header *types.Header) error {
+	if s.last != nil {
+		if header.Hash() == s.last.Hash() {
+			return nil
+		}
+		next, number := s.last.Number.Uint64()+1, header.Number.Uint64()
+		if number > next {
+			if number-next > maxBackfill {
+				s.client.logf("node: %d headers missed before block %d, backfilling the latest %d", number-next, number, maxBackfill)
+				next = number - maxBackfill
+			}
+			headers, err := s.client.BlockHeadersByRange(ctx, new(big.Int).SetUint64(next), new(big.Int).SetUint64(number-1), 0)
+			if err != nil {
+				return err
+			}
+			for i := range headers {
+				if err := s.send(ctx, &headers[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return s.send(ctx, header)
+}
+
+func (s *headStream) send(ctx context.Context, header *types.Header) error {
+	select {
+	case s.out <- header:
+		s.last = header
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// logStream 日志订阅，next 为第一个可能还有日志未发送的区块
+type logStream struct {
+	client  *clnt
+	query   ethereum.FilterQuery
+	out     chan<- types.Log
+	started bool
+	next    uint64
+}
+
+func (s *logStream) subscribe(ctx context.Context, subRPC SubscriptionRPC) (event.Subscription, error) {
+	raw := make(chan types.Log, 128)
+	arg := map[string]any{"address": s.query.Addresses, "topics": s.query.Topics}
+	sub, err := subRPC.EthSubscribe(ctx, raw, "logs", arg)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		ctx, cancel := quitContext(quit)
+		defer cancel()
+
+		// 订阅建立后再补齐，之间产生的日志两边都可能收到，订阅收到的不高于补齐高度的日志丢弃
+		backfilled, err := s.catchUp(ctx)
+		if err != nil {
+			return err
+		}
+		for {
+			select {
+			case log := <-raw:
+				if log.BlockNumber <= backfilled && !log.Removed {
+					continue
+				}
+				if err := s.send(ctx, log); err != nil {
+					return err
+				}
+			case err := <-sub.Err():
+				if err == nil {
+					err = errSubscriptionClosed
+				}
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+func (s *logStream) poll() (event.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ctx, cancel := quitContext(quit)
+		defer cancel()
+		ticker := time.NewTicker(s.client.opts.pollInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.catchUp(ctx); err != nil {
+				return err
+			}
+			select {
+			case <-quit:
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}), nil
+}
+
+// catchUp 通过 eth_getLogs 发送 next 到最新区块之间的日志，返回补齐到的高度
+// 首次订阅且未指定 FromBlock 时不补发历史日志，从下一个区块开始
+func (s *logStream) catchUp(ctx context.Context) (uint64, error) {
+	header, err := s.client.BlockHeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	head := header.Number.Uint64()
+	if !s.started {
+		s.started, s.next = true, head+1
+	}
+	if s.next+maxBackfill <= head {
+		s.client.logf("node: logs of %d blocks missed before block %d, backfilling the latest %d", head+1-s.next, head+1, maxBackfill)
+		s.next = head + 1 - maxBackfill
+	}
+
+	for s.next <= head {
+		to := min(s.next+logsBackfillRange-1, head)
+		query := s.query
+		query.FromBlock, query.ToBlock = new(big.Int).SetUint64(s.next), new(big.Int).SetUint64(to)
+		logs, err := s.client.FilterLogs(query, nil)
+		if err != nil {
+			return 0, err
+		}
+		for _, log := range logs.Logs {
+			if err := s.send(ctx, log); err != nil {
+				return 0, err
+			}
+		}
+		s.next = to + 1
+	}
+	return head, nil
+}
+
+func (s *logStream) send(ctx context.Context, log types.Log) error {
+	select {
+	case s.out <- log:
+		if !log.Removed && log.BlockNumber >= s.next {
+			s.next = log.BlockNumber + 1
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pendingStream 交易池订阅，hashOnly 表示节点不支持完整交易模式
+type pendingStream struct {
+	client   *clnt
+	out      chan<- *types.Transaction
+	hashOnly bool
+}
+
+func (s *pendingStream) subscribe(ctx context.Context, subRPC SubscriptionRPC) (event.Subscription, error) {
+	raw := make(chan json.RawMessage, 256)
+	var sub ethereum.Subscription
+	var err error
+	if !s.hashOnly {
+		sub, err = subRPC.EthSubscribe(ctx, raw, "newPendingTransactions", true)
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32602 {
+			// -32602 invalid params：不支持完整交易参数的节点，改为只订阅哈希；其他错误返回后由 ResubscribeErr 重试
+			s.hashOnly = true
+		}
+	}
+	if s.hashOnly {
+		sub, err = subRPC.EthSubscribe(ctx, raw, "newPendingTransactions")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		// 先取消 ctx 再等待进行中的查询退出，订阅结束后不再向 out 发送
+		var lookups sync.WaitGroup
+		defer lookups.Wait()
+		ctx, cancel := quitContext(quit)
+		defer cancel()
+		slots := make(chan struct{}, pendingLookups)
+		for {
+			select {
+			case msg := <-raw:
+				var hash common.Hash
+				if json.Unmarshal(msg, &hash) != nil {
+					tx := new(types.Transaction)
+					if err := json.Unmarshal(msg, tx); err != nil {
+						s.client.logf("node: decode pending transaction: %v", err)
+						continue
+					}
+					if !s.send(ctx, tx) {
+						return nil
+					}
+					continue
+				}
+				// 只有哈希时在接收循环之外查询交易，查询慢时不阻塞后续通知
+				select {
+				case slots <- struct{}{}:
+				case <-quit:
+					return nil
+				}
+				lookups.Add(1)
+				go func() {
+					defer func() {
+						<-slots
+						lookups.Done()
+					}()
+					tx, err := s.client.TxByHash(ctx, hash)
+					if errors.Is(err, ethereum.NotFound) || ctx.Err() != nil {
+						// 已被打包或移出交易池
+						return
+					}
+					if err != nil {
+						s.client.logf("node: fetch pending transaction %s: %v", hash, err)
+						return
+					}
+					s.send(ctx, tx)
+				}()
+			case err := <-sub.Err():
+				if err == nil {
+					err = errSubscriptionClosed
+				}
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// send 发送交易，订阅结束时返回 false
+func (s *pendingStream) send(ctx context.Context, tx *types.Transaction) bool {
+	select {
+	case s.out <- tx:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *pendingStream) poll() (event.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
@@ -0,0 +1,422 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chainRPC 内存中的链，每个区块带一条日志；http 为 true 时和 HTTP 连接一样不支持订阅，fullTx 为 true 时交易池订阅推送完整交易
+type chainRPC struct {
+	RPC
+	http   bool
+	fullTx bool
+
+	mu      sync.Mutex
+	headers []*types.Header
+	logs    []types.Log
+	pending map[common.Hash]*types.Transaction
+	subs    []*fakeSubscription
+}
+
+// invalidParamsError 节点不支持完整交易模式时返回的参数错误
+type invalidParamsError struct{}
+
+func (invalidParamsError) Error() string  { return "invalid params" }
+func (invalidParamsError) ErrorCode() int { return -32602 }
+
+type fakeSubscription struct {
+	kind    string
+	channel reflect.Value
+	err     chan error
+}
+
+func (s *fakeSubscription) Unsubscribe()      {}
+func (s *fakeSubscription) Err() <-chan error { return s.err }
+
+func newChainRPC(http bool, blocks int) *chainRPC {
+	c := &chainRPC{http: http, pending: make(map[common.Hash]*types.Transaction)}
+	c.mine(blocks, false)
+	return c
+}
+
+// mine 出块，notify 为 true 时通知当前的订阅
+func (c *chainRPC) mine(n int, notify bool) {
+	c.mu.Lock()
+	var headers []*types.Header
+	var logs []types.Log
+	for i := 0; i < n; i++ {
+		header := &types.Header{Number: big.NewInt(int64(len(c.headers))), Difficulty: common.Big0, Time: uint64(len(c.headers))}
+		if len(c.headers) > 0 {
+			header.ParentHash = c.headers[len(c.headers)-1].Hash()
+		}
+		log := types.Log{
+			Topics:      []common.Hash{{0x01}},
+			BlockNumber: header.Number.Uint64(),
+			BlockHash:   header.Hash(),
+			TxHash:      common.BigToHash(header.Number),
+		}
+		c.headers = append(c.headers, header)
+		c.logs = append(c.logs, log)
+		headers, logs = append(headers, header), append(logs, log)
+	}
+	subs := append([]*fakeSubscription(nil), c.subs...)
+	c.mu.Unlock()
+
+	if !notify {
+		return
+	}
+	for _, sub := range subs {
+		if sub.kind == "newPendingTransactions" {
+			continue
+		}
+		if sub.kind == "newHeads" {
+			for _, header := range headers {
+				sub.channel.Send(reflect.ValueOf(header))
+			}
+		} else {
+			for _, log := range logs {
+				sub.channel.Send(reflect.ValueOf(log))
+			}
+		}
+	}
+}
+
+// broadcast 交易进入交易池并通知交易池订阅
+func (c *chainRPC) broadcast(tx *types.Transaction) {
+	c.mu.Lock()
+	c.pending[tx.Hash()] = tx
+	subs := append([]*fakeSubscription(nil), c.subs...)
+	c.mu.Unlock()
+
+	var msg []byte
+	if c.fullTx {
+		msg, _ = json.Marshal(tx)
+	} else {
+		msg, _ = json.Marshal(tx.Hash())
+	}
+	for _, sub := range subs {
+		if sub.kind == "newPendingTransactions" {
+			sub.channel.Send(reflect.ValueOf(json.RawMessage(msg)))
+		}
+	}
+}
+
+// disconnect 模拟连接断开，当前的订阅全部出错
+func (c *chainRPC) disconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, sub := range c.subs {
+		sub.err <- errors.New("connection lost")
+	}
+	c.subs = nil
+}
+
+func (c *chainRPC) EthSubscribe(ctx context.Context, channel any, args ...any) (ethereum.Subscription, error) {
+	if c.http {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	if args[0] == "newPendingTransactions" && len(args) > 1 && !c.fullTx {
+		return nil, invalidParamsError{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub := &fakeSubscription{kind: args[0].(string), channel: reflect.ValueOf(channel), err: make(chan error, 1)}
+	c.subs = append(c.subs, sub)
+	return sub, nil
+}
+
+func (c *chainRPC) CallContext(ctx context.Context, result any, method string, args ...any) error {
+	elems := []rpc.BatchElem{{Method: method, Args: args, Result: result}}
+	if err := c.BatchCallContext(ctx, elems); err != nil {
+		return err
+	}
+	return elems[0].Error
+}
+
+func (c *chainRPC) BatchCallContext(ctx context.Context, elems []rpc.BatchElem) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, elem := range elems {
+		var value any
+		switch elem.Method {
+		case "eth_getBlockByNumber":
+			if number := c.number(elem.Args[0]); number < uint64(len(c.headers)) {
+				value = c.headers[number]
+			}
+		case "eth_getTransactionByHash":
+			if tx, ok := c.pending[elem.Args[0].(common.Hash)]; ok {
+				value = tx
+			}
+		case "eth_getLogs":
+			arg := elem.Args[0].(map[string]any)
+			from, to := c.number(arg["fromBlock"]), c.number(arg["toBlock"])
+			logs := []types.Log{}
+			for _, log := range c.logs {
+				if log.BlockNumber >= from && log.BlockNumber <= to {
+					logs = append(logs, log)
+				}
+			}
+			value = logs
+		default:
+			elems[i].Error = errors.New("unsupported method " + elem.Method)
+			continue
+		}
+		data, _ := json.Marshal(value)
+		elems[i].Error = json.Unmarshal(data, elem.Result)
+	}
+	return nil
+}
+
+func (c *chainRPC) number(arg any) uint64 {
+	if arg == "latest" {
+		return uint64(len(c.headers) - 1)
+	}
+	return hexutil.MustDecodeUint64(arg.(string))
+}
+
+func receiveHeaders(t *testing.T, ch <-chan *types.Header, want ...uint64) {
+	t.Helper()
+	for _, number := range want {
+		select {
+		case header := <-ch:
+			if header.Number.Uint64() != number {
+				t.Fatalf("received header %d, want %d", header.Number, number)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("header %d not received", number)
+		}
+	}
+}
+
+func receiveLogs(t *testing.T, ch <-chan types.Log, want ...uint64) {
+	t.Helper()
+	for _, number := range want {
+		select {
+		case log := <-ch:
+			if log.BlockNumber != number {
+				t.Fatalf("received log of block %d, want %d", log.BlockNumber, number)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("log of block %d not received", number)
+		}
+	}
+}
+
+func TestBlockHeadersByRange(t *testing.T) {
+	client := NewEthClient(newChainRPC(false, 150))
+	headers, err := client.BlockHeadersByRange(context.Background(), big.NewInt(0), big.NewInt(149), 1)
+	if err != nil || len(headers) != 150 || headers[149].Number.Uint64() != 149 {
+		t.Fatalf("headers %d, %v", len(headers), err)
+	}
+	// 超出当前高度时只返回已有的部分
+	headers, err = client.BlockHeadersByRange(context.Background(), big.NewInt(148), big.NewInt(160), 1)
+	if err != nil || len(headers) != 2 {
+		t.Fatalf("headers %d, %v", len(headers), err)
+	}
+	if _, err := client.BlockHeadersByRange(context.Background(), big.NewInt(5), big.NewInt(4), 1); err == nil {
+		t.Fatal("expected invalid range error")
+	}
+	// 超大区间直接拒绝，不按区间大小分配内存
+	if _, err := client.BlockHeadersByRange(context.Background(), big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), 70), 1); err == nil {
+		t.Fatal("expected range too large error")
+	}
+}
+
+func TestSubscribeNewHeadResubscribe(t *testing.T) {
+	chain := newChainRPC(false, 3)
+	client := NewEthClient(chain, WithLogger(nil))
+	ch := make(chan *types.Header, 64)
+	sub, err := client.SubscribeNewHead(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	receiveHeaders(t, ch, 2)
+	chain.mine(1, true)
+	receiveHeaders(t, ch, 3)
+
+	// 断线期间出的块在重新订阅后补齐
+	chain.disconnect()
+	chain.mine(3, false)
+	receiveHeaders(t, ch, 4, 5, 6)
+	chain.mine(1, true)
+	receiveHeaders(t, ch, 7)
+}
+
+func TestSubscribeNewHeadPolling(t *testing.T) {
+	chain := newChainRPC(true, 3)
+	client := NewEthClient(chain, WithLogger(nil), WithPollInterval(10*time.Millisecond))
+	ch := make(chan *types.Header, 64)
+	sub, err := client.SubscribeNewHead(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	receiveHeaders(t, ch, 2)
+	chain.mine(3, false)
+	receiveHeaders(t, ch, 3, 4, 5)
+}
+
+func TestSubscribeFilterLogs(t *testing.T) {
+	for _, http := range []bool{false, true} {
+		chain := newChainRPC(http, 3)
+		client := NewEthClient(chain, WithLogger(nil), WithPollInterval(10*time.Millisecond))
+		ch := make(chan types.Log, 64)
+		sub, err := client.SubscribeFilterLogs(context.Background(), ethereum.FilterQuery{FromBlock: big.NewInt(1)}, ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		receiveLogs(t, ch, 1, 2)
+		chain.mine(1, true)
+		receiveLogs(t, ch, 3)
+		chain.disconnect()
+		chain.mine(2, false)
+		receiveLogs(t, ch, 4, 5)
+		chain.mine(1, true)
+		receiveLogs(t, ch, 6)
+		sub.Unsubscribe()
+
+		select {
+		case log := <-ch:
+			t.Fatalf("http=%v: duplicate log of block %d", http, log.BlockNumber)
+		default:
+		}
+	}
+}
+
+func TestSubscribePendingTransactions(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	for _, fullTx := range []bool{true, false} {
+		chain := newChainRPC(false, 1)
+		chain.fullTx = fullTx
+		client := NewEthClient(chain, WithLogger(nil))
+		ch := make(chan *types.Transaction, 8)
+		sub, err := client.SubscribePendingTransactions(context.Background(), ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{ChainID: big.NewInt(1), Gas: 21000, To: &common.Address{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		chain.broadcast(tx)
+		select {
+		case received := <-ch:
+			if received.Hash() != tx.Hash() {
+				t.Fatalf("fullTx=%v: received %s, want %s", fullTx, received.Hash(), tx.Hash())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fullTx=%v: transaction not received", fullTx)
+		}
+		sub.Unsubscribe()
+	}
+
+	if _, err := NewEthClient(newChainRPC(true, 1)).SubscribePendingTransactions(context.Background(), make(chan *types.Transaction)); !errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		t.Fatalf("http endpoint: %v", err)
+	}
+}
+
+// serverError 节点临时故障时返回的 JSON-RPC 错误
+type serverError struct{}
+
+func (serverError) Error() string  { return "server busy" }
+func (serverError) ErrorCode() int { return -32000 }
+
+// flakyPendingRPC 第一次订阅交易池时返回 serverError，记录每次订阅的参数
+type flakyPendingRPC struct {
+	*chainRPC
+	calls [][]any
+}
+
+func (c *flakyPendingRPC) EthSubscribe(ctx context.Context, channel any, args ...any) (ethereum.Subscription, error) {
+	c.calls = append(c.calls, args)
+	if len(c.calls) == 1 {
+		return nil, serverError{}
+	}
+	return c.chainRPC.EthSubscribe(ctx, channel, args...)
+}
+
+func TestSubscribePendingTransactionsTransientError(t *testing.T) {
+	chain := &flakyPendingRPC{chainRPC: newChainRPC(false, 1)}
+	chain.fullTx = true
+	client := NewEthClient(chain, WithLogger(nil))
+	ch := make(chan *types.Transaction, 8)
+
+	// 临时错误直接返回，不退化为只订阅哈希
+	if _, err := client.SubscribePendingTransactions(context.Background(), ch); !errors.As(err, new(serverError)) {
+		t.Fatalf("first subscribe err = %v", err)
+	}
+	sub, err := client.SubscribePendingTransactions(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	if len(chain.calls) != 2 || len(chain.calls[1]) != 2 || chain.calls[1][1] != true {
+		t.Fatalf("subscribe calls = %v, want full transaction mode", chain.calls)
+	}
+}
+
+// slowLookupRPC 查询 slow 交易时阻塞到 release 关闭
+type slowLookupRPC struct {
+	*chainRPC
+	slow    common.Hash
+	release chan struct{}
+}
+
+func (c *slowLookupRPC) CallContext(ctx context.Context, result any, method string, args ...any) error {
+	if method == "eth_getTransactionByHash" && args[0].(common.Hash) == c.slow {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return c.chainRPC.CallContext(ctx, result, method, args...)
+}
+
+func TestSubscribePendingTransactionsSlowLookup(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	slow, _ := types.SignNewTx(key, signer, &types.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 0, Gas: 21000, To: &common.Address{}})
+	fast, _ := types.SignNewTx(key, signer, &types.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 1, Gas: 21000, To: &common.Address{}})
+	chain := &slowLookupRPC{chainRPC: newChainRPC(false, 1), slow: slow.Hash(), release: make(chan struct{})}
+	ch := make(chan *types.Transaction, 8)
+	sub, err := NewEthClient(chain, WithLogger(nil)).SubscribePendingTransactions(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	// 只有哈希时慢查询不阻塞后续交易
+	chain.broadcast(slow)
+	chain.broadcast(fast)
+	for _, want := range []*types.Transaction{fast, slow} {
+		select {
+		case received := <-ch:
+			if received.Hash() != want.Hash() {
+				t.Fatalf("received %s, want %s", received.Hash(), want.Hash())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("transaction %s not received", want.Hash())
+		}
+		if want == fast {
+			close(chain.release)
+		}
+	}
+}
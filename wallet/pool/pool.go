@@ -0,0 +1,280 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+var (
+	ErrPoolEmpty      = errors.New("address pool is empty")
+	ErrUnknownFamily  = errors.New("unknown chain family")
+	ErrInvalidAddress = errors.New("source returned invalid address")
+)
+
+// Source 地址来源，如随机私钥、HD 派生或 CREATE2 分配
+type Source interface {
+	NewAddress(ctx context.Context) (*types.EthAddress, error)
+}
+
+// SourceFunc 将函数适配为 Source，例如 ethereum.CreateAddress
+type SourceFunc func(ctx context.Context) (*types.EthAddress, error)
+
+func (f SourceFunc) NewAddress(ctx context.Context) (*types.EthAddress, error) {
+	return f(ctx)
+}
+
+// Config 单个链族（EVM 链共用一套地址）的地址池配置
+type Config struct {
+	Family         string
+	Target         int           // 补充后的空闲地址数量
+	Threshold      int           // 空闲地址低于该值时触发补充
+	RefillInterval time.Duration // 定时检查间隔
+}
+
+// Assignment 已持久化的用户地址分配，用于重启后恢复地址池
+type Assignment struct {
+	UserId  string
+	Address string
+}
+
+// Pool 预生成地址池，用户注册时直接从池中分配
+// 分配关系只保存在内存中，调用方需持久化 Assign 的结果，并在启动时通过 Restore 恢复
+type Pool struct {
+	config Config
+	source Source
+	refill chan struct{}
+	// refillMu 串行化 Refill，后台补充与手动补充并发时不会超出 Target
+	refillMu sync.Mutex
+
+	mu        sync.RWMutex
+	free      []*types.EthAddress
+	byUser    map[string]*types.EthAddress
+	byAddress map[common.Address]string
+}
+
+func NewPool(config Config, source Source) *Pool {
+	if config.Threshold <= 0 || config.Threshold > config.Target {
+		config.Threshold = config.Target / 2
+	}
+	if config.RefillInterval <= 0 {
+		config.RefillInterval = time.Minute
+	}
+	return &Pool{
+		config:    config,
+		source:    source,
+		refill:    make(chan struct{}, 1),
+		byUser:    make(map[string]*types.EthAddress),
+		byAddress: make(map[common.Address]string),
+	}
+}
+
+func (p *Pool) Family() string {
+	return p.config.Family
+}
+
+// Available 当前空闲地址数量
+func (p *Pool) Available() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.free)
+}
+
+// Restore 恢复已持久化的分配，应在对外分配之前调用；与现有分配冲突的记录被忽略，返回恢复的数量
+func (p *Pool) Restore(assignments []Assignment) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	restored := 0
+	for _, assignment := range assignments {
+		if !common.IsHexAddress(assignment.Address) {
+			continue
+		}
+		address := common.HexToAddress(assignment.Address)
+		if _, ok := p.byUser[assignment.UserId]; ok {
+			continue
+		}
+		if _, ok := p.byAddress[address]; ok {
+			continue
+		}
+		p.byUser[assignment.UserId] = &types.EthAddress{Address: address.Hex()}
+		p.byAddress[address] = assignment.UserId
+		restored++
+	}
+	return restored
+}
+
+// Assign 为用户分配地址，已分配过的用户返回原地址；池空时返回 ErrPoolEmpty 并触发补充
+// 调用方持久化分配失败时应调用 Release 归还地址
+func (p *Pool) Assign(userId string) (*types.EthAddress, error) {
+	if userId == "" {
+		return nil, errors.New("user id is empty")
+	}
+
+	p.mu.Lock()
+	if address, ok := p.byUser[userId]; ok {
+		p.mu.Unlock()
+		return address, nil
+	}
+	if len(p.free) == 0 {
+		p.mu.Unlock()
+		p.triggerRefill()
+		return nil, ErrPoolEmpty
+	}
+	address := p.free[0]
+	p.free = p.free[1:]
+	p.byUser[userId] = address
+	p.byAddress[common.HexToAddress(address.Address)] = userId
+	remaining := len(p.free)
+	p.mu.Unlock()
+
+	if remaining < p.config.Threshold {
+		p.triggerRefill()
+	}
+	return address, nil
+}
+
+// Release 撤销尚未持久化的分配，地址放回空闲列表的头部，供下一次分配使用
+func (p *Pool) Release(userId string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	address, ok := p.byUser[userId]
+	if !ok {
+		return
+	}
+	delete(p.byUser, userId)
+	delete(p.byAddress, common.HexToAddress(address.Address))
+	p.free = append([]*types.EthAddress{address}, p.free...)
+}
+
+// LookupByUser 查询用户的充值地址
+func (p *Pool) LookupByUser(userId string) (*types.EthAddress, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	address, ok := p.byUser[userId]
+	return address, ok
+}
+
+// UserOf 按地址查询用户，供充值扫描把链上转账映射到账户
+func (p *Pool) UserOf(address common.Address) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	userId, ok := p.byAddress[address]
+	return userId, ok
+}
+
+// Refill 将空闲地址补充到 Target，并发调用时依次执行
+func (p *Pool) Refill(ctx context.Context) error {
+	p.refillMu.Lock()
+	defer p.refillMu.Unlock()
+	for p.Available() < p.config.Target {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		address, err := p.source.NewAddress(ctx)
+		if err != nil {
+			return err
+		}
+		if !common.IsHexAddress(address.Address) {
+			return ErrInvalidAddress
+		}
+		p.mu.Lock()
+		p.free = append(p.free, address)
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// Start 启动后台补充协程，ctx 取消后退出
+func (p *Pool) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.config.RefillInterval)
+		defer ticker.Stop()
+		for {
+			if p.Available() < p.config.Threshold || p.Available() == 0 {
+				if err := p.Refill(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("address pool %s refill failed: %v", p.config.Family, err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-p.refill:
+			}
+		}
+	}()
+}
+
+func (p *Pool) triggerRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// Service 按链族管理多个地址池
+type Service struct {
+	pools map[string]*Pool
+}
+
+func NewService(pools ...*Pool) *Service {
+	s := &Service{pools: make(map[string]*Pool, len(pools))}
+	for _, pool := range pools {
+		s.pools[pool.Family()] = pool
+	}
+	return s
+}
+
+func (s *Service) Pool(family string) (*Pool, error) {
+	pool, ok := s.pools[family]
+	if !ok {
+		return nil, ErrUnknownFamily
+	}
+	return pool, nil
+}
+
+// Start 启动所有地址池的后台补充
+func (s *Service) Start(ctx context.Context) {
+	for _, pool := range s.pools {
+		pool.Start(ctx)
+	}
+}
+
+// Assign 在指定链族中为用户分配地址
+func (s *Service) Assign(family, userId string) (*types.EthAddress, error) {
+	pool, err := s.Pool(family)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Assign(userId)
+}
+
+// Restore 恢复指定链族的已持久化分配，返回恢复的数量
+func (s *Service) Restore(family string, assignments []Assignment) (int, error) {
+	pool, err := s.Pool(family)
+	if err != nil {
+		return 0, err
+	}
+	return pool.Restore(assignments), nil
+}
+
+// Release 撤销指定链族中尚未持久化的分配
+func (s *Service) Release(family, userId string) {
+	if pool, err := s.Pool(family); err == nil {
+		pool.Release(userId)
+	}
+}
+
+// UserOf 在指定链族中按地址查询用户
+func (s *Service) UserOf(family string, address common.Address) (string, bool) {
+	pool, err := s.Pool(family)
+	if err != nil {
+		return "", false
+	}
+	return pool.UserOf(address)
+}
@@ -0,0 +1,148 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+func TestPoolAssign(t *testing.T) {
+	pool := NewPool(Config{Family: "evm", Target: 4, Threshold: 2}, SourceFunc(ethereum.CreateAddress))
+	if _, err := pool.Assign("user-0"); err != ErrPoolEmpty {
+		t.Fatalf("Assign() on empty pool err = %v", err)
+	}
+	if err := pool.Refill(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Available() != 4 {
+		t.Fatalf("Available() = %d", pool.Available())
+	}
+
+	// 并发分配，每个用户得到不同地址
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := pool.Assign(fmt.Sprintf("user-%d", i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		userId := fmt.Sprintf("user-%d", i)
+		address, ok := pool.LookupByUser(userId)
+		if !ok || seen[address.Address] {
+			t.Fatalf("user %s address = %+v", userId, address)
+		}
+		seen[address.Address] = true
+		if owner, ok := pool.UserOf(common.HexToAddress(address.Address)); !ok || owner != userId {
+			t.Errorf("UserOf(%s) = %s", address.Address, owner)
+		}
+		// 重复分配返回同一地址
+		again, err := pool.Assign(userId)
+		if err != nil || again.Address != address.Address {
+			t.Errorf("re-assign = %+v %v", again, err)
+		}
+	}
+}
+
+func TestPoolRestore(t *testing.T) {
+	pool := NewPool(Config{Family: "evm", Target: 1}, SourceFunc(ethereum.CreateAddress))
+	restored := pool.Restore([]Assignment{
+		{UserId: "user-0", Address: "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74"},
+		{UserId: "user-1", Address: "0x8ff44c9b5eab5e5ce8d1d642184b70e9b9587f74"}, // 地址已分配给其他用户
+		{UserId: "user-2", Address: "0x1234"},
+	})
+	if restored != 1 {
+		t.Fatalf("Restore() = %d, want 1", restored)
+	}
+	// 重启前分配的用户不需要空闲地址
+	address, err := pool.Assign("user-0")
+	if err != nil || address.Address != "0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74" {
+		t.Fatalf("Assign() = %+v %v", address, err)
+	}
+	if owner, ok := pool.UserOf(common.HexToAddress(address.Address)); !ok || owner != "user-0" {
+		t.Errorf("UserOf() = %s", owner)
+	}
+
+	// 持久化失败后归还的地址分配给下一个用户
+	if err := pool.Refill(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first, err := pool.Assign("user-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Release("user-3")
+	if _, ok := pool.LookupByUser("user-3"); ok || pool.Available() != 1 {
+		t.Fatalf("Release() left user assigned, available %d", pool.Available())
+	}
+	if next, err := pool.Assign("user-4"); err != nil || next.Address != first.Address {
+		t.Errorf("Assign() after release = %+v %v", next, err)
+	}
+}
+
+func TestPoolBackgroundRefill(t *testing.T) {
+	pool := NewPool(Config{Family: "evm", Target: 3, Threshold: 2, RefillInterval: time.Hour}, SourceFunc(ethereum.CreateAddress))
+	service := NewService(pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+
+	waitAvailable := func(n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for pool.Available() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("Available() = %d, want %d", pool.Available(), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitAvailable(3)
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.Assign("evm", fmt.Sprintf("user-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 低于阈值后由后台补充回目标数量
+	waitAvailable(3)
+
+	if _, err := service.Assign("tron", "user-9"); err != ErrUnknownFamily {
+		t.Errorf("Assign() unknown family err = %v", err)
+	}
+}
+
+func TestPoolConcurrentRefill(t *testing.T) {
+	pool := NewPool(Config{Family: "evm", Target: 5}, SourceFunc(func(ctx context.Context) (*types.EthAddress, error) {
+		// 放慢生成，使并发的 Refill 在补充过程中交错
+		time.Sleep(time.Millisecond)
+		return ethereum.CreateAddress(ctx)
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := pool.Refill(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := pool.Available(); got != 5 {
+		t.Errorf("Available() = %d, want 5", got)
+	}
+}
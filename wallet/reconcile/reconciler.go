@@ -0,0 +1,238 @@
+// Package reconcile 核对内部账本余额与链上实际余额
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sort"
+	"time"
+
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+// addressClasses 参与对账的地址类账户
+var addressClasses = []ledger.AccountType{ledger.AccountDeposit, ledger.AccountHot, ledger.AccountCold}
+
+// Config 单条链的对账配置
+type Config struct {
+	ChainId   uint64
+	BatchSize int // 每个批量 RPC 请求包含的查询数
+	// Tokens 除账本中已出现的代币外额外核对的代币
+	Tokens []common.Address
+	// Addresses 账本中可能没有余额但需要核对的地址，如新分配的充值地址
+	Addresses map[ledger.AccountType][]common.Address
+	Interval  time.Duration // Start 定时对账的间隔
+}
+
+// Reconciler 对账任务，按 (链, 代币, 地址类别) 汇总账本余额并与链上余额比对
+type Reconciler struct {
+	client node.EthClient
+	ledger *ledger.Ledger
+	config Config
+}
+
+func NewReconciler(client node.EthClient, l *ledger.Ledger, config Config) *Reconciler {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	return &Reconciler{client: client, ledger: l, config: config}
+}
+
+// Run 执行一次对账，所有链上余额固定在同一个最新区块上查询
+func (r *Reconciler) Run(ctx context.Context) (*Report, error) {
+	header, err := r.client.BlockHeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerBalances := r.ledgerBalances()
+	accounts := make([]ledger.Account, 0, len(ledgerBalances))
+	for account := range ledgerBalances {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].String() < accounts[j].String()
+	})
+
+	onChain, err := r.onChainBalances(ctx, accounts, header.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		ChainId:     r.config.ChainId,
+		BlockNumber: header.Number.Uint64(),
+		BlockHash:   header.Hash(),
+		GeneratedAt: time.Now().UTC(),
+		Balanced:    true,
+	}
+	type summaryKey struct {
+		token common.Address
+		class ledger.AccountType
+	}
+	totals := make(map[summaryKey]*[2]*big.Int)
+	summaries := make(map[summaryKey]*ClassSummary)
+	for i, account := range accounts {
+		key := summaryKey{token: account.Asset.Token, class: account.Type}
+		summary, ok := summaries[key]
+		if !ok {
+			summary = &ClassSummary{ChainId: r.config.ChainId, Token: key.token, Class: key.class}
+			summaries[key] = summary
+			totals[key] = &[2]*big.Int{new(big.Int), new(big.Int)}
+		}
+		summary.Addresses++
+		totals[key][0].Add(totals[key][0], ledgerBalances[account])
+		totals[key][1].Add(totals[key][1], onChain[i])
+
+		if diff := new(big.Int).Sub(onChain[i], ledgerBalances[account]); diff.Sign() != 0 {
+			report.Balanced = false
+			summary.Discrepancies = append(summary.Discrepancies, Discrepancy{
+				Address:    common.HexToAddress(account.Owner),
+				Ledger:     ledgerBalances[account].String(),
+				OnChain:    onChain[i].String(),
+				Difference: diff.String(),
+			})
+		}
+	}
+
+	for key, summary := range summaries {
+		summary.Ledger = totals[key][0].String()
+		summary.OnChain = totals[key][1].String()
+		summary.Difference = new(big.Int).Sub(totals[key][1], totals[key][0]).String()
+		report.Summaries = append(report.Summaries, *summary)
+	}
+	sort.Slice(report.Summaries, func(i, j int) bool {
+		a, b := report.Summaries[i], report.Summaries[j]
+		if a.Token != b.Token {
+			return a.Token.Hex() < b.Token.Hex()
+		}
+		return a.Class < b.Class
+	})
+	return report, nil
+}
+
+// ledgerBalances 收集本链所有地址类账户的账本余额，并补齐配置中需要额外核对的地址与代币
+func (r *Reconciler) ledgerBalances() map[ledger.Account]*big.Int {
+	balances := make(map[ledger.Account]*big.Int)
+	tokens := map[common.Address]bool{{}: true}
+	for _, token := range r.config.Tokens {
+		tokens[token] = true
+	}
+	for _, balance := range r.ledger.Balances(func(account ledger.Account) bool {
+		return account.Asset.ChainId == r.config.ChainId && isAddressClass(account.Type)
+	}) {
+		balances[balance.Account] = balance.Amount
+		tokens[balance.Account.Asset.Token] = true
+	}
+
+	for class, addresses := range r.config.Addresses {
+		if !isAddressClass(class) {
+			continue
+		}
+		for _, address := range addresses {
+			for token := range tokens {
+				account := ledger.AddressAccount(class, address, ledger.Asset{ChainId: r.config.ChainId, Token: token})
+				if _, ok := balances[account]; !ok {
+					balances[account] = new(big.Int)
+				}
+			}
+		}
+	}
+	return balances
+}
+
+// onChainBalances 分批查询链上余额：原生币用 eth_getBalance，代币用 balanceOf
+func (r *Reconciler) onChainBalances(ctx context.Context, accounts []ledger.Account, blockNumber *big.Int) ([]*big.Int, error) {
+	balances := make([]*big.Int, len(accounts))
+	var nativeIndexes, tokenIndexes []int
+	for i, account := range accounts {
+		if account.Asset.Token == (common.Address{}) {
+			nativeIndexes = append(nativeIndexes, i)
+		} else {
+			tokenIndexes = append(tokenIndexes, i)
+		}
+	}
+
+	for _, batch := range chunk(nativeIndexes, r.config.BatchSize) {
+		addresses := make([]common.Address, len(batch))
+		for j, i := range batch {
+			addresses[j] = common.HexToAddress(accounts[i].Owner)
+		}
+		results, err := r.client.BalancesAt(ctx, addresses, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != len(batch) {
+			return nil, fmt.Errorf("balance batch returned %d results, want %d", len(results), len(batch))
+		}
+		for j, i := range batch {
+			balances[i] = results[j]
+		}
+	}
+
+	for _, batch := range chunk(tokenIndexes, r.config.BatchSize) {
+		msgs := make([]ethereum.CallMsg, len(batch))
+		for j, i := range batch {
+			token := accounts[i].Asset.Token
+			msgs[j] = ethereum.CallMsg{To: &token, Data: WalletEthereum.BuildErc20BalanceOfData(common.HexToAddress(accounts[i].Owner))}
+		}
+		results, err := r.client.BatchCallContract(ctx, msgs, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != len(batch) {
+			return nil, fmt.Errorf("balanceOf batch returned %d results, want %d", len(results), len(batch))
+		}
+		for j, i := range batch {
+			balance, err := WalletEthereum.DecodeErc20BalanceOfResult(results[j])
+			if err != nil {
+				return nil, fmt.Errorf("balanceOf %s on %s: %w", accounts[i].Owner, accounts[i].Asset.Token.Hex(), err)
+			}
+			balances[i] = balance
+		}
+	}
+	return balances, nil
+}
+
+// Start 按 Interval 定时对账，每次结果交给 handle 处理（如签名导出、告警），直到 ctx 结束
+func (r *Reconciler) Start(ctx context.Context, handle func(*Report, error)) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		handle(r.Run(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func isAddressClass(accountType ledger.AccountType) bool {
+	for _, class := range addressClasses {
+		if class == accountType {
+			return true
+		}
+	}
+	return false
+}
+
+func chunk(indexes []int, size int) [][]int {
+	var batches [][]int
+	for len(indexes) > size {
+		batches = append(batches, indexes[:size])
+		indexes = indexes[size:]
+	}
+	if len(indexes) > 0 {
+		batches = append(batches, indexes)
+	}
+	return batches
+}
@@ -0,0 +1,155 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+)
+
+type balanceClient struct {
+	node.EthClient
+	native  map[common.Address]*big.Int
+	tokens  map[common.Address]map[common.Address]*big.Int
+	batches int
+}
+
+func (c *balanceClient) BlockHeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100)}, nil
+}
+
+func (c *balanceClient) BalancesAt(ctx context.Context, addresses []common.Address, blockNumber *big.Int) ([]*big.Int, error) {
+	c.batches++
+	balances := make([]*big.Int, len(addresses))
+	for i, address := range addresses {
+		balances[i] = new(big.Int)
+		if balance, ok := c.native[address]; ok {
+			balances[i].Set(balance)
+		}
+	}
+	return balances, nil
+}
+
+func (c *balanceClient) BatchCallContract(ctx context.Context, msgs []ethereum.CallMsg, blockNumber *big.Int) ([][]byte, error) {
+	c.batches++
+	results := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		owner := common.BytesToAddress(msg.Data[4:])
+		balance := new(big.Int)
+		if b, ok := c.tokens[*msg.To][owner]; ok {
+			balance.Set(b)
+		}
+		results[i] = common.LeftPadBytes(balance.Bytes(), 32)
+	}
+	return results, nil
+}
+
+func TestReconcilerRun(t *testing.T) {
+	usdt := common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	hot := common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227")
+	deposit1 := common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	deposit2 := common.HexToAddress("0x01C9E6bdb351AD536236b508092f49eDEe5be0e6")
+	unused := common.HexToAddress("0x779877A7B0D9E8603169DdbD7836e478b4624789")
+	asset := ledger.Asset{ChainId: 1, Token: usdt}
+
+	l := ledger.NewLedger()
+	for _, entry := range []*ledger.Entry{
+		ledger.DepositEntry("u1", deposit1, asset, big.NewInt(500), common.HexToHash("0x01"), 0),
+		ledger.DepositEntry("u2", deposit2, asset, big.NewInt(300), common.HexToHash("0x02"), 0),
+		ledger.SweepEntry(deposit2, hot, asset, big.NewInt(300), common.HexToHash("0x03")),
+	} {
+		if _, err := l.Post(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &balanceClient{
+		native: map[common.Address]*big.Int{unused: big.NewInt(7)},
+		tokens: map[common.Address]map[common.Address]*big.Int{usdt: {
+			deposit1: big.NewInt(500),
+			hot:      big.NewInt(290), // 热钱包少了 10
+			unused:   big.NewInt(5),   // 未入账的充值
+		}},
+	}
+	reconciler := NewReconciler(client, l, Config{
+		ChainId:   1,
+		BatchSize: 2,
+		Addresses: map[ledger.AccountType][]common.Address{ledger.AccountDeposit: {unused}},
+	})
+	report, err := reconciler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Balanced || report.BlockNumber != 100 {
+		t.Fatalf("report = %+v", report)
+	}
+	// 4 个代币账户分 2 批，1 个原生币账户 1 批
+	if client.batches != 3 {
+		t.Errorf("batches = %d, want 3", client.batches)
+	}
+
+	summaries := make(map[string]ClassSummary)
+	for _, summary := range report.Summaries {
+		summaries[summary.Token.Hex()+string(summary.Class)] = summary
+	}
+	hotSummary := summaries[usdt.Hex()+string(ledger.AccountHot)]
+	if hotSummary.Ledger != "300" || hotSummary.OnChain != "290" || hotSummary.Difference != "-10" {
+		t.Errorf("hot summary = %+v", hotSummary)
+	}
+	if len(hotSummary.Discrepancies) != 1 || hotSummary.Discrepancies[0].Address != hot {
+		t.Errorf("hot discrepancies = %+v", hotSummary.Discrepancies)
+	}
+	depositSummary := summaries[usdt.Hex()+string(ledger.AccountDeposit)]
+	if depositSummary.Addresses != 3 || depositSummary.Difference != "5" || len(depositSummary.Discrepancies) != 1 || depositSummary.Discrepancies[0].Address != unused {
+		t.Errorf("deposit summary = %+v", depositSummary)
+	}
+	nativeSummary := summaries[common.Address{}.Hex()+string(ledger.AccountDeposit)]
+	if nativeSummary.OnChain != "7" {
+		t.Errorf("native summary = %+v", nativeSummary)
+	}
+}
+
+func TestSignedReport(t *testing.T) {
+	report := &Report{ChainId: 1, BlockNumber: 100, Balanced: true}
+	signed, err := report.Sign("17a01d2d0862c190dd3d286f5233039938c0522da31fd7d580569cdc07e642f4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Signer != common.HexToAddress("0xEB80a127b2b763C631D8ADCeBb0976b190C8C227") {
+		t.Errorf("signer = %v", signed.Signer)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := signed.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded SignedReport
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := VerifySignedReport(&loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.BlockNumber != 100 || !verified.Balanced {
+		t.Errorf("verified report = %+v", verified)
+	}
+
+	loaded.Report = json.RawMessage(`{"chain_id":1,"block_number":100,"balanced":false}`)
+	if _, err := VerifySignedReport(&loaded); !errors.Is(err, ErrReportSignature) {
+		t.Errorf("VerifySignedReport() tampered err = %v", err)
+	}
+}
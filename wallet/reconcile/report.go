@@ -0,0 +1,99 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"os"
+	"time"
+
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+)
+
+var ErrReportSignature = errors.New("reconciliation report signature mismatch")
+
+// Discrepancy 单个地址上账本余额与链上余额不一致，Difference 为链上减账本
+type Discrepancy struct {
+	Address    common.Address `json:"address"`
+	Ledger     string         `json:"ledger"`
+	OnChain    string         `json:"on_chain"`
+	Difference string         `json:"difference"`
+}
+
+// ClassSummary 一种资产在一类地址（充值、热、冷）上的汇总，Discrepancies 可下钻到具体地址
+type ClassSummary struct {
+	ChainId       uint64             `json:"chain_id"`
+	Token         common.Address     `json:"token"` // 零地址表示原生币
+	Class         ledger.AccountType `json:"class"`
+	Addresses     int                `json:"addresses"`
+	Ledger        string             `json:"ledger"`
+	OnChain       string             `json:"on_chain"`
+	Difference    string             `json:"difference"`
+	Discrepancies []Discrepancy      `json:"discrepancies,omitempty"`
+}
+
+// Report 一次对账的结果，链上余额均取自 BlockNumber 对应的区块
+type Report struct {
+	ChainId     uint64         `json:"chain_id"`
+	BlockNumber uint64         `json:"block_number"`
+	BlockHash   common.Hash    `json:"block_hash"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Balanced    bool           `json:"balanced"`
+	Summaries   []ClassSummary `json:"summaries"`
+}
+
+// SignedReport 导出的签名报告，签名为对 Report 原始 JSON 的 personal_sign
+type SignedReport struct {
+	Report    json.RawMessage `json:"report"`
+	Signer    common.Address  `json:"signer"`
+	Signature hexutil.Bytes   `json:"signature"`
+}
+
+// Sign 使用对账服务的私钥签名报告，审计方可用 VerifySignedReport 校验
+func (r *Report) Sign(privateKeyStr string) (*SignedReport, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := WalletEthereum.SignPersonalMessage(privateKeyStr, data)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedReport{
+		Report:    data,
+		Signer:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		Signature: signature,
+	}, nil
+}
+
+// WriteFile 将签名报告以 JSON 写入文件，不做缩进以保持被签名的报告字节不变
+func (s *SignedReport) WriteFile(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// VerifySignedReport 校验签名来自 Signer 且报告未被篡改，返回解析后的报告
+func VerifySignedReport(signed *SignedReport) (*Report, error) {
+	ok, err := WalletEthereum.VerifyPersonalMessage(signed.Signer, signed.Report, signed.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrReportSignature
+	}
+	var report Report
+	if err := json.Unmarshal(signed.Report, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
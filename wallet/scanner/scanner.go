@@ -0,0 +1,653 @@
+// Package scanner 逐块扫描链上交易发现用户充值，达到确认数后入账，并在区块回滚时撤销未入账的充值
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/0xweb-3/EthCEXWallet/api/mq"
+	"github.com/0xweb-3/EthCEXWallet/api/webhook"
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/wallet/custody"
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+// ErrReorgTooDeep 回溯 MaxReorgDepth 个区块仍未找到分叉点，需要人工处理
+var ErrReorgTooDeep = errors.New("reorg deeper than max reorg depth")
+
+// Config 单条链的扫描配置
+type Config struct {
+	ChainId       uint64
+	Name          string           // 游标名，默认 "deposit"
+	Family        string           // 充值地址所属链族，默认 "evm"
+	StartBlock    uint64           // 没有游标时的起始高度
+	Confirmations uint64           // 入账所需确认数，包含交易所在区块，默认 12
+	MaxReorgDepth uint64           // 查找分叉点的最大回溯深度，默认 64
+	BatchSize     uint64           // 每轮最多扫描的区块数，默认 100
+	PollInterval  time.Duration    // 追上链头后的轮询间隔，默认 5s
+	Tokens        []common.Address // 监听 Transfer 事件的 ERC-20 合约
+	Nfts          []common.Address // 监听 Transfer 事件的 ERC-721 合约，需要配置 Options.Nfts
+}
+
+// Options 扫描依赖，Ledger、Nfts、Events、Outbox 与 Webhooks 可为空，为空时跳过入账与对应的事件发布
+type Options struct {
+	Client     node.EthClient
+	Repository database.Repository
+	Ledger     *ledger.Ledger
+	Events     *events.Bus
+	Outbox     *mq.Relay
+	// Nfts 不为空时转入充值地址的 ERC-721 与区块游标在同一事务中记入托管账本，区块回滚时移除
+	Nfts *custody.NftLedger
+	// Webhooks 不为空时 webhook 投递与充值状态在同一事务中写入
+	Webhooks *webhook.Notifier
+}
+
+// Scanner 充值扫描任务，同一条链同一个游标只应运行一个实例
+// 只识别直接转入充值地址的原生币交易与 ERC-20、ERC-721 Transfer 事件，合约内部转账不在识别范围内
+type Scanner struct {
+	opts   Options
+	config Config
+	signer types.Signer
+}
+
+func NewScanner(opts Options, config Config) (*Scanner, error) {
+	if opts.Client == nil || opts.Repository == nil {
+		return nil, errors.New("scanner: client and repository are required")
+	}
+	if config.ChainId == 0 {
+		return nil, errors.New("scanner: chain id is required")
+	}
+	if config.Name == "" {
+		config.Name = "deposit"
+	}
+	if config.Family == "" {
+		config.Family = "evm"
+	}
+	if config.Confirmations == 0 {
+		config.Confirmations = 12
+	}
+	if config.MaxReorgDepth == 0 {
+		config.MaxReorgDepth = 64
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	return &Scanner{
+		opts:   opts,
+		config: config,
+		signer: types.LatestSignerForChainID(new(big.Int).SetUint64(config.ChainId)),
+	}, nil
+}
+
+// ScanOnce 执行一轮扫描：处理回滚、扫描至多 BatchSize 个新区块、确认达到确认数的充值，返回扫描的区块数
+func (s *Scanner) ScanOnce(ctx context.Context) (int, error) {
+	head, err := s.opts.Client.BlockHeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	headNumber := head.Number.Uint64()
+
+	cursor, err := s.opts.Repository.GetCursor(ctx, s.config.ChainId, s.config.Name)
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		cursor = nil
+	case err != nil:
+		return 0, err
+	}
+
+	// 节点落后于游标时等待节点同步
+	if cursor != nil && cursor.Number > headNumber {
+		return 0, nil
+	}
+	if cursor != nil {
+		canonical, err := s.opts.Client.BlockHeaderByNumber(ctx, new(big.Int).SetUint64(cursor.Number))
+		if err != nil {
+			return 0, err
+		}
+		if canonical.Hash().Hex() != cursor.Hash {
+			if cursor, err = s.rollback(ctx, cursor); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	scanned := 0
+	next := s.config.StartBlock
+	if cursor != nil {
+		next = cursor.Number + 1
+	}
+	if next <= headNumber {
+		end := min(headNumber, next+s.config.BatchSize-1)
+		if scanned, err = s.scanRange(ctx, cursor, next, end, headNumber); err != nil {
+			return scanned, err
+		}
+	}
+	return scanned, s.confirm(ctx, headNumber)
+}
+
+// Run 持续扫描直到 ctx 取消，落后较多时连续扫描；追上链头后等待新区块通知，最长等待 PollInterval
+func (s *Scanner) Run(ctx context.Context) {
+	heads := make(chan *types.Header, 1)
+	sub, err := s.opts.Client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		log.Printf("scanner: chain %d: subscribe new heads: %v, polling every %s", s.config.ChainId, err, s.config.PollInterval)
+	} else {
+		defer sub.Unsubscribe()
+	}
+
+	for {
+		scanned, err := s.ScanOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("scanner: chain %d: %v", s.config.ChainId, err)
+		} else if uint64(scanned) == s.config.BatchSize {
+			continue
+		}
+
+		timer := time.NewTimer(s.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-heads:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// scanRange 逐块扫描 [from, to]，每个区块的充值与游标在同一事务中提交
+// 发现区块不再连续时停止，交给下一轮的回滚检查处理
+func (s *Scanner) scanRange(ctx context.Context, cursor *database.BlockCursor, from, to, head uint64) (int, error) {
+	addresses, err := s.depositAddresses(ctx)
+	if err != nil {
+		return 0, err
+	}
+	transferLogs, err := s.transferLogs(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	var parent common.Hash
+	if cursor != nil {
+		parent = common.HexToHash(cursor.Hash)
+	}
+	for number := from; number <= to; number++ {
+		header, err := s.opts.Client.BlockHeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return int(number - from), err
+		}
+		hash := header.Hash()
+		if parent != (common.Hash{}) && header.ParentHash != parent {
+			return int(number - from), nil
+		}
+
+		deposits, err := s.nativeDeposits(ctx, header, addresses)
+		if err != nil {
+			return int(number - from), err
+		}
+		var nftLogs []types.Log
+		for _, l := range transferLogs[number] {
+			if l.BlockHash != hash {
+				// 日志与区块头来自不同的分叉，下一轮重新扫描
+				return int(number - from), nil
+			}
+			if slices.Contains(s.config.Nfts, l.Address) {
+				nftLogs = append(nftLogs, l)
+			} else if deposit := s.tokenDeposit(header, l, addresses); deposit != nil {
+				deposits = append(deposits, deposit)
+			}
+		}
+
+		detected := make([]events.Event, 0, len(deposits))
+		for _, deposit := range deposits {
+			detected = append(detected, s.depositEvent(events.DepositDetected, deposit, head))
+		}
+		next := &database.BlockCursor{ChainId: s.config.ChainId, Name: s.config.Name, Number: number, Hash: hash.Hex()}
+		err = s.commitNfts(ctx, func(ctx context.Context, repo database.Repository) error {
+			for _, deposit := range deposits {
+				if err := repo.SaveDeposit(ctx, deposit); err != nil {
+					return err
+				}
+			}
+			return repo.SaveCursor(ctx, next)
+		}, detected, nftLogs, addresses)
+		if err != nil {
+			return int(number - from), err
+		}
+		parent = hash
+	}
+	return int(to - from + 1), nil
+}
+
+// depositAddresses 返回充值地址到用户 ID 的映射，每轮重新加载以包含新分配的地址
+func (s *Scanner) depositAddresses(ctx context.Context) (map[common.Address]string, error) {
+	records, err := s.opts.Repository.ListAddressesByKind(ctx, s.config.Family, database.AddressKindDeposit)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make(map[common.Address]string, len(records))
+	for _, record := range records {
+		addresses[common.HexToAddress(record.Address)] = record.UserId
+	}
+	return addresses, nil
+}
+
+// transferLogs 查询区间内监听代币与 NFT 的 Transfer 事件并按区块分组，收款方在本地匹配，避免地址过多时超出节点的 topic 限制
+func (s *Scanner) transferLogs(ctx context.Context, from, to uint64) (map[uint64][]types.Log, error) {
+	contracts := s.config.Tokens
+	if s.opts.Nfts != nil {
+		contracts = append(slices.Clip(contracts), s.config.Nfts...)
+	}
+	if len(contracts) == 0 {
+		return nil, nil
+	}
+	result, err := s.opts.Client.FilterLogs(ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: contracts,
+		Topics:    [][]common.Hash{{WalletEthereum.TransferTopic}},
+	}, new(big.Int).SetUint64(s.config.ChainId))
+	if err != nil {
+		return nil, err
+	}
+	logs := make(map[uint64][]types.Log)
+	for _, l := range result.Logs {
+		logs[l.BlockNumber] = append(logs[l.BlockNumber], l)
+	}
+	return logs, nil
+}
+
+func (s *Scanner) nativeDeposits(ctx context.Context, header *types.Header, addresses map[common.Address]string) ([]*database.Deposit, error) {
+	block, err := s.opts.Client.BlockByNumber(ctx, header.Number)
+	if err != nil {
+		return nil, err
+	}
+	if block.Hash != header.Hash() {
+		return nil, fmt.Errorf("block %d changed while scanning", header.Number)
+	}
+
+	var deposits []*database.Deposit
+	for _, item := range block.Transactions {
+		if !common.IsHexAddress(item.To) {
+			continue
+		}
+		to := common.HexToAddress(item.To)
+		userId, ok := addresses[to]
+		if !ok {
+			continue
+		}
+		hash := common.HexToHash(item.Hash)
+		tx, err := s.opts.Client.TxByHash(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if tx.Value().Sign() <= 0 {
+			continue
+		}
+		receipt, err := s.opts.Client.TxReceiptByHash(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		from, err := types.Sender(s.signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, &database.Deposit{
+			ChainId:     s.config.ChainId,
+			TxHash:      hash.Hex(),
+			LogIndex:    database.NativeLogIndex,
+			BlockNumber: header.Number.Uint64(),
+			BlockHash:   header.Hash().Hex(),
+			From:        from.Hex(),
+			To:          to.Hex(),
+			Amount:      tx.Value().String(),
+			UserId:      userId,
+			Status:      database.DepositStatusPending,
+		})
+	}
+	return deposits, nil
+}
+
+func (s *Scanner) tokenDeposit(header *types.Header, l types.Log, addresses map[common.Address]string) *database.Deposit {
+	// ERC-721 的 Transfer 签名相同但 tokenId 位于第四个 topic，据此排除
+	if l.Removed || len(l.Topics) != 3 || len(l.Data) != 32 {
+		return nil
+	}
+	to := common.BytesToAddress(l.Topics[2].Bytes())
+	userId, ok := addresses[to]
+	if !ok {
+		return nil
+	}
+	amount := new(big.Int).SetBytes(l.Data)
+	if amount.Sign() == 0 {
+		return nil
+	}
+	return &database.Deposit{
+		ChainId:     s.config.ChainId,
+		TxHash:      l.TxHash.Hex(),
+		LogIndex:    uint64(l.Index),
+		BlockNumber: header.Number.Uint64(),
+		BlockHash:   header.Hash().Hex(),
+		Token:       l.Address.Hex(),
+		From:        common.BytesToAddress(l.Topics[1].Bytes()).Hex(),
+		To:          to.Hex(),
+		Amount:      amount.String(),
+		UserId:      userId,
+		Status:      database.DepositStatusPending,
+	}
+}
+
+// confirm 将达到确认数且仍在主链上的待确认充值入账，不在主链上的留给回滚处理
+func (s *Scanner) confirm(ctx context.Context, head uint64) error {
+	pending, err := s.opts.Repository.ListDepositsByStatus(ctx, s.config.ChainId, database.DepositStatusPending)
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		deposit := &pending[i]
+		if deposit.BlockNumber+s.config.Confirmations > head+1 {
+			continue
+		}
+		header, err := s.opts.Client.BlockHeaderByNumber(ctx, new(big.Int).SetUint64(deposit.BlockNumber))
+		if err != nil {
+			return err
+		}
+		if header.Hash().Hex() != deposit.BlockHash {
+			continue
+		}
+
+		var entries []*ledger.Entry
+		if s.opts.Ledger != nil {
+			entry, err := depositEntry(deposit)
+			if err != nil {
+				return err
+			}
+			// 回滚后重新上链的充值已有被冲正的入账分录，使用新的 Id 重新入账
+			if id, posted := s.depositCredit(entry.Id); !posted {
+				entry.Id = id
+				entries = append(entries, entry)
+			}
+		}
+		deposit.Status = database.DepositStatusConfirmed
+		err = s.commitEntries(ctx, func(ctx context.Context, repo database.Repository) error {
+			return repo.UpdateDepositStatus(ctx, deposit.ChainId, deposit.TxHash, deposit.LogIndex, deposit.Status)
+		}, []events.Event{s.depositEvent(events.DepositConfirmed, deposit, head)}, entries)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// depositEntry 充值入账分录，以交易哈希与序号幂等
+func depositEntry(deposit *database.Deposit) (*ledger.Entry, error) {
+	amount, ok := new(big.Int).SetString(deposit.Amount, 10)
+	if !ok {
+		return nil, fmt.Errorf("deposit %s:%d has invalid amount %q", deposit.TxHash, deposit.LogIndex, deposit.Amount)
+	}
+	asset := ledger.Asset{ChainId: deposit.ChainId, Token: common.HexToAddress(deposit.Token)}
+	return ledger.DepositEntry(deposit.UserId, common.HexToAddress(deposit.To), asset, amount, common.HexToHash(deposit.TxHash), uint(deposit.LogIndex)), nil
+}
+
+// depositCredit 返回充值当前的入账分录 Id，posted 表示该分录已过账且未被冲正
+// 充值每次被回滚冲正后再次上链时按 "<base>#<n>" 使用新的 Id，冲正与重新入账都不会因 Id 重复被跳过
+func (s *Scanner) depositCredit(base string) (id string, posted bool) {
+	for n := 0; ; n++ {
+		id = base
+		if n > 0 {
+			id = fmt.Sprintf("%s#%d", base, n)
+		}
+		if _, ok := s.opts.Ledger.Entry(id); !ok {
+			return id, false
+		}
+		if _, ok := s.opts.Ledger.Entry(ledger.ReversalId(id)); !ok {
+			return id, true
+		}
+	}
+}
+
+// rollback 沿旧链的父哈希回溯到与主链相同的区块，将其后区块中的充值标记为回滚并把游标移到分叉点
+func (s *Scanner) rollback(ctx context.Context, cursor *database.BlockCursor) (*database.BlockCursor, error) {
+	hash := common.HexToHash(cursor.Hash)
+	number := cursor.Number
+	var removed []string
+	for {
+		canonical, err := s.opts.Client.BlockHeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, err
+		}
+		if canonical.Hash() == hash {
+			break
+		}
+		if uint64(len(removed)) >= s.config.MaxReorgDepth || number == 0 {
+			return nil, fmt.Errorf("%w: chain %d at block %d", ErrReorgTooDeep, s.config.ChainId, cursor.Number)
+		}
+		removed = append(removed, hash.Hex())
+		orphan, err := s.opts.Client.BlockHeaderByHash(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("load orphaned block %s: %w", hash.Hex(), err)
+		}
+		hash = orphan.ParentHash
+		number--
+	}
+
+	var reverted []database.Deposit
+	var reversals []*ledger.Entry
+	for _, status := range []string{database.DepositStatusPending, database.DepositStatusConfirmed} {
+		deposits, err := s.opts.Repository.ListDepositsByStatus(ctx, s.config.ChainId, status)
+		if err != nil {
+			return nil, err
+		}
+		for _, deposit := range deposits {
+			if deposit.BlockNumber <= number {
+				continue
+			}
+			reverted = append(reverted, deposit)
+			if status != database.DepositStatusConfirmed || s.opts.Ledger == nil {
+				continue
+			}
+			// 已入账的充值与状态更新在同一事务中冲正
+			entry, err := depositEntry(&deposit)
+			if err != nil {
+				return nil, err
+			}
+			id, posted := s.depositCredit(entry.Id)
+			if !posted {
+				log.Printf("scanner: confirmed deposit %s:%d reorged out but has no ledger entry %s", deposit.TxHash, deposit.LogIndex, id)
+				continue
+			}
+			original, _ := s.opts.Ledger.Entry(id)
+			reversals = append(reversals, ledger.ReversalEntry(original))
+		}
+	}
+
+	reorg := &events.Reorg{ForkBlock: number, RemovedBlocks: removed}
+	for _, deposit := range reverted {
+		reorg.Deposits = append(reorg.Deposits, events.DepositRef{UserId: deposit.UserId, TxHash: deposit.TxHash, LogIndex: deposit.LogIndex})
+	}
+	next := &database.BlockCursor{ChainId: s.config.ChainId, Name: s.config.Name, Number: number, Hash: hash.Hex()}
+	write := func(ctx context.Context, repo database.Repository) error {
+		for _, deposit := range reverted {
+			if err := repo.UpdateDepositStatus(ctx, deposit.ChainId, deposit.TxHash, deposit.LogIndex, database.DepositStatusReorged); err != nil {
+				return err
+			}
+		}
+		return repo.SaveCursor(ctx, next)
+	}
+	pending := []events.Event{{Type: events.ReorgRollback, ChainId: s.config.ChainId, Reorg: reorg}}
+
+	// 分叉点之后入账的 NFT 与充值状态在同一事务中移出托管账本
+	var nfts []*WalletTypes.NftHolding
+	committed := false
+	if s.opts.Nfts != nil {
+		var err error
+		nfts, err = s.opts.Nfts.RevertAfter(number, func(reverted []*WalletTypes.NftHolding) error {
+			committed = true
+			return s.commitEntries(ctx, func(ctx context.Context, repo database.Repository) error {
+				if err := write(ctx, repo); err != nil {
+					return err
+				}
+				for _, holding := range reverted {
+					if err := custody.DeleteHolding(ctx, repo, s.config.ChainId, holding); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, pending, reversals)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !committed {
+		if err := s.commitEntries(ctx, write, pending, reversals); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("scanner: chain %d reorg, fork block %d, %d blocks removed, %d deposits and %d nfts reverted", s.config.ChainId, number, len(removed), len(reverted), len(nfts))
+	return next, nil
+}
+
+func (s *Scanner) depositEvent(eventType events.Type, deposit *database.Deposit, head uint64) events.Event {
+	return events.Event{
+		Type:    eventType,
+		ChainId: deposit.ChainId,
+		Deposit: &events.Deposit{
+			UserId:        deposit.UserId,
+			TxHash:        deposit.TxHash,
+			LogIndex:      deposit.LogIndex,
+			BlockNumber:   deposit.BlockNumber,
+			BlockHash:     deposit.BlockHash,
+			Token:         deposit.Token,
+			From:          deposit.From,
+			To:            deposit.To,
+			Amount:        deposit.Amount,
+			Confirmations: head - deposit.BlockNumber + 1,
+		},
+	}
+}
+
+// commitNfts 与 commit 相同，并将 logs 中转入充值地址的 ERC-721 记入托管账本；托管记录与 write 在同一事务中持久化，事务失败时托管账本不变
+// 未配置托管账本或没有新入账的 NFT 时等同于 commit
+func (s *Scanner) commitNfts(ctx context.Context, write func(ctx context.Context, repo database.Repository) error, pending []events.Event, logs []types.Log, addresses map[common.Address]string) error {
+	if s.opts.Nfts == nil || len(logs) == 0 {
+		return s.commit(ctx, write, pending)
+	}
+	committed := false
+	lookupUser := func(address common.Address) (string, bool) {
+		userId, ok := addresses[address]
+		return userId, ok
+	}
+	_, err := s.opts.Nfts.ProcessLogsWith(logs, lookupUser, func(credited []*WalletTypes.NftHolding) error {
+		committed = true
+		return s.commit(ctx, func(ctx context.Context, repo database.Repository) error {
+			if err := write(ctx, repo); err != nil {
+				return err
+			}
+			for _, holding := range credited {
+				if err := custody.SaveHolding(ctx, repo, s.config.ChainId, holding); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, pending)
+	})
+	if err != nil || committed {
+		return err
+	}
+	return s.commit(ctx, write, pending)
+}
+
+// commitEntries 与 commit 相同，并将 entries 过账；分录与 write 在同一事务中持久化，事务失败时账本不变
+// 已过账的分录跳过，没有待过账的分录或未配置账本时等同于 commit
+func (s *Scanner) commitEntries(ctx context.Context, write func(ctx context.Context, repo database.Repository) error, pending []events.Event, entries []*ledger.Entry) error {
+	if s.opts.Ledger == nil {
+		return s.commit(ctx, write, pending)
+	}
+	var unposted []*ledger.Entry
+	for _, entry := range entries {
+		if _, ok := s.opts.Ledger.Entry(entry.Id); !ok {
+			unposted = append(unposted, entry)
+		}
+	}
+	if len(unposted) == 0 {
+		return s.commit(ctx, write, pending)
+	}
+	_, err := s.opts.Ledger.PostAll(unposted, func(posted []*ledger.Entry) error {
+		return s.commit(ctx, func(ctx context.Context, repo database.Repository) error {
+			if err := write(ctx, repo); err != nil {
+				return err
+			}
+			for _, entry := range posted {
+				if err := ledger.SaveEntry(ctx, repo, entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, pending)
+	})
+	return err
+}
+
+// commit 在一个事务中执行 write，配置了发件箱或 webhook 时事件一同写入；提交后再发布到进程内总线
+func (s *Scanner) commit(ctx context.Context, write func(ctx context.Context, repo database.Repository) error, pending []events.Event) error {
+	err := s.opts.Repository.RunInTx(ctx, func(ctx context.Context, tx database.Repository) error {
+		if err := write(ctx, tx); err != nil {
+			return err
+		}
+		for i := range pending {
+			var err error
+			if s.opts.Outbox != nil {
+				if pending[i], err = mq.AddEvent(ctx, tx, pending[i]); err != nil {
+					return err
+				}
+			}
+			if s.opts.Webhooks != nil {
+				if pending[i], err = s.opts.Webhooks.AddEvent(ctx, tx, pending[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		if s.opts.Outbox != nil {
+			s.opts.Outbox.Notify()
+		}
+		if s.opts.Webhooks != nil {
+			s.opts.Webhooks.Notify()
+		}
+	}
+	if s.opts.Events != nil {
+		for _, event := range pending {
+			if _, err := s.opts.Events.Publish(event); err != nil {
+				log.Printf("scanner: publish %s: %v", event.Type, err)
+			}
+		}
+	}
+	return nil
+}
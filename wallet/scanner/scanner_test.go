@@ -0,0 +1,429 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xweb-3/EthCEXWallet/database"
+	"github.com/0xweb-3/EthCEXWallet/database/boltstore"
+	"github.com/0xweb-3/EthCEXWallet/wallet/custody"
+	WalletEthereum "github.com/0xweb-3/EthCEXWallet/wallet/ethereum"
+	"github.com/0xweb-3/EthCEXWallet/wallet/events"
+	"github.com/0xweb-3/EthCEXWallet/wallet/ledger"
+	"github.com/0xweb-3/EthCEXWallet/wallet/node"
+	WalletTypes "github.com/0xweb-3/EthCEXWallet/wallet/types"
+)
+
+const chainId = 11155111
+
+var (
+	depositAddress = common.HexToAddress("0x8ff44C9b5Eab5E5CE8d1d642184b70e9b9587F74")
+	usdt           = common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+)
+
+type fakeBlock struct {
+	header *types.Header
+	txs    []*types.Transaction
+	logs   []types.Log
+}
+
+// fakeChain 内存中的链，canonical 为主链，byHash 保留被回滚的区块以便沿父哈希回溯
+type fakeChain struct {
+	node.EthClient
+	canonical []*fakeBlock
+	byHash    map[common.Hash]*fakeBlock
+	txs       map[common.Hash]*types.Transaction
+	receipts  map[common.Hash]*types.Receipt
+}
+
+func newFakeChain() *fakeChain {
+	c := &fakeChain{
+		byHash:   make(map[common.Hash]*fakeBlock),
+		txs:      make(map[common.Hash]*types.Transaction),
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+	c.mine(0, nil, nil)
+	return c
+}
+
+// mine 在 parent 之上出块并替换主链中该高度之后的区块，fork 用于区分同一高度的不同区块
+func (c *fakeChain) mine(fork byte, txs []*types.Transaction, transfers []types.Log) *fakeBlock {
+	header := &types.Header{Number: big.NewInt(int64(len(c.canonical))), Extra: []byte{fork}, Difficulty: common.Big0}
+	if len(c.canonical) > 0 {
+		header.ParentHash = c.canonical[len(c.canonical)-1].header.Hash()
+	}
+	block := &fakeBlock{header: header, txs: txs}
+	hash := header.Hash()
+	for i, tx := range txs {
+		c.txs[tx.Hash()] = tx
+		c.receipts[tx.Hash()] = &types.Receipt{Status: types.ReceiptStatusSuccessful, TransactionIndex: uint(i)}
+	}
+	for i, l := range transfers {
+		l.BlockNumber = header.Number.Uint64()
+		l.BlockHash = hash
+		l.Index = uint(i)
+		block.logs = append(block.logs, l)
+	}
+	c.canonical = append(c.canonical, block)
+	c.byHash[hash] = block
+	return block
+}
+
+// reorg 丢弃 number 及之后的区块
+func (c *fakeChain) reorg(number int) {
+	c.canonical = c.canonical[:number]
+}
+
+func (c *fakeChain) BlockHeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return c.canonical[len(c.canonical)-1].header, nil
+	}
+	if number.Uint64() >= uint64(len(c.canonical)) {
+		return nil, ethereum.NotFound
+	}
+	return c.canonical[number.Uint64()].header, nil
+}
+
+func (c *fakeChain) BlockHeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	block, ok := c.byHash[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return block.header, nil
+}
+
+func (c *fakeChain) BlockByNumber(ctx context.Context, number *big.Int) (*WalletTypes.RpcBlock, error) {
+	block := c.canonical[number.Uint64()]
+	rpcBlock := &WalletTypes.RpcBlock{Hash: block.header.Hash()}
+	for _, tx := range block.txs {
+		rpcBlock.Transactions = append(rpcBlock.Transactions, WalletTypes.TransactionList{To: tx.To().Hex(), Hash: tx.Hash().Hex()})
+	}
+	return rpcBlock, nil
+}
+
+func (c *fakeChain) TxByHash(ctx context.Context, hash common.Hash) (*types.Transaction, error) {
+	return c.txs[hash], nil
+}
+
+func (c *fakeChain) TxReceiptByHash(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	return c.receipts[hash], nil
+}
+
+func (c *fakeChain) FilterLogs(query ethereum.FilterQuery, chainID *big.Int) (WalletTypes.Logs, error) {
+	var logs []types.Log
+	for n := query.FromBlock.Uint64(); n <= query.ToBlock.Uint64(); n++ {
+		logs = append(logs, c.canonical[n].logs...)
+	}
+	return WalletTypes.Logs{Logs: logs, BlockHeader: c.canonical[query.ToBlock.Uint64()].header}, nil
+}
+
+func signedTransfer(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, to common.Address, value int64) *types.Transaction {
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(chainId)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(chainId),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(value),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func tokenTransfer(from, to common.Address, amount int64) types.Log {
+	return types.Log{
+		Address: usdt,
+		Topics:  []common.Hash{WalletEthereum.TransferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:    common.LeftPadBytes(big.NewInt(amount).Bytes(), 32),
+		TxHash:  common.BytesToHash(append([]byte("token"), byte(amount))),
+	}
+}
+
+func newTestScanner(t *testing.T, chain *fakeChain) (*Scanner, database.Repository, *ledger.Ledger, *events.Subscription) {
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	ctx := context.Background()
+	err = store.CreateAddress(ctx, &database.Address{Address: depositAddress.Hex(), Family: "evm", Kind: database.AddressKindDeposit, UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := ledger.NewLedger()
+	bus := events.NewBus(100)
+	sub, err := bus.Subscribe(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScanner(Options{Client: chain, Repository: store, Ledger: l, Events: bus}, Config{
+		ChainId:       chainId,
+		StartBlock:    1,
+		Confirmations: 3,
+		Tokens:        []common.Address{usdt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, store, l, sub
+}
+
+func nextEvent(t *testing.T, sub *events.Subscription) events.Event {
+	select {
+	case event := <-sub.Events():
+		return event
+	default:
+		t.Fatal("expected event")
+		return events.Event{}
+	}
+}
+
+func TestScannerConfirmDeposits(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	other := common.HexToAddress("0x01C9E6bdb351AD536236b508092f49eDEe5be0e6")
+
+	chain := newFakeChain()
+	native := signedTransfer(t, key, 0, depositAddress, 1000)
+	// 代币转账与原生币转账在同一笔交易中，且日志序号等于交易序号，两笔充值不能互相覆盖
+	token := tokenTransfer(sender, depositAddress, 42)
+	token.TxHash = native.Hash()
+	chain.mine(0, []*types.Transaction{signedTransfer(t, key, 1, other, 5), native}, []types.Log{tokenTransfer(sender, other, 7), token})
+	s, repo, l, sub := newTestScanner(t, chain)
+	ctx := context.Background()
+
+	if scanned, err := s.ScanOnce(ctx); err != nil || scanned != 1 {
+		t.Fatalf("scan: %d, %v", scanned, err)
+	}
+	pending, err := repo.ListDepositsByStatus(ctx, chainId, database.DepositStatusPending)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("pending deposits: %v, %v", pending, err)
+	}
+	deposit, err := repo.GetDeposit(ctx, chainId, native.Hash().Hex(), database.NativeLogIndex)
+	if err != nil || deposit.From != sender.Hex() || deposit.Amount != "1000" || deposit.UserId != "u1" {
+		t.Fatalf("native deposit: %+v, %v", deposit, err)
+	}
+	for range 2 {
+		if event := nextEvent(t, sub); event.Type != events.DepositDetected || event.Deposit.Confirmations != 1 {
+			t.Fatalf("detected event: %+v", event)
+		}
+	}
+
+	chain.mine(0, nil, nil)
+	chain.mine(0, nil, nil)
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	confirmed, err := repo.ListDepositsByStatus(ctx, chainId, database.DepositStatusConfirmed)
+	if err != nil || len(confirmed) != 2 {
+		t.Fatalf("confirmed deposits: %v, %v", confirmed, err)
+	}
+	for range 2 {
+		if event := nextEvent(t, sub); event.Type != events.DepositConfirmed || event.Deposit.Confirmations != 3 {
+			t.Fatalf("confirmed event: %+v", event)
+		}
+	}
+	if got := l.Balance(ledger.UserAccount("u1", ledger.Asset{ChainId: chainId})); got.Int64() != 1000 {
+		t.Fatalf("native balance %v", got)
+	}
+	if got := l.Balance(ledger.UserAccount("u1", ledger.Asset{ChainId: chainId, Token: usdt})); got.Int64() != 42 {
+		t.Fatalf("token balance %v", got)
+	}
+
+	cursor, err := repo.GetCursor(ctx, chainId, "deposit")
+	if err != nil || cursor.Number != 3 || cursor.Hash != chain.canonical[3].header.Hash().Hex() {
+		t.Fatalf("cursor: %+v, %v", cursor, err)
+	}
+}
+
+func TestScannerReorg(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	chain := newFakeChain()
+	chain.mine(0, nil, nil)
+	orphan := chain.mine(0, nil, []types.Log{tokenTransfer(sender, depositAddress, 42)})
+	s, repo, l, sub := newTestScanner(t, chain)
+	ctx := context.Background()
+
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, sub)
+
+	// 区块 2 被替换，新分叉上没有这笔充值
+	chain.reorg(2)
+	chain.mine(1, nil, nil)
+	chain.mine(1, nil, nil)
+	chain.mine(1, nil, nil)
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextEvent(t, sub)
+	if event.Type != events.ReorgRollback || event.Reorg.ForkBlock != 1 || len(event.Reorg.RemovedBlocks) != 1 ||
+		event.Reorg.RemovedBlocks[0] != orphan.header.Hash().Hex() || len(event.Reorg.Deposits) != 1 {
+		t.Fatalf("reorg event: %+v", event.Reorg)
+	}
+	reorged, err := repo.ListDepositsByStatus(ctx, chainId, database.DepositStatusReorged)
+	if err != nil || len(reorged) != 1 {
+		t.Fatalf("reorged deposits: %v, %v", reorged, err)
+	}
+	cursor, err := repo.GetCursor(ctx, chainId, "deposit")
+	if err != nil || cursor.Number != 4 || cursor.Hash != chain.canonical[4].header.Hash().Hex() {
+		t.Fatalf("cursor: %+v, %v", cursor, err)
+	}
+	if got := l.Balance(ledger.UserAccount("u1", ledger.Asset{ChainId: chainId, Token: usdt})); got.Sign() != 0 {
+		t.Fatalf("reorged deposit credited: %v", got)
+	}
+}
+
+func TestScannerReorgConfirmed(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	chain := newFakeChain()
+	chain.mine(0, nil, nil)
+	chain.mine(0, nil, []types.Log{tokenTransfer(sender, depositAddress, 42)})
+	chain.mine(0, nil, nil)
+	chain.mine(0, nil, nil)
+	s, repo, l, _ := newTestScanner(t, chain)
+	ctx := context.Background()
+	account := ledger.UserAccount("u1", ledger.Asset{ChainId: chainId, Token: usdt})
+
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Balance(account); got.Int64() != 42 {
+		t.Fatalf("confirmed balance %v", got)
+	}
+
+	// 已入账的充值被回滚，冲正分录与回滚状态一同提交
+	chain.reorg(2)
+	for range 4 {
+		chain.mine(1, nil, nil)
+	}
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Balance(account); got.Sign() != 0 {
+		t.Fatalf("balance after reorg %v", got)
+	}
+	if reorged, err := repo.ListDepositsByStatus(ctx, chainId, database.DepositStatusReorged); err != nil || len(reorged) != 1 {
+		t.Fatalf("reorged deposits: %v, %v", reorged, err)
+	}
+	entries, err := repo.ListLedgerEntries(ctx, 0, 0)
+	if err != nil || len(entries) != 2 || entries[1].Kind != string(ledger.EntryReversal) || entries[1].Reference != entries[0].Id {
+		t.Fatalf("ledger entries: %+v, %v", entries, err)
+	}
+	reloaded, err := ledger.Load(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Balance(account); got.Sign() != 0 {
+		t.Errorf("reloaded balance %v", got)
+	}
+}
+
+func TestScannerReorgReincluded(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	chain := newFakeChain()
+	chain.mine(0, nil, nil)
+	native := signedTransfer(t, key, 0, depositAddress, 1000)
+	chain.mine(0, []*types.Transaction{native}, []types.Log{tokenTransfer(sender, depositAddress, 42)})
+	chain.mine(0, nil, nil)
+	chain.mine(0, nil, nil)
+	s, repo, l, _ := newTestScanner(t, chain)
+	ctx := context.Background()
+	nativeAccount := ledger.UserAccount("u1", ledger.Asset{ChainId: chainId})
+	tokenAccount := ledger.UserAccount("u1", ledger.Asset{ChainId: chainId, Token: usdt})
+
+	// 已入账的充值两次被回滚后又在新的主链上打包，每次重新打包都重新入账
+	for fork := byte(1); fork <= 2; fork++ {
+		if _, err := s.ScanOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if native, token := l.Balance(nativeAccount), l.Balance(tokenAccount); native.Int64() != 1000 || token.Int64() != 42 {
+			t.Fatalf("fork %d: confirmed balances %v, %v", fork, native, token)
+		}
+		chain.reorg(1)
+		chain.mine(fork, nil, nil)
+		chain.mine(fork, []*types.Transaction{native}, []types.Log{tokenTransfer(sender, depositAddress, 42)})
+		chain.mine(fork, nil, nil)
+		chain.mine(fork, nil, nil)
+	}
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if native, token := l.Balance(nativeAccount), l.Balance(tokenAccount); native.Int64() != 1000 || token.Int64() != 42 {
+		t.Fatalf("re-included balances %v, %v", native, token)
+	}
+	reloaded, err := ledger.Load(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if native, token := reloaded.Balance(nativeAccount), reloaded.Balance(tokenAccount); native.Int64() != 1000 || token.Int64() != 42 {
+		t.Errorf("reloaded balances %v, %v", native, token)
+	}
+}
+
+func TestScannerNftDeposit(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	nft := common.HexToAddress("0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238")
+	transfer := types.Log{
+		Address: nft,
+		Topics:  []common.Hash{WalletEthereum.TransferTopic, common.BytesToHash(sender.Bytes()), common.BytesToHash(depositAddress.Bytes()), common.BigToHash(big.NewInt(7))},
+		TxHash:  common.HexToHash("0x07"),
+	}
+
+	chain := newFakeChain()
+	chain.mine(0, nil, nil)
+	chain.mine(0, nil, []types.Log{transfer})
+	s, repo, _, _ := newTestScanner(t, chain)
+	nfts := custody.NewNftLedger(chain)
+	s.opts.Nfts = nfts
+	s.config.Nfts = []common.Address{nft}
+	ctx := context.Background()
+
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if holding, ok := nfts.OwnerOf(nft, big.NewInt(7)); !ok || holding.UserId != "u1" || holding.BlockNumber != 2 {
+		t.Fatalf("OwnerOf() = %+v, %v", holding, ok)
+	}
+	// 重启后从存储加载托管账本
+	loaded, err := custody.LoadNftLedger(ctx, chain, chainId, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holdings := loaded.Holdings("u1"); len(holdings) != 1 || holdings[0].TokenId != "7" || holdings[0].DepositTx != transfer.TxHash.Hex() {
+		t.Fatalf("loaded holdings = %+v", holdings)
+	}
+
+	// 区块 2 被替换，NFT 移出托管账本与存储
+	chain.reorg(2)
+	chain.mine(1, nil, nil)
+	if _, err := s.ScanOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nfts.OwnerOf(nft, big.NewInt(7)); ok {
+		t.Error("reorged nft still in custody")
+	}
+	if holdings, err := repo.ListNftHoldings(ctx, chainId); err != nil || len(holdings) != 0 {
+		t.Errorf("ListNftHoldings() after reorg = %+v, %v", holdings, err)
+	}
+}
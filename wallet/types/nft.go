@@ -0,0 +1,11 @@
+package types
+
+// NftHolding 托管中的一个 NFT，记录归属用户与链上持有地址
+type NftHolding struct {
+	UserId      string `json:"user_id"`
+	Contract    string `json:"contract"`
+	TokenId     string `json:"token_id"`
+	Holder      string `json:"holder"` // 持有该 NFT 的交易所地址
+	DepositTx   string `json:"deposit_tx"`
+	BlockNumber uint64 `json:"block_number"`
+}
@@ -0,0 +1,30 @@
+package types
+
+// ContractCall 交易 data 中识别出的代币合约调用
+type ContractCall struct {
+	Standard string `json:"standard"` // ERC20 / ERC721 / ERC1155
+	Method   string `json:"method"`
+	Token    string `json:"token"` // 代币合约地址，即交易的 to
+	From     string `json:"from,omitempty"`
+	To       string `json:"to"`
+	Amount   string `json:"amount,omitempty"`
+	TokenId  string `json:"token_id,omitempty"`
+}
+
+// TransactionSummary 原始交易解码后的摘要，用于审批界面与审计日志
+type TransactionSummary struct {
+	Hash      string        `json:"hash"`
+	Type      uint8         `json:"type"`
+	ChainId   string        `json:"chain_id"`
+	Nonce     uint64        `json:"nonce"`
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Value     string        `json:"value"`
+	Gas       uint64        `json:"gas"`
+	GasPrice  string        `json:"gas_price"`
+	GasTipCap string        `json:"gas_tip_cap"`
+	GasFeeCap string        `json:"gas_fee_cap"`
+	Data      string        `json:"data"`
+	Call      *ContractCall `json:"call,omitempty"`
+	Warnings  []string      `json:"warnings,omitempty"` // 解码中发现的问题，如选择器已知但调用数据不规范
+}
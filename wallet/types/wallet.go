@@ -5,3 +5,10 @@ type EthAddress struct {
 	PublicKey  string `json:"public_key"`
 	Address    string `json:"address"`
 }
+
+// AddressValidation 提现地址校验结果
+type AddressValidation struct {
+	Address  string   `json:"address"`        // 规范化后的校验和地址
+	Kind     string   `json:"kind,omitempty"` // zero / burn / precompile，普通地址为空
+	Warnings []string `json:"warnings,omitempty"`
+}